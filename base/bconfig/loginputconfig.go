@@ -15,7 +15,7 @@ type LogInputConfig interface {
 
 	NewInput(parentLogger logger.Logger, allocator *base.LogAllocator, schema base.LogSchema,
 		logBufferReceiver base.MultiSinkBufferReceiver, metricCreator promreg.MetricCreator,
-		stopRequest channels.Awaitable) (base.LogInput, error)
+		customMetrics *base.LogCustomMetricRegistry, stopRequest channels.Awaitable) (base.LogInput, error)
 
 	NewParser(parentLogger logger.Logger, allocator *base.LogAllocator, schema base.LogSchema,
 		inputCounter *base.LogInputCounterSet) (base.LogParser, error)
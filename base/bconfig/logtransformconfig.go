@@ -16,6 +16,14 @@ type LogTransformConfig interface {
 	VerifyConfig(schema base.LogSchema) error
 }
 
+// LogTopLevelTransformConfig is an optional interface of LogTransformConfig for transforms which only work in
+// top-level transformations of pipelines, e.g. those producing multiple records from one
+type LogTopLevelTransformConfig interface {
+	LogTransformConfig
+
+	TopLevelOnly()
+}
+
 // LogTransformConfigHolder holds LogTransformConfig
 type LogTransformConfigHolder = ConfigHolder[LogTransformConfig]
 
@@ -28,6 +28,7 @@ type PipelineArgs struct {
 	Schema              base.LogSchema
 	Deallocator         *base.LogAllocator
 	MetricKeyLocators   []base.LogFieldLocator
+	CustomMetrics       *base.LogCustomMetricRegistry     // registry of custom metrics from transforms, e.g. 'metric'
 	TransformConfigs    []LogTransformConfigHolder // Verified config list of transforms
 	OutputBufferPairs   []OutputBufferConfig
 	NewConsumerOverride base.ChunkConsumerOverrideCreator // nil or override ChunkConsumer (ex: forwarder) for test
@@ -11,6 +11,9 @@ type OutputBufferConfig struct {
 	Name         string                          `yaml:"name"`
 	BufferConfig ConfigHolder[ChunkBufferConfig] `yaml:"buffer"`
 	OutputConfig ConfigHolder[LogOutputConfig]   `yaml:"output"`
+
+	// Transformations are performed on records before serialization for this output only, after the top-level ones
+	Transformations []LogTransformConfigHolder `yaml:"transformations,omitempty"`
 }
 
 // VerifyConfig verifies the configuration
@@ -7,22 +7,76 @@ import (
 // LogMatcher can match log records of certain conditions
 type LogMatcher struct {
 	fieldMatches []keyValueMatch
+	groupMatches []groupMatch // nested groups, checked after fieldMatches
+	cost         int          // total cost of all conditions, used to order nested groups
 }
 
 type keyValueMatch struct {
-	locator base.LogFieldLocator
-	match   valueMatcher
+	locator  base.LogFieldLocator // MissingFieldLocator for extra fields
+	extraKey string               // key of extra field or empty
+	match    valueMatcher
 }
 
+// groupMatch matches a list of nested groups, ordered by cost
+type groupMatch struct {
+	mode    groupMode
+	members []LogMatcher
+	cost    int
+}
+
+type groupMode int
+
+const (
+	groupAny  groupMode = iota // any of members must match
+	groupAll                   // all of members must match
+	groupNone                  // none of members may match
+)
+
 // Match checks whether the given record matches the condition of this matcher
 func (m LogMatcher) Match(record *base.LogRecord) bool {
 	fields := record.Fields
 	// TODO: REUSE results from distribution if all keys are among the distribution's key fields
 	for _, fm := range m.fieldMatches {
-		value := fm.locator.Get(fields)
+		var value string
+		if fm.locator == base.MissingFieldLocator {
+			value = record.Extra.Get(fm.extraKey)
+		} else {
+			value = fm.locator.Get(fields)
+		}
 		if !fm.match(value) {
 			return false
 		}
 	}
+	for _, gm := range m.groupMatches {
+		if !gm.match(record) {
+			return false
+		}
+	}
 	return true
 }
+
+func (gm groupMatch) match(record *base.LogRecord) bool {
+	switch gm.mode {
+	case groupAny:
+		for _, m := range gm.members {
+			if m.Match(record) {
+				return true
+			}
+		}
+		return false
+	case groupAll:
+		for _, m := range gm.members {
+			if !m.Match(record) {
+				return false
+			}
+		}
+		return true
+	default:
+		for _, m := range gm.members {
+			if m.Match(record) {
+				return false
+			}
+		}
+		return true
+	}
+}
@@ -38,3 +38,107 @@ match:
 	assert.False(t, lm.Match(schema.NewTestRecord1(base.LogFields{"syslog", "DEBUG", "Foo FooBar", "105", "task.log"})))
 	assert.False(t, lm.Match(schema.NewTestRecord1(base.LogFields{"syslog", "", "", "", ""})))
 }
+
+func TestLogMatchGroups(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"level", "log", "source", "vhost"})
+	d := &LogMatcherTestData{}
+	if !assert.NoError(t, util.UnmarshalYamlString(`
+match:
+  level: !!str-not debug
+  any:
+    - log: !!regex ^(GET|POST)\s
+      vhost: !!str-end .com
+    - source: !!str-eq access.log
+  not:
+    any:
+      - vhost: !!str-start internal.
+      - log: !!str-contain healthcheck
+`, d)) {
+		return
+	}
+	if !assert.NoError(t, d.Match.VerifyConfig(schema)) {
+		return
+	}
+	lm := d.Match.NewMatcher(schema)
+	t.Run("test order by cost", func(tt *testing.T) {
+		assert.Equal(tt, "level", lm.fieldMatches[0].locator.Name(schema))
+		assert.Equal(tt, groupNone, lm.groupMatches[0].mode)
+		assert.Equal(tt, groupAny, lm.groupMatches[1].mode)
+		assert.Equal(tt, "source", lm.groupMatches[1].members[0].fieldMatches[0].locator.Name(schema))
+	})
+
+	assert.True(t, lm.Match(schema.NewTestRecord1(base.LogFields{"info", "GET /", "main.log", "foo.com"})))
+	assert.True(t, lm.Match(schema.NewTestRecord1(base.LogFields{"info", "hello", "access.log", "foo.net"})))
+	assert.False(t, lm.Match(schema.NewTestRecord1(base.LogFields{"debug", "GET /", "access.log", "foo.com"})))
+	assert.False(t, lm.Match(schema.NewTestRecord1(base.LogFields{"info", "GET /", "main.log", "foo.net"})))
+	assert.False(t, lm.Match(schema.NewTestRecord1(base.LogFields{"info", "GET /", "main.log", "internal.foo.com"})))
+	assert.False(t, lm.Match(schema.NewTestRecord1(base.LogFields{"info", "GET /healthcheck", "access.log", "foo.com"})))
+
+	dump, err := util.MarshalYaml(d)
+	assert.NoError(t, err)
+	assert.Equal(t, `match:
+  any:
+    - log: ~= ^(GET|POST)\s
+      vhost: $= .com
+    - source: == access.log
+  level: '!= debug'
+  not:
+    any:
+      - vhost: ˆ= internal.
+      - log: '*= healthcheck'
+`, dump)
+}
+
+func TestLogMatchGroupsVerify(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"level", "log"})
+	d := &LogMatcherTestData{}
+	assert.NoError(t, util.UnmarshalYamlString("match:\n  any:\n    - level: info\n    - {}", d))
+	assert.EqualError(t, d.Match.VerifyConfig(schema), "any[1]: group is empty")
+	assert.NoError(t, util.UnmarshalYamlString("match:\n  not:\n    source: foo", d))
+	assert.EqualError(t, d.Match.VerifyConfig(schema), "not: invalid match key 'source': field 'source' is not defined in schema")
+	assert.ErrorContains(t, util.UnmarshalYamlString("match:\n  any: foo", d), "cannot unmarshal")
+	assert.EqualError(t, util.UnmarshalYamlString("match:\n  level: info\n  level: warn", d), "yaml line 3:3: duplicated match key 'level'")
+	assert.EqualError(t, util.UnmarshalYamlString("match:\n  any:\n    - level: info\n      level: warn", d), "yaml line 4:7: duplicated match key 'level'")
+
+	assert.NoError(t, util.UnmarshalYamlString("match:\n  level: info", d))
+	reservedSchema := base.MustNewLogSchema([]string{"level", "not"})
+	assert.EqualError(t, d.Match.VerifyConfig(reservedSchema), "field 'not' in schema cannot be matched because the name is reserved for groups")
+}
+
+func TestLogMatchExtraFields(t *testing.T) {
+	schema, err := base.NewLogSchema([]string{"level", "log"}, 2, 4)
+	assert.NoError(t, err)
+	d := &LogMatcherTestData{}
+	if !assert.NoError(t, util.UnmarshalYamlString(`
+match:
+  level: !!str-not debug
+  extra.user: !!str-start admin
+`, d)) {
+		return
+	}
+	if !assert.NoError(t, d.Match.VerifyConfig(schema)) {
+		return
+	}
+	lm := d.Match.NewMatcher(schema)
+
+	newRecord := func(level string, user string) *base.LogRecord {
+		record := schema.NewTestRecord1(base.LogFields{level, "hello"})
+		if len(user) > 0 {
+			record.Extra.Set("user", user)
+		}
+		return record
+	}
+	assert.True(t, lm.Match(newRecord("info", "admin1")))
+	assert.False(t, lm.Match(newRecord("debug", "admin1")))
+	assert.False(t, lm.Match(newRecord("info", "guest")))
+	assert.False(t, lm.Match(newRecord("info", "")))
+
+	noExtraSchema := base.MustNewLogSchema([]string{"level", "log"})
+	assert.EqualError(t, d.Match.VerifyConfig(noExtraSchema), "invalid match key 'extra.user': field 'extra.user' refers to extra fields, which are disabled in schema")
+
+	// keys without the prefix must be defined in schema even if extra fields are enabled, e.g. typos
+	assert.NoError(t, util.UnmarshalYamlString("match:\n  lvel: info", d))
+	assert.EqualError(t, d.Match.VerifyConfig(schema), "invalid match key 'lvel': field 'lvel' is not defined in schema")
+	assert.NoError(t, util.UnmarshalYamlString("match:\n  extra.level: info", d))
+	assert.EqualError(t, d.Match.VerifyConfig(schema), "invalid match key 'extra.level': field 'extra.level' refers to 'level' defined in schema")
+}
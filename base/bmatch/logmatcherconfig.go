@@ -4,48 +4,203 @@ import (
 	"fmt"
 	"sort"
 
+	"github.com/relex/gotils/logger"
 	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/util"
+	"golang.org/x/exp/slices"
+	"gopkg.in/yaml.v3"
 )
 
 // LogMatcherConfig is the configuration for LogMatch, to match log records of certain conditions
-type LogMatcherConfig map[string]valueMatch
+//
+// All conditions in the same group must be satisfied. Apart from field names, the following keys are reserved:
+//
+//   - "any": a list of nested groups, at least one of which must be satisfied
+//   - "all": a list of nested groups, all of which must be satisfied
+//   - "not": a nested group which must NOT be satisfied
+//
+// Schema fields of the reserved names cannot be matched and are rejected by VerifyConfig.
+//
+// Keys prefixed by "extra." are matched against extra fields if enabled by schema's maxExtraFields, e.g. "extra.user".
+// Other keys must be defined in schema.
+type LogMatcherConfig struct {
+	fields map[string]valueMatch
+	any    []LogMatcherConfig
+	all    []LogMatcherConfig
+	not    *LogMatcherConfig
+}
+
+const (
+	groupKeyAny = "any"
+	groupKeyAll = "all"
+	groupKeyNot = "not"
+)
 
 type sortableKeyValueMatches struct {
 	data []unsortedKeyValueMatch
 }
 
 type unsortedKeyValueMatch struct {
-	locator base.LogFieldLocator
-	match   valueMatch
+	locator  base.LogFieldLocator
+	extraKey string
+	match    valueMatch
+}
+
+// IsEmpty checks whether there is no condition at all
+func (cfg LogMatcherConfig) IsEmpty() bool {
+	return len(cfg.fields) == 0 && len(cfg.any) == 0 && len(cfg.all) == 0 && cfg.not == nil
 }
 
 // NewMatcher creates a LogMatcher from a set of field names and ValueMatch(s)
-func (cmap LogMatcherConfig) NewMatcher(schema base.LogSchema) LogMatcher {
-	ufieldMatches := make([]unsortedKeyValueMatch, 0, len(cmap))
-	for key, match := range cmap {
-		loc := schema.MustCreateFieldLocator(key)
-		ufieldMatches = append(ufieldMatches, unsortedKeyValueMatch{locator: loc, match: match})
+func (cfg LogMatcherConfig) NewMatcher(schema base.LogSchema) LogMatcher {
+	ufieldMatches := make([]unsortedKeyValueMatch, 0, len(cfg.fields))
+	for key, match := range cfg.fields {
+		loc, extraKey, err := schema.CreateFieldOrExtraLocator(key)
+		if err != nil {
+			logger.Panicf("invalid match key '%s': %s", key, err.Error())
+		}
+		ufieldMatches = append(ufieldMatches, unsortedKeyValueMatch{locator: loc, extraKey: extraKey, match: match})
 	}
 	sort.Sort(sortableKeyValueMatches{ufieldMatches})
 
-	sfieldMatches := make([]keyValueMatch, 0, len(cmap))
+	sfieldMatches := make([]keyValueMatch, 0, len(cfg.fields))
+	cost := 0
 	for _, pair := range ufieldMatches {
-		sfieldMatches = append(sfieldMatches, keyValueMatch{locator: pair.locator, match: pair.match.match})
+		sfieldMatches = append(sfieldMatches, keyValueMatch{locator: pair.locator, extraKey: pair.extraKey, match: pair.match.match})
+		cost += pair.match.cost
+	}
+
+	var groupMatches []groupMatch
+	if len(cfg.any) > 0 {
+		groupMatches = append(groupMatches, newGroupMatch(groupAny, cfg.any, schema))
+	}
+	if len(cfg.all) > 0 {
+		groupMatches = append(groupMatches, newGroupMatch(groupAll, cfg.all, schema))
+	}
+	if cfg.not != nil {
+		groupMatches = append(groupMatches, newGroupMatch(groupNone, []LogMatcherConfig{*cfg.not}, schema))
 	}
-	return LogMatcher{sfieldMatches}
+	sort.SliceStable(groupMatches, func(i, j int) bool {
+		return groupMatches[i].cost < groupMatches[j].cost
+	})
+	for _, gm := range groupMatches {
+		cost += gm.cost
+	}
+	return LogMatcher{fieldMatches: sfieldMatches, groupMatches: groupMatches, cost: cost}
+}
+
+func newGroupMatch(mode groupMode, configs []LogMatcherConfig, schema base.LogSchema) groupMatch {
+	members := make([]LogMatcher, 0, len(configs))
+	cost := 0
+	for _, c := range configs {
+		m := c.NewMatcher(schema)
+		members = append(members, m)
+		cost += m.cost
+	}
+	sort.SliceStable(members, func(i, j int) bool {
+		return members[i].cost < members[j].cost
+	})
+	return groupMatch{mode: mode, members: members, cost: cost}
 }
 
 // VerifyConfig checks all field names
-func (cmap LogMatcherConfig) VerifyConfig(schema base.LogSchema) error {
-	for key, matcher := range cmap {
-		_, err := schema.CreateFieldLocator(key)
-		if err != nil {
+func (cfg LogMatcherConfig) VerifyConfig(schema base.LogSchema) error {
+	for _, key := range []string{groupKeyAny, groupKeyAll, groupKeyNot} {
+		if slices.Contains(schema.GetFieldNames(), key) {
+			return fmt.Errorf("field '%s' in schema cannot be matched because the name is reserved for groups", key)
+		}
+	}
+	for key, matcher := range cfg.fields {
+		if _, _, err := schema.CreateFieldOrExtraLocator(key); err != nil {
 			return fmt.Errorf("invalid match key '%s': %w", key, err)
 		}
 		if matcher.match == nil { // extra check because empty value in map would NOT go through unmarshalling
 			return fmt.Errorf("missing match value for '%s'", key)
 		}
 	}
+	for i, group := range cfg.any {
+		if err := group.verifyGroup(schema); err != nil {
+			return fmt.Errorf("%s[%d]: %w", groupKeyAny, i, err)
+		}
+	}
+	for i, group := range cfg.all {
+		if err := group.verifyGroup(schema); err != nil {
+			return fmt.Errorf("%s[%d]: %w", groupKeyAll, i, err)
+		}
+	}
+	if cfg.not != nil {
+		if err := cfg.not.verifyGroup(schema); err != nil {
+			return fmt.Errorf("%s: %w", groupKeyNot, err)
+		}
+	}
+	return nil
+}
+
+func (cfg LogMatcherConfig) verifyGroup(schema base.LogSchema) error {
+	if cfg.IsEmpty() {
+		return fmt.Errorf("group is empty")
+	}
+	return cfg.VerifyConfig(schema)
+}
+
+// MarshalYAML provides custom marshalling to export readable document. The result is not reversible.
+func (cfg LogMatcherConfig) MarshalYAML() (interface{}, error) {
+	out := make(map[string]interface{}, len(cfg.fields)+3)
+	for key, match := range cfg.fields {
+		out[key] = match
+	}
+	if len(cfg.any) > 0 {
+		out[groupKeyAny] = cfg.any
+	}
+	if len(cfg.all) > 0 {
+		out[groupKeyAll] = cfg.all
+	}
+	if cfg.not != nil {
+		out[groupKeyNot] = cfg.not
+	}
+	return out, nil
+}
+
+// UnmarshalYAML provides custom unmarshalling for field conditions and nested groups
+func (cfg *LogMatcherConfig) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.MappingNode {
+		return util.NewYamlError(value, "match must be a map")
+	}
+	result := LogMatcherConfig{
+		fields: make(map[string]valueMatch, len(value.Content)/2),
+	}
+	keys := make(map[string]bool, len(value.Content)/2)
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		keyNode := value.Content[i]
+		valueNode := value.Content[i+1]
+		if keys[keyNode.Value] {
+			return util.NewYamlError(keyNode, fmt.Sprintf("duplicated match key '%s'", keyNode.Value))
+		}
+		keys[keyNode.Value] = true
+		switch keyNode.Value {
+		case groupKeyAny:
+			if err := valueNode.Decode(&result.any); err != nil {
+				return err
+			}
+		case groupKeyAll:
+			if err := valueNode.Decode(&result.all); err != nil {
+				return err
+			}
+		case groupKeyNot:
+			group := &LogMatcherConfig{}
+			if err := valueNode.Decode(group); err != nil {
+				return err
+			}
+			result.not = group
+		default:
+			var match valueMatch
+			if err := valueNode.Decode(&match); err != nil {
+				return err
+			}
+			result.fields[keyNode.Value] = match
+		}
+	}
+	*cfg = result
 	return nil
 }
 
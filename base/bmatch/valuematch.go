@@ -2,6 +2,7 @@ package bmatch
 
 import (
 	"fmt"
+	"net/netip"
 	"regexp"
 	"strconv"
 	"strings"
@@ -20,21 +21,35 @@ type valueMatch struct {
 }
 
 var valueMatcherConstructors = map[string]valueMatcherConstructor{
-	"!!str":         createValueMatcherStringEqualsTo, // the default tag for string value
-	"!!str-any":     createValueMatcherStringAny,
-	"!!str-eq":      createValueMatcherStringEqualsTo,
-	"!!str-not":     createValueMatcherStringNotEqualsTo,
-	"!!str-start":   createValueMatcherStringStartsWith,
-	"!!str-end":     createValueMatcherStringEndsWith,
-	"!!str-contain": createValueMatcherStringContains,
-	"!!glob":        createValueMatcherGlob,
-	"!!regex":       createValueMatcherRegex,
-	"!!len-gt":      createValueMatcherLengthGreaterThan,
-	"!!len-lt":      createValueMatcherLengthLessThan,
+	"!!str":          createValueMatcherStringEqualsTo, // the default tag for string value
+	"!!str-any":      createValueMatcherStringAny,
+	"!!str-eq":       createValueMatcherStringEqualsTo,
+	"!!str-not":      createValueMatcherStringNotEqualsTo,
+	"!!str-start":    createValueMatcherStringStartsWith,
+	"!!str-end":      createValueMatcherStringEndsWith,
+	"!!str-contain":  createValueMatcherStringContains,
+	"!!glob":         createValueMatcherGlob,
+	"!!regex":        createValueMatcherRegex,
+	"!!len-gt":       createValueMatcherLengthGreaterThan,
+	"!!len-lt":       createValueMatcherLengthLessThan,
+	"!!istr-eq":      createValueMatcherStringEqualsToIgnoreCase,
+	"!!istr-contain": createValueMatcherStringContainsIgnoreCase,
+	"!!num-gt":       createValueMatcherNumberGreaterThan,
+	"!!num-lt":       createValueMatcherNumberLessThan,
+	"!!cidr":         createValueMatcherCIDR,
+}
+
+// valueMatcherListConstructors creates value matchers from YAML sequences, e.g. "!!str-in [a, b, c]"
+var valueMatcherListConstructors = map[string]valueMatcherListConstructor{
+	"!!str-in":      createValueMatcherStringIn,
+	"!!num-between": createValueMatcherNumberBetween,
+	"!!cidr":        createValueMatcherCIDRList,
 }
 
 type valueMatcherConstructor = func(expression string) (valueMatch, error)
 
+type valueMatcherListConstructor = func(expressions []string) (valueMatch, error)
+
 type valueMatcher = func(value string) bool
 
 var emptyValueMatch = valueMatch{}
@@ -49,12 +64,28 @@ func (match valueMatch) String() string {
 }
 
 func (match *valueMatch) UnmarshalYAML(value *yaml.Node) error {
-	creator, found := valueMatcherConstructors[value.Tag]
-	if !found {
-		return util.NewYamlError(value, fmt.Sprintf("Unsupported value-match tag: %s", value.Tag))
+	var m valueMatch
+	var err error
+	if value.Kind == yaml.SequenceNode {
+		creator, found := valueMatcherListConstructors[value.Tag]
+		if !found {
+			return util.NewYamlError(value, fmt.Sprintf("Unsupported value-match tag for list: %s", value.Tag))
+		}
+		items := make([]string, 0, len(value.Content))
+		for _, itemNode := range value.Content {
+			if itemNode.Kind != yaml.ScalarNode {
+				return util.NewYamlError(itemNode, fmt.Sprintf("Non-scalar item in list of value-match tag %s", value.Tag))
+			}
+			items = append(items, itemNode.Value)
+		}
+		m, err = creator(items)
+	} else {
+		creator, found := valueMatcherConstructors[value.Tag]
+		if !found {
+			return util.NewYamlError(value, fmt.Sprintf("Unsupported value-match tag: %s", value.Tag))
+		}
+		m, err = creator(value.Value)
 	}
-
-	m, err := creator(value.Value)
 	if err != nil {
 		return util.NewYamlError(value, fmt.Sprintf("Failed value-match of tag %s: %s", value.Tag, err.Error()))
 	}
@@ -192,3 +223,233 @@ func createValueMatcherLengthLessThan(expr string) (valueMatch, error) {
 		cost:        0,
 	}, nil
 }
+
+// smallSetSize is the max size of value sets to be matched by linear search instead of hash set
+const smallSetSize = 4
+
+func createValueMatcherStringIn(exprList []string) (valueMatch, error) {
+	if len(exprList) == 0 {
+		return emptyValueMatch, fmt.Errorf("list is empty")
+	}
+	for i, expr := range exprList {
+		if expr == "" {
+			return emptyValueMatch, fmt.Errorf("value #%d is empty", i)
+		}
+	}
+	description := "in [" + strings.Join(exprList, ", ") + "]"
+	if len(exprList) <= smallSetSize {
+		cost := 0
+		for _, expr := range exprList {
+			cost += 1 + len(expr)/2
+		}
+		return valueMatch{
+			match: func(v string) bool {
+				for _, expr := range exprList {
+					if v == expr {
+						return true
+					}
+				}
+				return false
+			},
+			description: description,
+			cost:        cost,
+		}, nil
+	}
+	set := make(map[string]struct{}, len(exprList))
+	for _, expr := range exprList {
+		set[expr] = struct{}{}
+	}
+	return valueMatch{
+		match: func(v string) bool {
+			_, found := set[v]
+			return found
+		},
+		description: description,
+		cost:        20,
+	}, nil
+}
+
+func createValueMatcherStringEqualsToIgnoreCase(expr string) (valueMatch, error) {
+	if expr == "" {
+		return emptyValueMatch, fmt.Errorf("value is empty")
+	}
+	return valueMatch{
+		match: func(v string) bool {
+			return strings.EqualFold(v, expr)
+		},
+		description: "i== " + expr,
+		cost:        2 + len(expr),
+	}, nil
+}
+
+func createValueMatcherStringContainsIgnoreCase(expr string) (valueMatch, error) {
+	if expr == "" {
+		return emptyValueMatch, fmt.Errorf("value is empty")
+	}
+	return valueMatch{
+		match: func(v string) bool {
+			return containsFold(v, expr)
+		},
+		description: "i*= " + expr,
+		cost:        1000 + len(expr)*2,
+	}, nil
+}
+
+// containsFold checks whether s contains substr under simple Unicode case-folding, by comparing substrings of the
+// same length in bytes. Characters whose folded forms differ in length, e.g. "K" vs Kelvin sign, are not matched.
+func containsFold(s string, substr string) bool {
+	n := len(substr)
+	for i := 0; i+n <= len(s); i++ {
+		if strings.EqualFold(s[i:i+n], substr) {
+			return true
+		}
+	}
+	return false
+}
+
+func createValueMatcherNumberGreaterThan(expr string) (valueMatch, error) {
+	target, err := strconv.ParseInt(expr, 10, 64)
+	if err != nil {
+		return emptyValueMatch, err
+	}
+	return valueMatch{
+		match: func(v string) bool {
+			num, ok := parseInteger(v)
+			return ok && num > target
+		},
+		description: "num > " + expr,
+		cost:        10,
+	}, nil
+}
+
+func createValueMatcherNumberLessThan(expr string) (valueMatch, error) {
+	target, err := strconv.ParseInt(expr, 10, 64)
+	if err != nil {
+		return emptyValueMatch, err
+	}
+	return valueMatch{
+		match: func(v string) bool {
+			num, ok := parseInteger(v)
+			return ok && num < target
+		},
+		description: "num < " + expr,
+		cost:        10,
+	}, nil
+}
+
+func createValueMatcherNumberBetween(exprList []string) (valueMatch, error) {
+	if len(exprList) != 2 {
+		return emptyValueMatch, fmt.Errorf("list must contain two numbers for min and max (inclusive)")
+	}
+	min, minErr := strconv.ParseInt(exprList[0], 10, 64)
+	if minErr != nil {
+		return emptyValueMatch, minErr
+	}
+	max, maxErr := strconv.ParseInt(exprList[1], 10, 64)
+	if maxErr != nil {
+		return emptyValueMatch, maxErr
+	}
+	if min > max {
+		return emptyValueMatch, fmt.Errorf("min is larger than max")
+	}
+	return valueMatch{
+		match: func(v string) bool {
+			num, ok := parseInteger(v)
+			return ok && num >= min && num <= max
+		},
+		description: "num in " + exprList[0] + ".." + exprList[1],
+		cost:        10,
+	}, nil
+}
+
+// parseInteger parses decimal integer with optional sign, without allocation on failures unlike strconv.ParseInt
+func parseInteger(s string) (int64, bool) {
+	if len(s) == 0 || len(s) > 18 { // no overflow check needed for up to 18 digits
+		return 0, false
+	}
+	negative := false
+	switch s[0] {
+	case '-':
+		negative = true
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+	if len(s) == 0 {
+		return 0, false
+	}
+	var num int64
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		num = num*10 + int64(c-'0')
+	}
+	if negative {
+		return -num, true
+	}
+	return num, true
+}
+
+func createValueMatcherCIDR(expr string) (valueMatch, error) {
+	return createValueMatcherCIDRList([]string{expr})
+}
+
+func createValueMatcherCIDRList(exprList []string) (valueMatch, error) {
+	if len(exprList) == 0 {
+		return emptyValueMatch, fmt.Errorf("list is empty")
+	}
+	prefixes := make([]netip.Prefix, 0, len(exprList))
+	for _, expr := range exprList {
+		prefix, err := netip.ParsePrefix(expr)
+		if err != nil {
+			return emptyValueMatch, err
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return valueMatch{
+		match: func(v string) bool {
+			if !looksLikeAddr(v) {
+				return false
+			}
+			addr, err := netip.ParseAddr(v)
+			if err != nil {
+				return false
+			}
+			addr = addr.Unmap()
+			for _, prefix := range prefixes {
+				if prefix.Contains(addr) {
+					return true
+				}
+			}
+			return false
+		},
+		description: "in-cidr " + strings.Join(exprList, ", "),
+		cost:        50 + 5*len(prefixes),
+	}, nil
+}
+
+// maxAddrLength is the max length of IP addresses in text, as in "ffff:ffff:ffff:ffff:ffff:ffff:255.255.255.255"
+const maxAddrLength = 45
+
+// looksLikeAddr checks whether the value consists of only characters of IPv4 or IPv6 addresses without zone, to skip
+// parsing of other values as netip.ParseAddr allocates an error for each failure
+func looksLikeAddr(v string) bool {
+	if len(v) < 2 || len(v) > maxAddrLength {
+		return false
+	}
+	separated := false
+	for i := 0; i < len(v); i++ {
+		c := v[i]
+		switch {
+		case c == '.' || c == ':':
+			separated = true
+		case c >= '0' && c <= '9':
+		case c|0x20 >= 'a' && c|0x20 <= 'f': // hex letter in either case
+		default:
+			return false
+		}
+	}
+	return separated
+}
@@ -91,6 +91,83 @@ func TestValueMatchLengthLessThan(t *testing.T) {
 	}
 }
 
+func TestValueMatchStringIn(t *testing.T) {
+	if f := tryBuildMatch(t, `value: !!str-in [GET, HEAD]`); f != nil {
+		assert.True(t, f("HEAD"))
+		assert.False(t, f("POST"))
+		assert.False(t, f(""))
+	}
+	if f := tryBuildMatch(t, `value: !!str-in [a, b, c, d, e, f]`); f != nil {
+		assert.True(t, f("a"))
+		assert.True(t, f("f"))
+		assert.False(t, f("g"))
+	}
+	d := &valueMatchTestData{}
+	assert.EqualError(t, util.UnmarshalYamlString(`value: !!str-in []`, d), "yaml line 1:8: Failed value-match of tag !!str-in: list is empty")
+	assert.EqualError(t, util.UnmarshalYamlString(`value: !!str-eq [a]`, d), "yaml line 1:8: Unsupported value-match tag for list: !!str-eq")
+	assert.EqualError(t, util.UnmarshalYamlString(`value: !!str-in [[a]]`, d), "yaml line 1:18: Non-scalar item in list of value-match tag !!str-in")
+}
+
+func TestValueMatchIgnoreCase(t *testing.T) {
+	if f := tryBuildMatch(t, `value: !!istr-eq Error`); f != nil {
+		assert.True(t, f("ERROR"))
+		assert.True(t, f("error"))
+		assert.False(t, f("errors"))
+	}
+	if f := tryBuildMatch(t, `value: !!istr-contain timeout`); f != nil {
+		assert.True(t, f("Connection TimeOut!"))
+		assert.True(t, f("timeout"))
+		assert.False(t, f("time out"))
+	}
+}
+
+func TestValueMatchNumber(t *testing.T) {
+	if f := tryBuildMatch(t, `value: !!num-gt 499`); f != nil {
+		assert.True(t, f("500"))
+		assert.False(t, f("499"))
+		assert.False(t, f("5xx"))
+		assert.False(t, f(""))
+	}
+	if f := tryBuildMatch(t, `value: !!num-lt -5`); f != nil {
+		assert.True(t, f("-6"))
+		assert.False(t, f("-5"))
+		assert.False(t, f("-"))
+	}
+	if f := tryBuildMatch(t, `value: !!num-between [200, 299]`); f != nil {
+		assert.True(t, f("200"))
+		assert.True(t, f("+299"))
+		assert.False(t, f("300"))
+		assert.False(t, f("1234567890123456789"))
+	}
+	d := &valueMatchTestData{}
+	assert.EqualError(t, util.UnmarshalYamlString(`value: !!num-between [3, 2]`, d), "yaml line 1:8: Failed value-match of tag !!num-between: min is larger than max")
+	assert.ErrorContains(t, util.UnmarshalYamlString(`value: !!num-gt 1.5`, d), "invalid syntax")
+}
+
+func TestValueMatchCIDR(t *testing.T) {
+	if f := tryBuildMatch(t, `value: !!cidr 10.0.0.0/8`); f != nil {
+		assert.True(t, f("10.1.2.3"))
+		assert.True(t, f("::ffff:10.1.2.3"))
+		assert.False(t, f("192.168.0.1"))
+		assert.False(t, f("localhost"))
+	}
+	if f := tryBuildMatch(t, `value: !!cidr [192.168.1.1/16, "fd00::/8"]`); f != nil {
+		assert.True(t, f("192.168.3.4"))
+		assert.True(t, f("fd12::1"))
+		assert.False(t, f("10.1.2.3"))
+		assert.False(t, f("FD12::1%eth0"))
+		assert.False(t, f("cafe"))
+		assert.Zero(t, testing.AllocsPerRun(10, func() {
+			f("192.168.3.4")
+			f("GET /index.html")
+			f("fd12::zz")
+			f("")
+		}))
+	}
+	d := &valueMatchTestData{}
+	assert.ErrorContains(t, util.UnmarshalYamlString(`value: !!cidr 10.0.0.0`, d), "no '/'")
+}
+
 func tryBuildMatch(t *testing.T, matcherYAML string) valueMatcher {
 	d := &valueMatchTestData{}
 
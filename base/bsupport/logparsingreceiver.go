@@ -17,6 +17,7 @@ type logParsingReceiver struct {
 	createParser   LogParserConstructor
 	outputReceiver base.MultiSinkBufferReceiver
 	metricCreator  promreg.MetricCreator
+	customMetrics  *base.LogCustomMetricRegistry
 }
 
 type logParsingReceiverSink struct {
@@ -33,19 +34,20 @@ type logParsingReceiverSink struct {
 //
 // Actual parsers are created on demand for each of connections
 func NewLogParsingReceiver(parentLogger logger.Logger, createParser LogParserConstructor, nextReceiver base.MultiSinkBufferReceiver,
-	metricCreator promreg.MetricCreator,
+	metricCreator promreg.MetricCreator, customMetrics *base.LogCustomMetricRegistry,
 ) base.MultiSinkMessageReceiver {
 	return &logParsingReceiver{
 		logger:         parentLogger.WithField(defs.LabelComponent, "LogParsingReceiver"),
 		createParser:   createParser,
 		outputReceiver: nextReceiver,
 		metricCreator:  metricCreator,
+		customMetrics:  customMetrics,
 	}
 }
 
 func (recv *logParsingReceiver) NewSink(clientAddress string, clientNumber base.ClientNumber) base.MessageReceiverSink {
 	slogger := base.NewSinkLogger(recv.logger, clientAddress, clientNumber)
-	inputCounter := base.NewLogInputCounter(recv.metricCreator)
+	inputCounter := base.NewLogInputCounter(recv.metricCreator, recv.customMetrics)
 	return &logParsingReceiverSink{
 		logger:        slogger,
 		parser:        recv.createParser(slogger, inputCounter),
@@ -82,6 +84,9 @@ func (sess *logParsingReceiverSink) Flush() {
 // Close ends this sink
 func (sess *logParsingReceiverSink) Close() {
 	sess.logger.Info("close")
+	if closer, ok := sess.parser.(base.LogParserCloser); ok {
+		closer.Close()
+	}
 	sess.outputSink.Close()
 	sess.inputCounter.UpdateMetrics()
 }
@@ -11,35 +11,23 @@ import (
 // LogProcessingWorker is a worker for transformation, serialization and chunk making
 type LogProcessingWorker struct {
 	PipelineWorkerBase[[]*base.LogRecord]
-	deallocator   *base.LogAllocator
+	processor     *LogProcessor
 	procCounter   *base.LogProcessCounterSet
-	transformList []base.LogTransformFunc
-	outputList    []OutputInterface
 	lastChunkTime time.Time
 }
 
-// OutputInterface is a joint interface of output components
-type OutputInterface struct {
-	base.LogSerializer
-	base.LogChunkMaker
-	Name        string
-	AcceptChunk base.LogChunkAccepter
-}
-
 // NewLogProcessingWorker creates LogProcessingWorker
 func NewLogProcessingWorker(parentLogger logger.Logger,
 	input <-chan []*base.LogRecord, deallocator *base.LogAllocator, procCounter *base.LogProcessCounterSet,
-	transforms []base.LogTransformFunc, outputInterfaces []OutputInterface,
+	transformStages []LogTransformStage, outputInterfaces []OutputInterface,
 ) *LogProcessingWorker {
 	worker := &LogProcessingWorker{
 		PipelineWorkerBase: NewPipelineWorkerBase(
 			parentLogger.WithField(defs.LabelComponent, "LogProcessingWorker"),
 			input,
 		),
-		deallocator:   deallocator,
+		processor:     NewLogProcessor(deallocator, procCounter, transformStages, outputInterfaces),
 		procCounter:   procCounter,
-		transformList: transforms,
-		outputList:    outputInterfaces,
 		lastChunkTime: time.Now(),
 	}
 	worker.InitInternal(worker.onInput, worker.onTick, worker.onStop)
@@ -47,37 +35,7 @@ func NewLogProcessingWorker(parentLogger logger.Logger,
 }
 
 func (worker *LogProcessingWorker) onInput(buffer []*base.LogRecord) {
-	if len(buffer) == 0 {
-		return
-	}
-	for _, record := range buffer {
-		icounter := worker.procCounter.SelectMetricKeySet(record)
-		if RunTransforms(record, worker.transformList) == base.DROP {
-			icounter.CountRecordDrop(record)
-			worker.deallocator.Release(record)
-			continue
-		}
-		icounter.CountRecordPass(record)
-
-		for i, output := range worker.outputList {
-			// TODO:
-			//if RunTransforms(record, output.transformList) == base.DROP {
-			//	icounter.CountOutputFilter(i, record)
-			//	worker.deallocator.Release(record)
-			//	continue
-			//}
-			stream := output.SerializeRecord(record)
-			// TODO: decide whether to release once at the end or release here after per-output transform is implemented
-			// It will depend on whether records are duplicated for additional outputs, or the same record with all transforms run in place.
-			worker.deallocator.Release(record)
-			worker.procCounter.CountStream(i, stream)
-			maybeChunk := output.WriteStream(stream)
-			if maybeChunk != nil {
-				worker.procCounter.CountChunk(i, maybeChunk)
-				output.AcceptChunk(*maybeChunk)
-			}
-		}
-	}
+	worker.processor.ProcessBuffer(buffer)
 }
 
 func (worker *LogProcessingWorker) onTick() {
@@ -85,21 +43,12 @@ func (worker *LogProcessingWorker) onTick() {
 	if time.Since(worker.lastChunkTime) < defs.IntermediateFlushInterval {
 		return
 	}
-	worker.flushChunk()
+	worker.processor.FlushChunks()
 	worker.procCounter.UpdateMetrics()
 }
 
 func (worker *LogProcessingWorker) onStop() {
-	worker.flushChunk()
+	worker.processor.FlushTransforms()
+	worker.processor.FlushChunks()
 	worker.procCounter.UpdateMetrics()
 }
-
-func (worker *LogProcessingWorker) flushChunk() {
-	for i, output := range worker.outputList {
-		maybeChunk := output.FlushBuffer()
-		if maybeChunk != nil {
-			worker.procCounter.CountChunk(i, maybeChunk)
-			output.AcceptChunk(*maybeChunk)
-		}
-	}
-}
@@ -0,0 +1,238 @@
+package bsupport
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/relex/gotils/logger"
+	"github.com/relex/gotils/promexporter/promext"
+	"github.com/relex/gotils/promexporter/promreg"
+	"github.com/relex/slog-agent/base"
+	"github.com/stretchr/testify/assert"
+)
+
+// testLogOutput serializes the "log" field and collects all streams
+type testLogOutput struct {
+	streams []string
+}
+
+func (output *testLogOutput) SerializeRecord(record *base.LogRecord) base.LogStream {
+	return base.LogStream(record.Fields[1])
+}
+
+func (output *testLogOutput) WriteStream(stream base.LogStream) *base.LogChunk {
+	output.streams = append(output.streams, string(stream))
+	return nil
+}
+
+func (output *testLogOutput) FlushBuffer() *base.LogChunk {
+	return nil
+}
+
+func TestLogProcessingWorkerByStages(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"level", "log"})
+	allocator := base.NewLogAllocator(schema, 1)
+	mfactory := promreg.NewMetricFactory("test_", nil, nil)
+	pcounter := base.NewLogProcessCounter(mfactory, base.NewLogCustomMetricRegistry("test_"), schema, []base.LogFieldLocator{schema.MustCreateFieldLocator("level")}, []string{"default"})
+	countSeen := pcounter.RegisterCustomCounter("seen")
+
+	var batchSizes []int
+	stages := []LogTransformStage{
+		{Transforms: []base.LogTransformFunc{func(record *base.LogRecord) base.FilterResult {
+			countSeen(record.RawLength)
+			return base.FilterResult(record.Fields[0] != "debug")
+		}}},
+		{Batch: func(records []*base.LogRecord, results []base.FilterResult, selectRecord base.LogRecordSelector) {
+			batchSizes = append(batchSizes, len(records))
+			for i, record := range records {
+				record.Fields[0] = "changed" // key fields changed in the middle should not affect metrics
+				selectRecord(i)
+				countSeen(record.RawLength)
+				results[i] = base.FilterResult(record.Fields[1] != "drop")
+			}
+		}},
+		{Transforms: []base.LogTransformFunc{func(record *base.LogRecord) base.FilterResult {
+			countSeen(record.RawLength)
+			return base.PASS
+		}}},
+	}
+	output := &testLogOutput{}
+	worker := NewLogProcessingWorker(logger.Root(), nil, allocator, pcounter, stages, []OutputInterface{{
+		LogSerializer: output,
+		LogChunkMaker: output,
+		Name:          "default",
+		AcceptChunk:   func(base.LogChunk) {},
+		Transforms:    nil,
+	}})
+
+	newRecord := func(level string, log string) *base.LogRecord {
+		record, input := allocator.NewRecord([]byte(level + log))
+		record.Fields[0] = input[:len(level)]
+		record.Fields[1] = input[len(level):]
+		record.RawLength = len(input)
+		return record
+	}
+	worker.onInput([]*base.LogRecord{
+		newRecord("info", "a"),
+		newRecord("debug", "b"),
+		newRecord("warn", "drop"),
+		newRecord("warn", "c"),
+	})
+	pcounter.UpdateMetrics()
+
+	assert.Equal(t, []int{3}, batchSizes)
+	assert.Equal(t, []string{"a", "c"}, output.streams)
+	assert.Equal(t, `test_labelled_record_bytes_total{key_level="debug",label="seen"} 6
+test_labelled_record_bytes_total{key_level="info",label="seen"} 15
+test_labelled_record_bytes_total{key_level="warn",label="seen"} 31
+test_labelled_records_total{key_level="debug",label="seen"} 1
+test_labelled_records_total{key_level="info",label="seen"} 3
+test_labelled_records_total{key_level="warn",label="seen"} 5
+`, promext.DumpMetrics("test_labelled_", true, false, mfactory))
+}
+
+func TestLogProcessingWorkerExpand(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"level", "log"})
+	allocator := base.NewLogAllocator(schema, 1)
+	mfactory := promreg.NewMetricFactory("test_", nil, nil)
+	pcounter := base.NewLogProcessCounter(mfactory, base.NewLogCustomMetricRegistry("test_"), schema, []base.LogFieldLocator{schema.MustCreateFieldLocator("level")}, []string{"default"})
+	countSeen := pcounter.RegisterCustomCounter("seen")
+
+	stages := []LogTransformStage{
+		{Expand: func(input *base.LogRecord, alloc *base.LogAllocator, outputs []*base.LogRecord) []*base.LogRecord {
+			switch whole := input.Fields[1]; whole {
+			case "drop":
+				return outputs
+			case "keep":
+				return append(outputs, input)
+			default:
+				for _, part := range strings.Split(whole, ",") {
+					input.Fields[1] = part
+					outputs = append(outputs, alloc.DeriveRecord(input))
+				}
+				return outputs
+			}
+		}},
+		{Transforms: []base.LogTransformFunc{func(record *base.LogRecord) base.FilterResult {
+			countSeen(record.RawLength)
+			return base.FilterResult(record.Fields[1] != "y")
+		}}},
+	}
+	output := &testLogOutput{}
+	worker := NewLogProcessingWorker(logger.Root(), nil, allocator, pcounter, stages, []OutputInterface{{
+		LogSerializer: output,
+		LogChunkMaker: output,
+		Name:          "default",
+		AcceptChunk:   func(base.LogChunk) {},
+		Transforms:    nil,
+	}})
+
+	newRecord := func(level string, log string) *base.LogRecord {
+		record, input := allocator.NewRecord([]byte(level + log))
+		record.Fields[0] = input[:len(level)]
+		record.Fields[1] = input[len(level):]
+		record.RawLength = len(input)
+		return record
+	}
+	for round := 0; round < 2; round++ { // second round with reused buffers
+		output.streams = nil
+		worker.onInput([]*base.LogRecord{
+			newRecord("info", "a,b,c"),
+			newRecord("warn", "drop"),
+			newRecord("warn", "keep"),
+			newRecord("error", "x,y,z"),
+		})
+		assert.Equal(t, []string{"a", "b", "c", "keep", "x", "z"}, output.streams)
+	}
+	pcounter.UpdateMetrics()
+
+	assert.Equal(t, `test_labelled_records_total{key_level="error",label="seen"} 6
+test_labelled_records_total{key_level="info",label="seen"} 6
+test_labelled_records_total{key_level="warn",label="seen"} 2
+`, promext.DumpMetrics("test_labelled_records_total", true, false, mfactory))
+
+	// each received record is counted once as passed or dropped, and derived records are counted apart
+	assert.Equal(t, `test_passed_records_total{key_level="error"} 2
+test_passed_records_total{key_level="info"} 2
+test_passed_records_total{key_level="warn"} 2
+`, promext.DumpMetrics("test_passed_records_total", true, false, mfactory))
+	assert.Equal(t, `test_dropped_records_total{key_level="error"} 0
+test_dropped_records_total{key_level="info"} 0
+test_dropped_records_total{key_level="warn"} 2
+`, promext.DumpMetrics("test_dropped_records_total", true, false, mfactory))
+	assert.Equal(t, `test_derived_passed_records_total{key_level="error"} 4
+test_derived_passed_records_total{key_level="info"} 6
+test_derived_passed_records_total{key_level="warn"} 0
+`, promext.DumpMetrics("test_derived_passed_records_total", true, false, mfactory))
+	assert.Equal(t, `test_derived_dropped_records_total{key_level="error"} 2
+test_derived_dropped_records_total{key_level="info"} 0
+test_derived_dropped_records_total{key_level="warn"} 0
+`, promext.DumpMetrics("test_derived_dropped_records_total", true, false, mfactory))
+}
+
+func TestLogProcessingWorkerFlush(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"level", "log"})
+	allocator := base.NewLogAllocator(schema, 1)
+	mfactory := promreg.NewMetricFactory("test_", nil, nil)
+	pcounter := base.NewLogProcessCounter(mfactory, base.NewLogCustomMetricRegistry("test_"), schema, []base.LogFieldLocator{schema.MustCreateFieldLocator("level")}, []string{"default"})
+
+	var held []*base.LogRecord
+	release := false
+	stages := []LogTransformStage{
+		{
+			Expand: func(input *base.LogRecord, alloc *base.LogAllocator, outputs []*base.LogRecord) []*base.LogRecord {
+				release = input.Fields[1] == "release"
+				if input.Fields[1] == "hold" {
+					held = append(held, alloc.DeriveRecord(input))
+					return outputs
+				}
+				return append(outputs, input)
+			},
+			Flush: func(outputs []*base.LogRecord, final bool) []*base.LogRecord {
+				if release || final {
+					outputs = append(outputs, held...)
+					held = nil
+				}
+				return outputs
+			},
+		},
+		{Transforms: []base.LogTransformFunc{func(record *base.LogRecord) base.FilterResult {
+			record.Fields[1] += "!"
+			return base.PASS
+		}}},
+	}
+	output := &testLogOutput{}
+	worker := NewLogProcessingWorker(logger.Root(), nil, allocator, pcounter, stages, []OutputInterface{{
+		LogSerializer: output,
+		LogChunkMaker: output,
+		Name:          "default",
+		AcceptChunk:   func(base.LogChunk) {},
+		Transforms:    nil,
+	}})
+
+	newRecord := func(level string, log string) *base.LogRecord {
+		record, input := allocator.NewRecord([]byte(level + log))
+		record.Fields[0] = input[:len(level)]
+		record.Fields[1] = input[len(level):]
+		record.RawLength = len(input)
+		return record
+	}
+	worker.onInput([]*base.LogRecord{
+		newRecord("warn", "hold"),
+		newRecord("info", "release"),
+		newRecord("error", "hold"),
+	})
+	assert.Equal(t, []string{"release!", "hold!"}, output.streams)
+	worker.onStop()
+	assert.Equal(t, []string{"release!", "hold!", "hold!"}, output.streams)
+
+	// held records are counted under the metric keys of the records they're derived from, apart from received records
+	assert.Equal(t, `test_derived_passed_records_total{key_level="error"} 1
+test_derived_passed_records_total{key_level="info"} 0
+test_derived_passed_records_total{key_level="warn"} 1
+`, promext.DumpMetrics("test_derived_passed_records_total", true, false, mfactory))
+	assert.Equal(t, `test_passed_records_total{key_level="error"} 0
+test_passed_records_total{key_level="info"} 1
+test_passed_records_total{key_level="warn"} 0
+`, promext.DumpMetrics("test_passed_records_total", true, false, mfactory))
+}
@@ -0,0 +1,216 @@
+package bsupport
+
+import (
+	"github.com/relex/slog-agent/base"
+)
+
+// LogProcessor performs transformation, serialization and chunk making on buffers of log records
+//
+// It's used by LogProcessingWorker and can be called directly without goroutines, e.g. in test pipelines.
+type LogProcessor struct {
+	deallocator   *base.LogAllocator
+	procCounter   *base.LogProcessCounterSet
+	transformList []base.LogTransformFunc // transforms if there is no batch or expand transform, or nil
+	stageList     []LogTransformStage     // transform stages if there are batch or expand transforms, or nil
+	outputList    []OutputInterface
+	stageRecords  []*base.LogRecord      // reused buffer of remaining records in stages
+	stageResults  []base.FilterResult    // reused buffer of results for stageRecords
+	spareRecords  []*base.LogRecord      // reused buffer to be swapped with stageRecords in expand stages
+	batchRecords  []*base.LogRecord      // records in the running batch stage
+	selectRecord  base.LogRecordSelector // selector of records in the running batch stage
+}
+
+// OutputInterface is a joint interface of output components
+type OutputInterface struct {
+	base.LogSerializer
+	base.LogChunkMaker
+	Name        string
+	AcceptChunk base.LogChunkAccepter
+	Transforms  []base.LogTransformFunc // optional transforms for this output only
+}
+
+// NewLogProcessor creates LogProcessor
+func NewLogProcessor(deallocator *base.LogAllocator, procCounter *base.LogProcessCounterSet,
+	transformStages []LogTransformStage, outputInterfaces []OutputInterface,
+) *LogProcessor {
+	proc := &LogProcessor{
+		deallocator:   deallocator,
+		procCounter:   procCounter,
+		transformList: nil,
+		stageList:     nil,
+		outputList:    outputInterfaces,
+		stageRecords:  nil,
+		stageResults:  nil,
+		spareRecords:  nil,
+		batchRecords:  nil,
+		selectRecord:  nil,
+	}
+	proc.selectRecord = func(index int) {
+		proc.procCounter.ReselectMetricKeySet(proc.batchRecords[index])
+	}
+	switch {
+	case len(transformStages) == 1 && transformStages[0].Batch == nil && transformStages[0].Expand == nil:
+		proc.transformList = transformStages[0].Transforms
+	case len(transformStages) > 0:
+		proc.stageList = transformStages
+	}
+	return proc
+}
+
+// ProcessBuffer runs transforms on the records in buffer and writes the remaining ones to outputs
+func (proc *LogProcessor) ProcessBuffer(buffer []*base.LogRecord) {
+	if len(buffer) == 0 {
+		return
+	}
+	if proc.stageList != nil {
+		records := append(proc.stageRecords[:0], buffer...)
+		for _, record := range records {
+			proc.procCounter.SelectMetricKeySet(record)
+		}
+		proc.processByStages(records, proc.stageList)
+		return
+	}
+	for _, record := range buffer {
+		icounter := proc.procCounter.SelectMetricKeySet(record)
+		if RunTransforms(record, proc.transformList) == base.DROP {
+			icounter.CountRecordDrop(record)
+			proc.deallocator.Discard(record)
+			continue
+		}
+		icounter.CountRecordPass(record)
+		proc.writeOutputs(record)
+	}
+}
+
+// FlushTransforms passes all the records held by expand transforms through the following stages to outputs, e.g.
+// kept records of ongoing runs in dedup, before the pipeline stops
+func (proc *LogProcessor) FlushTransforms() {
+	for i, stage := range proc.stageList {
+		if stage.Flush == nil {
+			continue
+		}
+		records := stage.Flush(proc.stageRecords[:0], true)
+		if len(records) > 0 {
+			proc.processByStages(records, proc.stageList[i+1:])
+		}
+	}
+}
+
+// processByStages runs transforms stage by stage on the whole buffer, for batch transforms to be called once per
+// buffer instead of per record and for expand transforms to replace records in the buffer
+//
+// Metric key-sets must have been selected for the records or the records they're derived from.
+func (proc *LogProcessor) processByStages(records []*base.LogRecord, stages []LogTransformStage) {
+	for _, stage := range stages {
+		if stage.Expand != nil {
+			expanded := proc.expandRecords(stage, records)
+			proc.spareRecords = records[:0]
+			records = expanded
+			continue
+		}
+		if cap(proc.stageResults) < len(records) {
+			proc.stageResults = make([]base.FilterResult, len(records))
+		}
+		results := proc.stageResults[:len(records)]
+		if stage.Batch != nil {
+			proc.batchRecords = records
+			stage.Batch(records, results, proc.selectRecord)
+			proc.batchRecords = nil
+		} else {
+			for i, record := range records {
+				proc.procCounter.ReselectMetricKeySet(record)
+				results[i] = RunTransforms(record, stage.Transforms)
+			}
+		}
+		numRemaining := 0
+		for i, record := range records {
+			if results[i] == base.DROP {
+				proc.procCounter.CountRecordDrop(record)
+				proc.deallocator.Discard(record)
+				continue
+			}
+			records[numRemaining] = record
+			numRemaining++
+		}
+		records = records[:numRemaining]
+	}
+
+	for _, record := range records {
+		proc.procCounter.ReselectMetricKeySet(record)
+		proc.procCounter.CountRecordPass(record)
+		proc.writeOutputs(record)
+	}
+	// keep the buffers but not the records in them
+	proc.stageRecords = records[:0]
+	clear(proc.stageRecords[:cap(proc.stageRecords)])
+	clear(proc.spareRecords[:cap(proc.spareRecords)])
+}
+
+// expandRecords replaces each of the records with its derived records in the spare buffer, which is returned
+//
+// Derived records inherit the metric key-sets of the records they're derived from, including records held by the
+// transform and flushed after other records. A record replaced by derived ones is counted as passed here, while the
+// derived records are counted apart when they pass or get dropped.
+func (proc *LogProcessor) expandRecords(stage LogTransformStage, records []*base.LogRecord) []*base.LogRecord {
+	expanded := proc.spareRecords[:0]
+	for _, record := range records {
+		proc.procCounter.ReselectMetricKeySet(record)
+		start := len(expanded)
+		expanded = stage.Expand(record, proc.deallocator, expanded)
+		kept := false
+		for _, derived := range expanded[start:] {
+			kept = kept || derived == record
+		}
+		switch {
+		case len(expanded) == start:
+			proc.procCounter.CountRecordDrop(record)
+			proc.deallocator.Discard(record)
+		case !kept:
+			proc.procCounter.CountRecordPass(record)
+			proc.deallocator.Discard(record)
+		}
+		if stage.Flush != nil {
+			expanded = stage.Flush(expanded, false)
+		}
+	}
+	return expanded
+}
+
+func (proc *LogProcessor) writeOutputs(record *base.LogRecord) {
+	lastIndex := len(proc.outputList) - 1
+	for i, output := range proc.outputList {
+		// Each output holds one reference of the record. If there are output transforms, they're run on a copy
+		// unless this is the last output and nothing else would see the changes.
+		outputRecord := record
+		if len(output.Transforms) > 0 {
+			if i < lastIndex {
+				outputRecord = proc.deallocator.CopyRecord(record)
+				proc.deallocator.Release(record)
+			}
+			if RunTransforms(outputRecord, output.Transforms) == base.DROP {
+				proc.procCounter.CountOutputFilter(i, outputRecord)
+				proc.deallocator.Release(outputRecord)
+				continue
+			}
+		}
+		stream := output.SerializeRecord(outputRecord)
+		proc.deallocator.Release(outputRecord)
+		proc.procCounter.CountStream(i, stream)
+		maybeChunk := output.WriteStream(stream)
+		if maybeChunk != nil {
+			proc.procCounter.CountChunk(i, maybeChunk)
+			output.AcceptChunk(*maybeChunk)
+		}
+	}
+}
+
+// FlushChunks sends buffered streams of all outputs as chunks
+func (proc *LogProcessor) FlushChunks() {
+	for i, output := range proc.outputList {
+		maybeChunk := output.FlushBuffer()
+		if maybeChunk != nil {
+			proc.procCounter.CountChunk(i, maybeChunk)
+			output.AcceptChunk(*maybeChunk)
+		}
+	}
+}
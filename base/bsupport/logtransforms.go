@@ -19,24 +19,107 @@ func RunTransforms(record *base.LogRecord, transforms []base.LogTransformFunc) b
 	return base.PASS
 }
 
+// LogTransformStage is a part of top-level transforms to run on a buffer of records: either a sequence of transforms
+// to run record by record, a batch transform to run on all the remaining records at once, or an expand transform to
+// replace each of the remaining records with derived ones
+type LogTransformStage struct {
+	Transforms []base.LogTransformFunc     // transforms to run per record, if Batch and Expand are nil
+	Batch      base.LogBatchTransformFunc  // batch transform
+	Expand     base.LogExpandTransformFunc // expand transform
+	Flush      base.LogExpandFlushFunc     // optional function to pass records held by the expand transform
+}
+
 // NewTransformsFromConfig creates transforms from a list of transform configurations
+//
+// The returned close function must be called to release resources held by the transforms when they're no longer used
 func NewTransformsFromConfig(transformConfigs []bconfig.LogTransformConfigHolder, schema base.LogSchema,
 	parentLogger logger.Logger, customCounterHost base.LogCustomCounterRegistry,
-) []base.LogTransformFunc {
+) ([]base.LogTransformFunc, func()) {
 	transforms := make([]base.LogTransformFunc, len(transformConfigs))
+	var closers []base.LogTransformCloser
 	for i, tc := range transformConfigs {
-		tlogger := parentLogger.WithFields(logger.Fields{
-			defs.LabelPart:   tc.Value.GetType(),
-			defs.LabelSource: tc.Location,
-		})
-		transforms[i] = tc.Value.NewTransform(schema, tlogger, customCounterHost).Transform
+		tf := newTransformFromConfig(tc, schema, parentLogger, customCounterHost)
+		transforms[i] = tf.Transform
+		if closer, ok := tf.(base.LogTransformCloser); ok {
+			closers = append(closers, closer)
+		}
+	}
+	return transforms, newTransformsCloseFunc(closers)
+}
+
+// NewTransformStagesFromConfig creates transforms from a list of transform configurations, grouped into stages to run
+// on buffers of records. Each batch or expand transform becomes a stage of its own.
+//
+// The returned close function must be called to release resources held by the transforms when they're no longer used
+func NewTransformStagesFromConfig(transformConfigs []bconfig.LogTransformConfigHolder, schema base.LogSchema,
+	parentLogger logger.Logger, customCounterHost base.LogCustomCounterRegistry,
+) ([]LogTransformStage, func()) {
+	stages := make([]LogTransformStage, 0, 1)
+	var transforms []base.LogTransformFunc
+	var closers []base.LogTransformCloser
+	for _, tc := range transformConfigs {
+		tf := newTransformFromConfig(tc, schema, parentLogger, customCounterHost)
+		if closer, ok := tf.(base.LogTransformCloser); ok {
+			closers = append(closers, closer)
+		}
+		var stage LogTransformStage
+		switch stf := tf.(type) {
+		case base.LogBatchTransform:
+			stage = LogTransformStage{Transforms: nil, Batch: stf.TransformBatch, Expand: nil, Flush: nil}
+		case base.LogExpandFlusher:
+			stage = LogTransformStage{Transforms: nil, Batch: nil, Expand: stf.ExpandRecord, Flush: stf.FlushRecords}
+		case base.LogExpandTransform:
+			stage = LogTransformStage{Transforms: nil, Batch: nil, Expand: stf.ExpandRecord, Flush: nil}
+		default:
+			transforms = append(transforms, tf.Transform)
+			continue
+		}
+		if len(transforms) > 0 {
+			stages = append(stages, LogTransformStage{Transforms: transforms, Batch: nil, Expand: nil, Flush: nil})
+			transforms = nil
+		}
+		stages = append(stages, stage)
+	}
+	if len(transforms) > 0 {
+		stages = append(stages, LogTransformStage{Transforms: transforms, Batch: nil, Expand: nil, Flush: nil})
 	}
-	return transforms
+	return stages, newTransformsCloseFunc(closers)
 }
 
-// VerifyTransformConfigs verifies a list of transform configurations
+func newTransformsCloseFunc(closers []base.LogTransformCloser) func() {
+	return func() {
+		for _, closer := range closers {
+			closer.Close()
+		}
+	}
+}
+
+func newTransformFromConfig(tc bconfig.LogTransformConfigHolder, schema base.LogSchema,
+	parentLogger logger.Logger, customCounterHost base.LogCustomCounterRegistry,
+) base.LogTransform {
+	tlogger := parentLogger.WithFields(logger.Fields{
+		defs.LabelPart:   tc.Value.GetType(),
+		defs.LabelSource: tc.Location,
+	})
+	return tc.Value.NewTransform(schema, tlogger, customCounterHost)
+}
+
+// VerifyTransformConfigs verifies a list of nested or output transform configurations, which must not contain
+// transforms limited to top-level transformations of pipelines
 func VerifyTransformConfigs(transformConfigs []bconfig.LogTransformConfigHolder, schema base.LogSchema, header string) error {
+	return verifyTransformConfigs(transformConfigs, schema, header, false)
+}
+
+// VerifyTopLevelTransformConfigs verifies a list of top-level transform configurations of pipelines
+func VerifyTopLevelTransformConfigs(transformConfigs []bconfig.LogTransformConfigHolder, schema base.LogSchema, header string) error {
+	return verifyTransformConfigs(transformConfigs, schema, header, true)
+}
+
+func verifyTransformConfigs(transformConfigs []bconfig.LogTransformConfigHolder, schema base.LogSchema, header string, topLevel bool) error {
 	for i, tfc := range transformConfigs {
+		if _, ok := tfc.Value.(bconfig.LogTopLevelTransformConfig); ok && !topLevel {
+			return fmt.Errorf("%s[%d] %s: .type '%s' is only supported in top-level transformations", header, i, tfc.Location, tfc.Value.GetType())
+		}
 		err := tfc.Value.VerifyConfig(schema)
 		if err != nil {
 			return fmt.Errorf("%s[%d] %s: %w", header, i, tfc.Location, err)
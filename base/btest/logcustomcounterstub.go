@@ -52,3 +52,8 @@ func (stub *stubLogCustomCounterRegistry) lookupCustomCounter(label string) (int
 
 	return pCounter.count, pCounter.length
 }
+
+// RegisterCustomMetric returns an observer which ignores all values. Use a real LogProcessCounterSet to test metrics.
+func (stub *stubLogCustomCounterRegistry) RegisterCustomMetric(_ base.LogCustomMetricSpec) base.LogCustomMetricObserver {
+	return func(_ []string, _ float64) {}
+}
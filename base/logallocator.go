@@ -20,9 +20,10 @@ type LogAllocator struct {
 // NewLogAllocator creates LogAllocator linked to the given schema
 func NewLogAllocator(schema LogSchema, outputCount int) *LogAllocator {
 	maxFields := schema.GetMaxFields()
+	maxExtraFields := schema.GetMaxExtraFields()
 	recordPool := &sync.Pool{}
 	recordPool.New = func() interface{} {
-		return newLogRecord(maxFields)
+		return newLogRecord(maxFields, maxExtraFields)
 	}
 	return &LogAllocator{
 		recordPool:      recordPool,
@@ -31,14 +32,21 @@ func NewLogAllocator(schema LogSchema, outputCount int) *LogAllocator {
 	}
 }
 
-func newLogRecord(maxFields int) *LogRecord {
+func newLogRecord(maxFields int, maxExtraFields int) *LogRecord {
+	var extra LogExtraFields
+	if maxExtraFields > 0 {
+		extra = make(LogExtraFields, 0, maxExtraFields)
+	}
 	return &LogRecord{
 		Fields:    make(LogFields, maxFields),
 		RawLength: 0,
 		Timestamp: time.Time{},
 		Unescaped: false,
+		Extra:     extra,
 		_backbuf:  nil,
 		_refCount: 0,
+		_keySet:   nil,
+		_derived:  false,
 	}
 }
 
@@ -57,6 +65,65 @@ func (alloc *LogAllocator) NewRecord(input []byte) (*LogRecord, util.MutableStri
 	return record, util.DeepCopyStringFromBytes(input)
 }
 
+// CopyRecord makes a deep copy of the given record with its own backing buffer and reference count of one
+//
+// The copy can be modified and released independently, e.g. by transforms specific to one of the outputs
+func (alloc *LogAllocator) CopyRecord(source *LogRecord) *LogRecord {
+	return alloc.copyRecord(source, 1)
+}
+
+// DeriveRecord makes a new record from a deep copy of the given parent, with the initial reference count like NewRecord
+//
+// The parent is not affected and may be released before the derived record. The derived record is counted in metrics
+// apart from received records.
+func (alloc *LogAllocator) DeriveRecord(parent *LogRecord) *LogRecord {
+	record := alloc.copyRecord(parent, alloc.initialRefCount)
+	record._derived = true
+	return record
+}
+
+func (alloc *LogAllocator) copyRecord(source *LogRecord, refCount int) *LogRecord {
+	record := alloc.recordPool.Get().(*LogRecord)
+	record._refCount += refCount
+	record.RawLength = source.RawLength
+	record.Timestamp = source.Timestamp
+	record.Unescaped = source.Unescaped
+	record._keySet = source._keySet
+	record._derived = source._derived
+
+	length := 0
+	for _, value := range source.Fields {
+		length += len(value)
+	}
+	for _, field := range source.Extra {
+		length += len(field.Key) + len(field.Value)
+	}
+	var buf []byte
+	if length > defs.InputLogMinRecordBytesToPool {
+		backbuf := alloc.backbufPools.Get(length)
+		record._backbuf = backbuf
+		buf = (*backbuf)[:0]
+	} else {
+		buf = make([]byte, 0, length)
+	}
+	for i, value := range source.Fields {
+		start := len(buf)
+		buf = append(buf, value...)
+		record.Fields[i] = util.StringFromBytes(buf[start:])
+	}
+	for _, field := range source.Extra {
+		keyStart := len(buf)
+		buf = append(buf, field.Key...)
+		valueStart := len(buf)
+		buf = append(buf, field.Value...)
+		record.Extra = append(record.Extra, LogExtraField{
+			Key:   util.StringFromBytes(buf[keyStart:valueStart]),
+			Value: util.StringFromBytes(buf[valueStart:]),
+		})
+	}
+	return record
+}
+
 // Release releases this log record for recycling
 func (alloc *LogAllocator) Release(record *LogRecord) {
 	record._refCount--
@@ -66,11 +133,28 @@ func (alloc *LogAllocator) Release(record *LogRecord) {
 	if record._refCount > 0 {
 		return
 	}
+	alloc.resetAndRecycleRecord(record)
+}
+
+// Discard releases a new log record which hasn't been passed to outputs, i.e. still has the initial reference count
+func (alloc *LogAllocator) Discard(record *LogRecord) {
+	if record._refCount != alloc.initialRefCount {
+		logger.Panic("unexpected reference count in discarded record: ", record)
+	}
+	record._refCount = 0
+	alloc.resetAndRecycleRecord(record)
+}
+
+func (alloc *LogAllocator) resetAndRecycleRecord(record *LogRecord) {
 	for i := range record.Fields {
 		record.Fields[i] = ""
 	}
+	clear(record.Extra)
+	record.Extra = record.Extra[:0]
 	record.RawLength = 0
 	record.Timestamp = time.Time{}
+	record._keySet = nil
+	record._derived = false
 	alloc.recycleRecord(record)
 }
 
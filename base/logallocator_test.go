@@ -0,0 +1,94 @@
+package base
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogAllocatorCopyRecord(t *testing.T) {
+	schema := MustNewLogSchema([]string{"a", "b"})
+	alloc := NewLogAllocator(schema, 2)
+
+	longValue := strings.Repeat("x", 1000)
+	source, input := alloc.NewRecord([]byte("first" + longValue))
+	source.Fields[0] = input[:5]
+	source.Fields[1] = input[5:]
+	source.RawLength = len(input)
+	source.Timestamp = time.Unix(1660550130, 0)
+	source.Unescaped = true
+
+	copied := alloc.CopyRecord(source)
+	assert.Equal(t, source.Fields[:2], copied.Fields[:2])
+	assert.Equal(t, source.RawLength, copied.RawLength)
+	assert.Equal(t, source.Timestamp, copied.Timestamp)
+	assert.True(t, copied.Unescaped)
+	assert.Equal(t, 1, copied._refCount)
+
+	copied.Fields[0] = "changed"
+	assert.Equal(t, "first", source.Fields[0])
+
+	alloc.Release(source)
+	assert.Equal(t, 1, source._refCount)
+	alloc.Release(copied)
+	assert.Equal(t, 0, copied._refCount)
+	assert.Nil(t, copied._backbuf)
+	alloc.Release(source)
+	assert.Equal(t, 0, source._refCount)
+}
+
+func TestLogAllocatorDeriveRecord(t *testing.T) {
+	schema := MustNewLogSchema([]string{"a", "b"})
+	alloc := NewLogAllocator(schema, 2)
+
+	parent, input := alloc.NewRecord([]byte("parent"))
+	parent.Fields[0] = input
+	parent.Fields[1] = "b"
+
+	derived := alloc.DeriveRecord(parent)
+	assert.Equal(t, parent.Fields[:2], derived.Fields[:2])
+	assert.Equal(t, 2, derived._refCount)
+
+	alloc.Discard(parent)
+	assert.Equal(t, LogFields{"parent", "b"}, derived.Fields[:2])
+	alloc.Discard(derived)
+}
+
+func TestLogAllocatorDiscard(t *testing.T) {
+	schema := MustNewLogSchema([]string{"a", "b"})
+	alloc := NewLogAllocator(schema, 2)
+
+	record, input := alloc.NewRecord([]byte("test"))
+	record.Fields[0] = input
+	alloc.Discard(record)
+	assert.Equal(t, 0, record._refCount)
+	assert.Equal(t, "", record.Fields[0])
+
+	released, _ := alloc.NewRecord([]byte("test"))
+	alloc.Release(released)
+	assert.Panics(t, func() { alloc.Discard(released) })
+}
+
+func TestLogAllocatorExtraFields(t *testing.T) {
+	schema, err := NewLogSchema([]string{"a"}, 1, 2)
+	assert.NoError(t, err)
+	alloc := NewLogAllocator(schema, 1)
+
+	source, input := alloc.NewRecord([]byte("key1value1"))
+	assert.Equal(t, 2, cap(source.Extra))
+	assert.True(t, source.Extra.Set(input[:4], input[4:]))
+	assert.True(t, source.Extra.Set("key2", "value2"))
+	assert.False(t, source.Extra.Set("key3", "value3"))
+
+	copied := alloc.CopyRecord(source)
+	assert.Equal(t, source.Extra, copied.Extra)
+	copied.Extra.Set("key1", "changed")
+	assert.Equal(t, "value1", source.Extra.Get("key1"))
+
+	alloc.Release(copied)
+	assert.Empty(t, copied.Extra)
+	assert.Equal(t, 2, cap(copied.Extra))
+	alloc.Release(source)
+}
@@ -3,6 +3,9 @@ package base
 // LogCustomCounterRegistry allows registration of custom record counters by label
 //
 // RegisterCustomCounter returns a function to be called to count record length
+//
+// RegisterCustomMetric returns a function to be called to observe values into a custom metric of labels
 type LogCustomCounterRegistry interface {
 	RegisterCustomCounter(label string) func(length int)
+	RegisterCustomMetric(spec LogCustomMetricSpec) LogCustomMetricObserver
 }
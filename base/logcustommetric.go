@@ -0,0 +1,321 @@
+package base
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/relex/gotils/logger"
+	"github.com/relex/slog-agent/util"
+	"golang.org/x/exp/slices"
+)
+
+// LogCustomMetricKind defines the kind of custom metrics derived from log records
+type LogCustomMetricKind int
+
+// Kinds of custom metrics
+const (
+	CustomMetricCounter   LogCustomMetricKind = iota // counter of records: name_total
+	CustomMetricSummary                              // summary without quantiles: name_count, name_sum
+	CustomMetricHistogram                            // histogram: name_count, name_sum and cumulative name_bucket{le=..}
+)
+
+// LogCustomMetricSpec defines a custom metric derived from log records, e.g. by the 'metric' transform
+//
+// The same metric may be registered by many transforms, inputs and pipelines, as long as the kinds, label names and
+// buckets are the same.
+type LogCustomMetricSpec struct {
+	Name       string
+	Help       string
+	Kind       LogCustomMetricKind
+	LabelNames []string
+	Buckets    []float64 // upper bounds of histogram buckets in ascending order, without +Inf
+	MaxSeries  int       // max count of label-value combinations per registrant, after which new combinations are ignored
+}
+
+// CheckConflict returns error if the other spec has the same name but a different kind, labels or buckets
+func (spec LogCustomMetricSpec) CheckConflict(other LogCustomMetricSpec) error {
+	if spec.Name != other.Name {
+		return nil
+	}
+	if spec.Kind != other.Kind || !slices.Equal(spec.LabelNames, other.LabelNames) || !slices.Equal(spec.Buckets, other.Buckets) {
+		return fmt.Errorf("custom metric '%s' is already registered with a different kind, labels or buckets", spec.Name)
+	}
+	return nil
+}
+
+// LogCustomMetricObserver is a function to observe a value by label values in the order of LabelNames
+//
+// The label values may be transient and are copied when needed. The value is ignored for counters.
+type LogCustomMetricObserver func(labelValues []string, value float64)
+
+// LogCustomMetricRegistry keeps custom metrics from all inputs and pipelines, exported as Prometheus counters,
+// summaries and histograms
+//
+// Custom metrics are named as prefix + "custom_" + name, and labelled by their own label names only. Values observed
+// in different inputs or pipelines are aggregated into the same series.
+//
+// LogCustomMetricRegistry is a prometheus.Gatherer to be exported in addition to metric factories. It's concurrently
+// usable.
+type LogCustomMetricRegistry struct {
+	prefix    string
+	gatherer  *prometheus.Registry
+	familyMap map[string]*logCustomMetricFamily // map of spec name => family
+	mapLock   sync.Mutex
+}
+
+// logCustomMetricFamily keeps all series of a custom metric, as a prometheus.Collector
+type logCustomMetricFamily struct {
+	spec       LogCustomMetricSpec
+	localName  string // full name without the registry prefix
+	desc       *prometheus.Desc
+	seriesMap  map[string]*logCustomMetricValues // map of merged label values => values
+	seriesLock sync.Mutex
+}
+
+// logCustomMetricValues keeps the values of a custom metric series
+type logCustomMetricValues struct {
+	labelValues []string
+	count       uint64
+	sum         float64
+	buckets     []uint64 // non-cumulative counts of each bucket, the last of which is +Inf
+}
+
+// logCustomMetricHost hosts custom metrics registered by transforms, each of which is flushed in UpdateMetrics
+type logCustomMetricHost struct {
+	registry  *LogCustomMetricRegistry
+	metricMap map[string]*logCustomMetricVec
+}
+
+// logCustomMetricVec tracks unwritten values of a custom metric by label values
+type logCustomMetricVec struct {
+	family    *logCustomMetricFamily
+	seriesMap map[string]*logCustomMetricValues
+	keyBuffer []byte // reused buffer to build merged key from label values
+	logger    logger.Logger
+}
+
+// NewLogCustomMetricRegistry creates a LogCustomMetricRegistry with prefix for names of all metrics inside
+func NewLogCustomMetricRegistry(prefix string) *LogCustomMetricRegistry {
+	registry := &LogCustomMetricRegistry{
+		prefix:    prefix,
+		gatherer:  prometheus.NewPedanticRegistry(),
+		familyMap: make(map[string]*logCustomMetricFamily),
+		mapLock:   sync.Mutex{},
+	}
+	registry.gatherer.MustRegister(registry)
+	return registry
+}
+
+// Describe implements prometheus.Collector's Describe function
+//
+// Nothing is described, as custom metrics may be registered after the registry itself is collected
+func (registry *LogCustomMetricRegistry) Describe(_ chan<- *prometheus.Desc) {
+}
+
+// Collect implements prometheus.Collector's Collect function, storing metrics in the output channel
+func (registry *LogCustomMetricRegistry) Collect(output chan<- prometheus.Metric) {
+	registry.mapLock.Lock()
+	defer registry.mapLock.Unlock()
+
+	for _, family := range registry.familyMap {
+		family.Collect(output)
+	}
+}
+
+// Gather implements prometheus.Gatherer's Gather function
+func (registry *LogCustomMetricRegistry) Gather() ([]*dto.MetricFamily, error) {
+	return registry.gatherer.Gather()
+}
+
+// addOrGetFamily adds or gets a custom metric family by spec, or returns error if the spec conflicts with the
+// registered one of the same name
+func (registry *LogCustomMetricRegistry) addOrGetFamily(spec LogCustomMetricSpec) (*logCustomMetricFamily, error) {
+	registry.mapLock.Lock()
+	defer registry.mapLock.Unlock()
+
+	if family, exists := registry.familyMap[spec.Name]; exists {
+		if err := spec.CheckConflict(family.spec); err != nil {
+			return nil, err
+		}
+		return family, nil
+	}
+
+	localName := "custom_" + spec.Name
+	if spec.Kind == CustomMetricCounter {
+		localName += "_total"
+	}
+	family := &logCustomMetricFamily{
+		spec:       spec,
+		localName:  localName,
+		desc:       prometheus.NewDesc(registry.prefix+localName, spec.Help, spec.LabelNames, nil),
+		seriesMap:  make(map[string]*logCustomMetricValues),
+		seriesLock: sync.Mutex{},
+	}
+	registry.familyMap[spec.Name] = family
+	return family, nil
+}
+
+// CheckSpec checks whether the spec conflicts with the registered one of the same name, without registering it
+func (registry *LogCustomMetricRegistry) CheckSpec(spec LogCustomMetricSpec) error {
+	registry.mapLock.Lock()
+	defer registry.mapLock.Unlock()
+
+	if family, exists := registry.familyMap[spec.Name]; exists {
+		return spec.CheckConflict(family.spec)
+	}
+	return nil
+}
+
+// Describe implements prometheus.Collector's Describe function, storing the description in the output channel
+func (family *logCustomMetricFamily) Describe(output chan<- *prometheus.Desc) {
+	output <- family.desc
+}
+
+// Collect implements prometheus.Collector's Collect function, storing metrics in the output channel
+func (family *logCustomMetricFamily) Collect(output chan<- prometheus.Metric) {
+	family.seriesLock.Lock()
+	defer family.seriesLock.Unlock()
+
+	for _, series := range family.seriesMap {
+		switch family.spec.Kind {
+		case CustomMetricCounter:
+			output <- prometheus.MustNewConstMetric(family.desc, prometheus.CounterValue, float64(series.count), series.labelValues...)
+		case CustomMetricSummary:
+			output <- prometheus.MustNewConstSummary(family.desc, series.count, series.sum, nil, series.labelValues...)
+		case CustomMetricHistogram:
+			cumulativeBuckets := make(map[float64]uint64, len(family.spec.Buckets))
+			var cumulative uint64
+			for i, bound := range family.spec.Buckets {
+				cumulative += series.buckets[i]
+				cumulativeBuckets[bound] = cumulative
+			}
+			output <- prometheus.MustNewConstHistogram(family.desc, series.count, series.sum, cumulativeBuckets, series.labelValues...)
+		}
+	}
+}
+
+// add adds values of a series by merged label values
+func (family *logCustomMetricFamily) add(mergedKey string, values *logCustomMetricValues) {
+	family.seriesLock.Lock()
+	defer family.seriesLock.Unlock()
+
+	series, found := family.seriesMap[mergedKey]
+	if !found {
+		series = newLogCustomMetricValues(values.labelValues, len(values.buckets))
+		family.seriesMap[mergedKey] = series
+	}
+	series.count += values.count
+	series.sum += values.sum
+	for i, num := range values.buckets {
+		series.buckets[i] += num
+	}
+}
+
+func newLogCustomMetricValues(permLabelValues []string, numBuckets int) *logCustomMetricValues {
+	values := &logCustomMetricValues{
+		labelValues: permLabelValues,
+		count:       0,
+		sum:         0,
+		buckets:     nil,
+	}
+	if numBuckets > 0 {
+		values.buckets = make([]uint64, numBuckets)
+	}
+	return values
+}
+
+func (values *logCustomMetricValues) reset() {
+	values.count = 0
+	values.sum = 0
+	for i := range values.buckets {
+		values.buckets[i] = 0
+	}
+}
+
+func newLogCustomMetricHost(registry *LogCustomMetricRegistry) logCustomMetricHost {
+	return logCustomMetricHost{
+		registry:  registry,
+		metricMap: make(map[string]*logCustomMetricVec),
+	}
+}
+
+// RegisterCustomMetric registers a custom metric by spec and returns the observer function
+//
+// Conflicting specs should have been rejected in config verification by LogSchema.VerifyCustomMetric. Otherwise a spec
+// conflicting with the registered one of the same name is rejected with error logged, and values observed by its
+// returned function are ignored.
+func (host *logCustomMetricHost) RegisterCustomMetric(spec LogCustomMetricSpec) LogCustomMetricObserver {
+	family, err := host.registry.addOrGetFamily(spec)
+	if err != nil {
+		logger.Errorf("%s, values are ignored", err.Error())
+		return func(_ []string, _ float64) {}
+	}
+	vec, exists := host.metricMap[spec.Name]
+	if !exists {
+		vec = &logCustomMetricVec{
+			family:    family,
+			seriesMap: make(map[string]*logCustomMetricValues, spec.MaxSeries),
+			keyBuffer: make([]byte, 0, 200),
+			logger:    logger.WithField("metric", family.localName),
+		}
+		host.metricMap[spec.Name] = vec
+	}
+	return vec.observe
+}
+
+// UpdateMetrics writes unwritten values of custom metrics to the registry
+func (host *logCustomMetricHost) UpdateMetrics() {
+	for _, vec := range host.metricMap {
+		vec.UpdateMetrics()
+	}
+}
+
+func (vec *logCustomMetricVec) observe(labelValues []string, value float64) {
+	tempKey := vec.keyBuffer
+	for _, lv := range labelValues {
+		tempKey = append(tempKey, lv...)
+		tempKey = append(tempKey, 0)
+	}
+	vec.keyBuffer = tempKey[:0]
+
+	spec := &vec.family.spec
+	series, found := vec.seriesMap[string(tempKey)]
+	if !found {
+		if len(vec.seriesMap) >= spec.MaxSeries {
+			return
+		}
+		numBuckets := 0
+		if spec.Kind == CustomMetricHistogram {
+			numBuckets = len(spec.Buckets) + 1
+		}
+		series = newLogCustomMetricValues(util.DeepCopyStrings(labelValues), numBuckets)
+		vec.seriesMap[util.DeepCopyStringFromBytes(tempKey)] = series
+		if len(vec.seriesMap) == spec.MaxSeries {
+			vec.logger.Warnf("reached max series (%d), values of new labels are ignored from now on", spec.MaxSeries)
+		}
+	}
+
+	series.count++
+	if spec.Kind == CustomMetricCounter {
+		return
+	}
+	series.sum += value
+	if series.buckets != nil {
+		// the last bucket is +Inf, which is returned if the value is greater than all bounds
+		series.buckets[sort.SearchFloat64s(spec.Buckets, value)]++
+	}
+}
+
+// UpdateMetrics writes unwritten values of all series to the registry
+func (vec *logCustomMetricVec) UpdateMetrics() {
+	for key, series := range vec.seriesMap {
+		if series.count == 0 {
+			continue
+		}
+		vec.family.add(key, series)
+		series.reset()
+	}
+}
@@ -0,0 +1,63 @@
+package base
+
+import (
+	"github.com/relex/slog-agent/util"
+)
+
+// LogExtraFieldKeyPrefix marks keys of extra fields where fields are referred by name in config, e.g. "extra.user" for
+// extra field "user" in matches
+const LogExtraFieldKeyPrefix = "extra."
+
+// LogExtraField is a key-value pair of a field not defined in schema
+type LogExtraField struct {
+	Key   util.MutableString
+	Value util.MutableString
+}
+
+// LogExtraFields is an overflow list of fields not defined in schema, e.g. arbitrary keys parsed from messages, enabled
+// by schema's maxExtraFields.
+//
+// The list is allocated with records from pool and never grows beyond its capacity. Lookup is by linear search, meant
+// for a few fields per record only - fields to be matched or processed frequently should be defined in schema instead.
+//
+// As in LogFields, keys and values are temporary and empty values are the same as missing fields. Keys of fields
+// defined in schema shouldn't be added here.
+type LogExtraFields []LogExtraField
+
+// Get returns the value of the given key or empty string if not found
+func (extra LogExtraFields) Get(key string) util.MutableString {
+	for _, field := range extra {
+		if field.Key == key {
+			return field.Value
+		}
+	}
+	return ""
+}
+
+// Set updates the value of the given key, or adds it if there is room left
+//
+// Returns false if the key is new and the list is already full
+func (extra *LogExtraFields) Set(key util.MutableString, value util.MutableString) bool {
+	list := *extra
+	for i := range list {
+		if list[i].Key == key {
+			list[i].Value = value
+			return true
+		}
+	}
+	if len(list) == cap(list) {
+		return false
+	}
+	*extra = append(list, LogExtraField{Key: key, Value: value})
+	return true
+}
+
+// Del clears the value of the given key if it exists, without removing the key
+func (extra LogExtraFields) Del(key string) {
+	for i := range extra {
+		if extra[i].Key == key {
+			extra[i].Value = ""
+			return
+		}
+	}
+}
@@ -11,6 +11,7 @@ import (
 // created here may duplicate with others, as long as the labels match.
 type LogInputCounterSet struct {
 	logCustomCounterHost
+	logCustomMetricHost
 	passedRecordsCountTotal   valueCounterProvider
 	passedRecordsLengthTotal  valueCounterProvider
 	droppedRecordsCountTotal  valueCounterProvider
@@ -24,9 +25,12 @@ type valueCounterProvider struct {
 }
 
 // NewLogInputCounter creates a LogInputCounter
-func NewLogInputCounter(metricCreator promreg.MetricCreator) *LogInputCounterSet {
+//
+// Custom metrics registered here are kept in the given registry, separately from metrics made by metricCreator.
+func NewLogInputCounter(metricCreator promreg.MetricCreator, customMetrics *LogCustomMetricRegistry) *LogInputCounterSet {
 	return &LogInputCounterSet{
 		logCustomCounterHost: *newLogCustomCounterHost(metricCreator),
+		logCustomMetricHost:  newLogCustomMetricHost(customMetrics),
 		passedRecordsCountTotal: valueCounterProvider{
 			metricCreator.AddOrGetCounter("passed_records_total", "Numbers of passed log records", nil, nil), 0,
 		},
@@ -57,6 +61,7 @@ func (icounter *LogInputCounterSet) CountRecordDrop(record *LogRecord) { // xx:i
 // UpdateMetrics writes unwritten values in the counter to underlying Prometheus counters
 func (icounter *LogInputCounterSet) UpdateMetrics() {
 	icounter.logCustomCounterHost.UpdateMetrics()
+	icounter.logCustomMetricHost.UpdateMetrics()
 
 	icounter.passedRecordsCountTotal.UpdateMetric()
 	icounter.passedRecordsLengthTotal.UpdateMetric()
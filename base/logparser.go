@@ -8,3 +8,11 @@ import (
 type LogParser interface {
 	Parse(input []byte, timestamp time.Time) *LogRecord
 }
+
+// LogParserCloser is an optional interface of LogParser to release resources when the connection is closed
+type LogParserCloser interface {
+	LogParser
+
+	// Close releases resources held by the parser
+	Close()
+}
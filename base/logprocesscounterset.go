@@ -19,17 +19,22 @@ import (
 // inefficient.
 type LogProcessCounterSet struct {
 	factory             promreg.MetricCreator
-	metricKeyExtractor  FieldSetExtractor                     // to extract metric keys from log records
-	metricKeyNames      []string                              // label names of metric keys (ex: key_vhost)
-	customCounterVecMap map[string]logProcessCustomCounterVec // map of custom label => counter-vector[label], with unfilled metric key labels
-	keySetPairs         map[string]logKeySetCounterPair       // map of merged metric key => (input counter, custom counters)
+	customMetrics       *LogCustomMetricRegistry
+	metricKeyExtractor  FieldSetExtractor                             // to extract metric keys from log records
+	metricKeyNames      []string                                      // label names of metric keys (ex: key_vhost)
+	customCounterVecMap map[string]logProcessCustomCounterVec         // map of custom label => counter-vector[label], with unfilled metric key labels
+	keySetPairs         map[string]*logKeySetCounterPair              // map of merged metric key => (input counter, custom counters)
+	keySetsByInput      map[*LogInputCounterSet]*logKeySetCounterPair // map of input counter => the same pair, for reselection
+	customMetricHost    logCustomMetricHost                           // custom metrics of their own labels, not split by metric keys
 
-	serializedLengthTotal []valueCounterProvider // an array of per-output metrics counters, accessed by output index
+	filteredCountTotal    []valueCounterProvider // an array of per-output metrics counters, accessed by output index
+	filteredLengthTotal   []valueCounterProvider
+	serializedLengthTotal []valueCounterProvider
 	chunksCountTotal      []valueCounterProvider
 	chunksLengthTotal     []valueCounterProvider
 
 	currentCustomCounters []*logCustomCounterImpl // custom counter array of the currently selected metric key-set
-	mergeKeyBuffer        []byte              // reused buffer to build merged metric key from record
+	mergeKeyBuffer        []byte                  // reused buffer to build merged metric key from record
 }
 
 // FIXME: why is logCustomCounterHost not used here?
@@ -42,29 +47,48 @@ type logProcessCustomCounterVec struct {
 type logKeySetCounterPair struct {
 	inputCounter   *LogInputCounterSet
 	customCounters []*logCustomCounterImpl
+
+	// counters of records derived by transforms, e.g. split, which are not counted by inputCounter
+	derivedPassedCountTotal   valueCounterProvider
+	derivedPassedLengthTotal  valueCounterProvider
+	derivedDroppedCountTotal  valueCounterProvider
+	derivedDroppedLengthTotal valueCounterProvider
 }
 
 // NewLogProcessCounter creates a LogProcessCounter
-func NewLogProcessCounter(factory promreg.MetricCreator, schema LogSchema, keyLocators []LogFieldLocator, outputNames []string) *LogProcessCounterSet {
+//
+// Custom metrics registered here are kept in the given registry, separately from metrics made by factory.
+func NewLogProcessCounter(factory promreg.MetricCreator, customMetrics *LogCustomMetricRegistry, schema LogSchema, keyLocators []LogFieldLocator, outputNames []string) *LogProcessCounterSet {
 	metricKeyNames := make([]string, len(keyLocators))
 	for i, loc := range keyLocators {
 		metricKeyNames[i] = "key_" + loc.Name(schema)
 	}
 	counter := &LogProcessCounterSet{
 		factory:               factory,
+		customMetrics:         customMetrics,
 		metricKeyExtractor:    *NewFieldSetExtractor(keyLocators),
 		metricKeyNames:        metricKeyNames,
 		customCounterVecMap:   make(map[string]logProcessCustomCounterVec, 100),
-		keySetPairs:           make(map[string]logKeySetCounterPair, 2000),
+		keySetPairs:           make(map[string]*logKeySetCounterPair, 2000),
+		keySetsByInput:        make(map[*LogInputCounterSet]*logKeySetCounterPair, 2000),
+		customMetricHost:      newLogCustomMetricHost(customMetrics),
 		currentCustomCounters: nil,
 		mergeKeyBuffer:        make([]byte, 0, 200),
 	}
 
+	counter.filteredCountTotal = make([]valueCounterProvider, len(outputNames))
+	counter.filteredLengthTotal = make([]valueCounterProvider, len(outputNames))
 	counter.serializedLengthTotal = make([]valueCounterProvider, len(outputNames))
 	counter.chunksCountTotal = make([]valueCounterProvider, len(outputNames))
 	counter.chunksLengthTotal = make([]valueCounterProvider, len(outputNames))
 
 	for i, output := range outputNames {
+		counter.filteredCountTotal[i] = valueCounterProvider{
+			factory.AddOrGetCounter("output_filtered_records_total", "Numbers of log records dropped by output transforms", []string{"output"}, []string{output}), 0,
+		}
+		counter.filteredLengthTotal[i] = valueCounterProvider{
+			factory.AddOrGetCounter("output_filtered_record_bytes_total", "Total length in bytes of log records dropped by output transforms", []string{"output"}, []string{output}), 0,
+		}
 		counter.serializedLengthTotal[i] = valueCounterProvider{
 			factory.AddOrGetCounter("serialized_bytes_total", "Total lengths in bytes of serialized log records", []string{"output"}, []string{output}), 0,
 		}
@@ -102,11 +126,22 @@ func (pcounter *LogProcessCounterSet) RegisterCustomCounter(label string) func(l
 	}
 }
 
+// RegisterCustomMetric registers a custom metric and returns the function to observe values
+//
+// Unlike custom counters, custom metrics are labelled by their own label names only, regardless of metric keys.
+//
+// This method must not be called in processing stage, when counters are already being selected and updated
+func (pcounter *LogProcessCounterSet) RegisterCustomMetric(spec LogCustomMetricSpec) LogCustomMetricObserver {
+	return pcounter.customMetricHost.RegisterCustomMetric(spec)
+}
+
 // SelectMetricKeySet switches the current metric key set to that of the given record.
 //
 // 1. Subsequent transforms would write counter values to the correct key-set.
 //
 // 2. Returns an input counter for that key-set.
+//
+// 3. The key-set is stored in the record and inherited by records derived from it, for ReselectMetricKeySet.
 func (pcounter *LogProcessCounterSet) SelectMetricKeySet(record *LogRecord) *LogInputCounterSet {
 	tempKeys := pcounter.metricKeyExtractor.Extract(record)
 
@@ -131,17 +166,73 @@ func (pcounter *LogProcessCounterSet) SelectMetricKeySet(record *LogRecord) *Log
 				unwrittenLength: 0,
 			}
 		}
-		pair = logKeySetCounterPair{
-			inputCounter:   NewLogInputCounter(pcounter.factory.AddOrGetPrefix("", pcounter.metricKeyNames, permKeys)),
+		keySetCreator := pcounter.factory.AddOrGetPrefix("", pcounter.metricKeyNames, permKeys)
+		pair = &logKeySetCounterPair{
+			inputCounter:   NewLogInputCounter(keySetCreator, pcounter.customMetrics),
 			customCounters: customCounters,
+			derivedPassedCountTotal: valueCounterProvider{
+				keySetCreator.AddOrGetCounter("derived_passed_records_total", "Numbers of passed log records derived by transforms", nil, nil), 0,
+			},
+			derivedPassedLengthTotal: valueCounterProvider{
+				keySetCreator.AddOrGetCounter("derived_passed_record_bytes_total", "Total length in bytes of passed log records derived by transforms", nil, nil), 0,
+			},
+			derivedDroppedCountTotal: valueCounterProvider{
+				keySetCreator.AddOrGetCounter("derived_dropped_records_total", "Numbers of dropped log records derived by transforms", nil, nil), 0,
+			},
+			derivedDroppedLengthTotal: valueCounterProvider{
+				keySetCreator.AddOrGetCounter("derived_dropped_record_bytes_total", "Total length in bytes of dropped log records derived by transforms", nil, nil), 0,
+			},
 		}
 		pcounter.keySetPairs[permMergedKey] = pair
+		pcounter.keySetsByInput[pair.inputCounter] = pair
 	}
 
 	pcounter.currentCustomCounters = pair.customCounters
+	record._keySet = pair.inputCounter
 	return pair.inputCounter
 }
 
+// ReselectMetricKeySet switches the current metric key set back to that of a record selected before, or of the
+// record it's derived from, and returns the input counter for that key-set.
+//
+// It's for processing records stage by stage, when the key fields may have been changed by transforms in between.
+func (pcounter *LogProcessCounterSet) ReselectMetricKeySet(record *LogRecord) *LogInputCounterSet {
+	pcounter.currentCustomCounters = pcounter.keySetsByInput[record._keySet].customCounters
+	return record._keySet
+}
+
+// CountRecordPass updates counters for a record passing transforms under its selected metric key-set
+//
+// Records derived by transforms are counted apart, so that each received record is counted as passed or dropped once.
+func (pcounter *LogProcessCounterSet) CountRecordPass(record *LogRecord) {
+	if !record._derived {
+		record._keySet.CountRecordPass(record)
+		return
+	}
+	pair := pcounter.keySetsByInput[record._keySet]
+	pair.derivedPassedCountTotal.unwrittenValue++
+	pair.derivedPassedLengthTotal.unwrittenValue += uint64(record.RawLength)
+}
+
+// CountRecordDrop updates counters for a record dropped by transforms under its selected metric key-set
+//
+// Records derived by transforms are counted apart, so that each received record is counted as passed or dropped once.
+func (pcounter *LogProcessCounterSet) CountRecordDrop(record *LogRecord) {
+	if !record._derived {
+		record._keySet.CountRecordDrop(record)
+		return
+	}
+	pair := pcounter.keySetsByInput[record._keySet]
+	pair.derivedDroppedCountTotal.unwrittenValue++
+	pair.derivedDroppedLengthTotal.unwrittenValue += uint64(record.RawLength)
+}
+
+// CountOutputFilter updates counters for records dropped by output transforms
+func (pcounter *LogProcessCounterSet) CountOutputFilter(outputIndex int, record *LogRecord) { // xx:inline
+	pcounter.filteredCountTotal[outputIndex].unwrittenValue++
+	pcounter.filteredLengthTotal[outputIndex].unwrittenValue += uint64(record.RawLength)
+}
+
 // CountStream updates counters for stream serialization
 func (pcounter *LogProcessCounterSet) CountStream(outputIndex int, stream LogStream) { // xx:inline
 	pcounter.serializedLengthTotal[outputIndex].unwrittenValue += uint64(len(stream))
@@ -160,10 +251,17 @@ func (pcounter *LogProcessCounterSet) UpdateMetrics() {
 		for _, counter := range pair.customCounters {
 			counter.UpdateMetrics()
 		}
+		pair.derivedPassedCountTotal.UpdateMetric()
+		pair.derivedPassedLengthTotal.UpdateMetric()
+		pair.derivedDroppedCountTotal.UpdateMetric()
+		pair.derivedDroppedLengthTotal.UpdateMetric()
 	}
+	pcounter.customMetricHost.UpdateMetrics()
 
 	// all these slices should have the same length, so we can iterate over them in one loop
 	for i := range pcounter.serializedLengthTotal {
+		pcounter.filteredCountTotal[i].UpdateMetric()
+		pcounter.filteredLengthTotal[i].UpdateMetric()
 		pcounter.serializedLengthTotal[i].UpdateMetric()
 		pcounter.chunksCountTotal[i].UpdateMetric()
 		pcounter.chunksLengthTotal[i].UpdateMetric()
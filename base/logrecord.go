@@ -8,12 +8,15 @@ import (
 
 // LogRecord defines the structure of log record before it's finalized for forwarding.
 type LogRecord struct {
-	Fields    LogFields // Field values by index and empty string if unset. The string values inside are temporary and only valid until record is released.
-	RawLength int       // Input length or approximated length of entire record, for statistics
-	Timestamp time.Time // Timestamp, might be zero until processed by a LogTransform
-	Unescaped bool      // Whether the main message field has been un-escaped. Multi-line logs start with true.
-	_backbuf  *[]byte   // Backing buffer where initial field values come from, nil if buffer pooling isn't used
-	_refCount int       // reference count, + outputs_length for new, -1 for release (back to pool)
+	Fields    LogFields           // Field values by index and empty string if unset. The string values inside are temporary and only valid until record is released.
+	RawLength int                 // Input length or approximated length of entire record, for statistics
+	Timestamp time.Time           // Timestamp, might be zero until processed by a LogTransform
+	Unescaped bool                // Whether the main message field has been un-escaped. Multi-line logs start with true.
+	Extra     LogExtraFields      // Fields not defined in schema, with capacity of schema's maxExtraFields. Same lifetime as Fields.
+	_backbuf  *[]byte             // Backing buffer where initial field values come from, nil if buffer pooling isn't used
+	_refCount int                 // reference count, + outputs_length for new, -1 for release (back to pool)
+	_keySet   *LogInputCounterSet // input counter of the metric key-set selected in processing, inherited by derived records
+	_derived  bool                // whether derived from another record by transforms, counted apart from received records
 }
 
 // LogFields represents named fields in LogRecord, to be used with LogSchema.
@@ -22,3 +25,29 @@ type LogRecord struct {
 //
 // Some fields at the end of this slice may be reserved by schema.MaxFields and they shouldn't be processed.
 type LogFields []util.MutableString
+
+// IsValueExclusive returns whether the given field value lies in the backing buffer of this record and isn't shared
+// with any other field, i.e. whether it can be overwritten in place
+//
+// Values may be shared after copying between fields, or come from constants and other records
+func (record *LogRecord) IsValueExclusive(value util.MutableString) bool {
+	if record._backbuf == nil {
+		return false
+	}
+	backbuf := *record._backbuf
+	if !util.StringWithin(value, util.StringFromBytes(backbuf[:cap(backbuf)])) {
+		return false
+	}
+	numShared := 0
+	for _, other := range record.Fields {
+		if util.StringsOverlap(value, other) {
+			numShared++
+		}
+	}
+	for _, field := range record.Extra {
+		if util.StringsOverlap(value, field.Key) || util.StringsOverlap(value, field.Value) {
+			numShared++
+		}
+	}
+	return numShared <= 1
+}
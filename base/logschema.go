@@ -15,14 +15,18 @@ import (
 //
 // In case of runtime schema update, only new fields should be appended at the end.
 type LogSchema struct {
-	fieldNames []string
-	maxFields  int
-	OnLocated  func(index int) // optional callback invoked after successful CreateFieldLocator calls
+	fieldNames     []string
+	maxFields      int
+	maxExtraFields int             // capacity of LogRecord.Extra, zero to disable
+	OnLocated      func(index int) // optional callback invoked after successful CreateFieldLocator calls
+
+	// OnCustomMetric is an optional callback invoked by VerifyCustomMetric to check conflicts between custom metrics
+	OnCustomMetric func(spec LogCustomMetricSpec) error
 }
 
 // MustNewLogSchema creates a new LogSchema or panic.
 func MustNewLogSchema(fieldNames []string) LogSchema {
-	schema, err := NewLogSchema(fieldNames, len(fieldNames))
+	schema, err := NewLogSchema(fieldNames, len(fieldNames), 0)
 	if err != nil {
 		logger.Panic("failed to create schema: ", err)
 	}
@@ -30,10 +34,15 @@ func MustNewLogSchema(fieldNames []string) LogSchema {
 }
 
 // NewLogSchema creates a new LogSchema with field names and environment field names.
-func NewLogSchema(fieldNames []string, maxFields int) (LogSchema, error) {
+//
+// maxExtraFields is the max number of fields not defined in schema for each record, zero to disable LogRecord.Extra
+func NewLogSchema(fieldNames []string, maxFields int, maxExtraFields int) (LogSchema, error) {
 	if maxFields < len(fieldNames) {
 		return LogSchema{}, fmt.Errorf("maxFields (%d) must be equal or greater than the number of field names (%d)", maxFields, len(fieldNames))
 	}
+	if maxExtraFields < 0 {
+		return LogSchema{}, fmt.Errorf("maxExtraFields (%d) cannot be negative", maxExtraFields)
+	}
 
 	m := make(map[string]bool, len(fieldNames)*2)
 	for i, name := range fieldNames {
@@ -47,20 +56,28 @@ func NewLogSchema(fieldNames []string, maxFields int) (LogSchema, error) {
 		m[name] = true
 	}
 	schema := LogSchema{
-		fieldNames: fieldNames,
-		maxFields:  maxFields,
-		OnLocated:  nil,
+		fieldNames:     fieldNames,
+		maxFields:      maxFields,
+		maxExtraFields: maxExtraFields,
+		OnLocated:      nil,
+		OnCustomMetric: nil,
 	}
 	return schema, nil
 }
 
 // CopyTestRecord makes a deep copy of given record
 func (s *LogSchema) CopyTestRecord(source *LogRecord) *LogRecord {
-	dest := newLogRecord(s.maxFields)
+	dest := newLogRecord(s.maxFields, s.maxExtraFields)
 	dest._refCount++
 	dest.Timestamp = source.Timestamp
 	dest.Unescaped = source.Unescaped
 	dest.Fields = util.DeepCopyStrings(source.Fields)
+	for _, field := range source.Extra {
+		dest.Extra = append(dest.Extra, LogExtraField{
+			Key:   util.DeepCopyString(field.Key),
+			Value: util.DeepCopyString(field.Value),
+		})
+	}
 	return dest
 }
 
@@ -69,7 +86,7 @@ func (s *LogSchema) NewTestRecord1(fields LogFields) *LogRecord {
 	if len(fields) != len(s.fieldNames) {
 		logger.Panicf("wrong numbers of test log fields: %s, should be %d", fields, len(s.fieldNames))
 	}
-	record := newLogRecord(s.maxFields)
+	record := newLogRecord(s.maxFields, s.maxExtraFields)
 	record._refCount++
 	record.Fields = fields
 	return record
@@ -80,7 +97,7 @@ func (s *LogSchema) NewTestRecord2(tm time.Time, fields LogFields) *LogRecord {
 	if len(fields) != len(s.fieldNames) {
 		logger.Panicf("wrong numbers of test log fields: %s, should be %d", fields, len(s.fieldNames))
 	}
-	record := newLogRecord(s.maxFields)
+	record := newLogRecord(s.maxFields, s.maxExtraFields)
 	record._refCount++
 	record.Timestamp = tm
 	record.Fields = fields
@@ -99,6 +116,38 @@ func (s *LogSchema) CreateFieldLocator(name string) (LogFieldLocator, error) {
 	return LogFieldLocator(index), nil
 }
 
+// CreateFieldOrExtraLocator creates a LogFieldLocator by field name, or returns MissingFieldLocator with the key of an
+// extra field if the name has LogExtraFieldKeyPrefix, e.g. "extra.user" for extra field "user"
+//
+// Names without the prefix must be defined in schema, so that typos are not taken as extra fields never set.
+func (s *LogSchema) CreateFieldOrExtraLocator(name string) (LogFieldLocator, string, error) {
+	extraKey, isExtra := strings.CutPrefix(name, LogExtraFieldKeyPrefix)
+	if !isExtra {
+		loc, err := s.CreateFieldLocator(name)
+		return loc, "", err
+	}
+	if s.maxExtraFields == 0 {
+		return MissingFieldLocator, "", fmt.Errorf("field '%s' refers to extra fields, which are disabled in schema", name)
+	}
+	if len(extraKey) == 0 {
+		return MissingFieldLocator, "", fmt.Errorf("field '%s' has no key of extra field", name)
+	}
+	if slices.Contains(s.fieldNames, extraKey) {
+		return MissingFieldLocator, "", fmt.Errorf("field '%s' refers to '%s' defined in schema", name, extraKey)
+	}
+	return MissingFieldLocator, extraKey, nil
+}
+
+// VerifyCustomMetric verifies the spec of a custom metric to be registered, against other custom metrics in config
+//
+// It should be called in VerifyConfig of transforms registering custom metrics
+func (s *LogSchema) VerifyCustomMetric(spec LogCustomMetricSpec) error {
+	if cb := s.OnCustomMetric; cb != nil {
+		return cb(spec)
+	}
+	return nil
+}
+
 // CreateFieldLocators creates LogFieldLocator(s) for field names
 func (s *LogSchema) CreateFieldLocators(names []string) ([]LogFieldLocator, error) {
 	locators := make([]LogFieldLocator, len(names))
@@ -133,6 +182,11 @@ func (s *LogSchema) GetMaxFields() int {
 	return s.maxFields
 }
 
+// GetMaxExtraFields returns the maximum number of fields not defined in schema for each record, zero if disabled
+func (s *LogSchema) GetMaxExtraFields() int {
+	return s.maxExtraFields
+}
+
 // MustCreateFieldLocator creates LogFieldLocator by field name or panic (if field doesn't exist in schema)
 func (s *LogSchema) MustCreateFieldLocator(name string) LogFieldLocator {
 	loc, err := s.CreateFieldLocator(name)
@@ -7,13 +7,13 @@ import (
 )
 
 func TestNewLogSchema(t *testing.T) {
-	_, err1 := NewLogSchema([]string{"a", "b", "c"}, 2)
+	_, err1 := NewLogSchema([]string{"a", "b", "c"}, 2, 0)
 	assert.EqualError(t, err1, "maxFields (2) must be equal or greater than the number of field names (3)")
 
-	_, err2 := NewLogSchema([]string{"a", "", "c"}, 10)
+	_, err2 := NewLogSchema([]string{"a", "", "c"}, 10, 0)
 	assert.EqualError(t, err2, "invalid 1th field ''")
 
-	_, err3 := NewLogSchema([]string{"a", "b", "b"}, 10)
+	_, err3 := NewLogSchema([]string{"a", "b", "b"}, 10, 0)
 	assert.EqualError(t, err3, "duplicated 2th field 'b'")
 }
 
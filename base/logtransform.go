@@ -9,6 +9,15 @@ type LogTransform interface {
 	Transform(input *LogRecord) FilterResult
 }
 
+// LogTransformCloser is an optional interface of LogTransform to release resources held by the transform, e.g. watched
+// files, when it's no longer used. The transform must not be called after Close.
+type LogTransformCloser interface {
+	LogTransform
+
+	// Close releases resources held by the transform
+	Close()
+}
+
 // LogTransformFunc defines a function to perform transformation on a single log record
 type LogTransformFunc func(input *LogRecord) FilterResult
 
@@ -20,3 +29,55 @@ const PASS FilterResult = true
 
 // DROP means transform aborts and the record is to be dropped
 const DROP FilterResult = false
+
+// LogBatchTransform is an optional interface of LogTransform to transform a buffer of records in one call, for
+// transforms with a high cost per call such as plugins in sandboxes.
+//
+// It's only used for top-level transformations of pipelines. Nested transforms (e.g. in "if") are called per record.
+type LogBatchTransform interface {
+	LogTransform
+
+	// TransformBatch transforms the given records and stores PASS or DROP for each of them to results of the same index
+	//
+	// selectRecord must be called with the index of a record before updating custom counters for it, in order for the
+	// counters to be updated under the metric keys of that record.
+	TransformBatch(inputs []*LogRecord, results []FilterResult, selectRecord LogRecordSelector)
+}
+
+// LogBatchTransformFunc defines a function to perform transformation on a buffer of log records
+type LogBatchTransformFunc func(inputs []*LogRecord, results []FilterResult, selectRecord LogRecordSelector)
+
+// LogRecordSelector defines a function to select the record of the given index in a batch, for custom counters
+type LogRecordSelector func(index int)
+
+// LogExpandTransform is an optional interface of LogTransform to replace a record with any number of derived records,
+// e.g. to split a message containing multiple events.
+//
+// It's only used for top-level transformations of pipelines. Elsewhere records are passed to Transform and not expanded.
+type LogExpandTransform interface {
+	LogTransform
+
+	// ExpandRecord appends the records derived from input to outputs and returns the extended slice.
+	//
+	// Derived records must be created by allocator.DeriveRecord. The input may be appended as-is to be kept; otherwise
+	// it's discarded by the caller. Appending nothing means the input is dropped.
+	ExpandRecord(input *LogRecord, allocator *LogAllocator, outputs []*LogRecord) []*LogRecord
+}
+
+// LogExpandTransformFunc defines a function to replace a log record with derived records
+type LogExpandTransformFunc func(input *LogRecord, allocator *LogAllocator, outputs []*LogRecord) []*LogRecord
+
+// LogExpandFlusher is an optional interface of LogExpandTransform to pass derived records held by the transform, e.g.
+// summaries of repeated records, which are not ready when the records they're derived from are expanded.
+//
+// Held records keep the metric key-sets of the records they're derived from.
+type LogExpandFlusher interface {
+	LogExpandTransform
+
+	// FlushRecords appends the held records which are ready to outputs and returns the extended slice. It's called after
+	// each call of ExpandRecord, and once with final = true for all the held records before the pipeline stops.
+	FlushRecords(outputs []*LogRecord, final bool) []*LogRecord
+}
+
+// LogExpandFlushFunc defines a function to pass derived records held by an expand transform
+type LogExpandFlushFunc func(outputs []*LogRecord, final bool) []*LogRecord
@@ -63,6 +63,11 @@ var (
 	// For example, to flush buffer streams into output chunks, or to update internal timer
 	IntermediateFlushInterval = 1 * time.Second
 
+	// WatchedFileCheckInterval defines how often to check files loaded by transforms (e.g. lookup tables) for changes
+	//
+	// Changed or replaced files are reloaded in background and swapped in without interrupting pipelines
+	WatchedFileCheckInterval = 10 * time.Second
+
 	// BufferMaxNumChunksInQueue is the max numbers of of loaded and unloaded chunks to be held in a queue,
 	// equal to the max numbers of queued files on disk, because at least all the filepaths need to be held in channel
 	//
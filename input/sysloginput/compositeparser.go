@@ -10,14 +10,18 @@ import (
 type compositeParser struct {
 	underlyingParser     base.LogParser
 	extractionTransforms []base.LogTransformFunc
+	closeExtractions     func()
 	deallocator          *base.LogAllocator
 }
 
 // newCompositeParser combines a parser and a set of extraction transforms that are executed immediately after parsing without additional goroutine
-func newCompositeParser(p base.LogParser, extractions []base.LogTransformFunc, deallocator *base.LogAllocator) base.LogParser {
+func newCompositeParser(p base.LogParser, extractions []base.LogTransformFunc, closeExtractions func(),
+	deallocator *base.LogAllocator,
+) base.LogParserCloser {
 	return &compositeParser{
 		underlyingParser:     p,
 		extractionTransforms: extractions,
+		closeExtractions:     closeExtractions,
 		deallocator:          deallocator,
 	}
 }
@@ -29,9 +33,14 @@ func (cp *compositeParser) Parse(input []byte, timestamp time.Time) *base.LogRec
 		return nil
 	}
 	if bsupport.RunTransforms(record, cp.extractionTransforms) == base.DROP {
-		cp.deallocator.Release(record)
+		cp.deallocator.Discard(record)
 		// TODO: metrics
 		return nil
 	}
 	return record
 }
+
+// Close releases resources held by the extraction transforms
+func (cp *compositeParser) Close() {
+	cp.closeExtractions()
+}
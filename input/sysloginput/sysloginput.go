@@ -43,7 +43,7 @@ func init() {
 // NewInput creates a SyslogInput and starts the network listener
 func (cfg *Config) NewInput(_ logger.Logger, allocator *base.LogAllocator, schema base.LogSchema,
 	logBufferReceiver base.MultiSinkBufferReceiver, metricCreator promreg.MetricCreator,
-	stopRequest channels.Awaitable,
+	customMetrics *base.LogCustomMetricRegistry, stopRequest channels.Awaitable,
 ) (base.LogInput, error) {
 	if len(cfg.LevelMapping) == 0 {
 		return nil, fmt.Errorf(".levelMapping is empty")
@@ -60,13 +60,13 @@ func (cfg *Config) NewInput(_ logger.Logger, allocator *base.LogAllocator, schem
 		if err != nil {
 			parentLogger.Panic("failed to create parser: ", err)
 		}
-		extractionTransforms := bsupport.NewTransformsFromConfig(cfg.Extractions, schema, inputLogger, inputCounter)
-		return newCompositeParser(parser, extractionTransforms, allocator)
+		extractionTransforms, closeExtractions := bsupport.NewTransformsFromConfig(cfg.Extractions, schema, inputLogger, inputCounter)
+		return newCompositeParser(parser, extractionTransforms, closeExtractions, allocator)
 	}
 
 	inputMetricCreator := metricCreator.AddOrGetPrefix("input_", []string{"protocol"}, []string{"syslog"})
 
-	rawMessageReceiver := bsupport.NewLogParsingReceiver(inputLogger, createParser, logBufferReceiver, inputMetricCreator)
+	rawMessageReceiver := bsupport.NewLogParsingReceiver(inputLogger, createParser, logBufferReceiver, inputMetricCreator, customMetrics)
 
 	lsnr, addr, err := tcplistener.NewTCPLineListener(inputLogger, cfg.Address, syslogprotocol.TestRecordStart, rawMessageReceiver, stopRequest)
 	if err != nil {
@@ -92,9 +92,11 @@ func (cfg *Config) NewParser(parentLogger logger.Logger, allocator *base.LogAllo
 
 	slogger := parentLogger.WithField(defs.LabelComponent, "SyslogInput")
 
+	extractionTransforms, closeExtractions := bsupport.NewTransformsFromConfig(cfg.Extractions, schema, slogger, inputCounter)
 	return newCompositeParser(
 		syslogparser.MustNewParser(slogger, allocator, schema, cfg.LevelMapping, inputCounter),
-		bsupport.NewTransformsFromConfig(cfg.Extractions, schema, slogger, inputCounter),
+		extractionTransforms,
+		closeExtractions,
 		allocator,
 	), nil
 }
@@ -116,7 +118,7 @@ func (cfg *Config) VerifyConfig(schema base.LogSchema) error {
 	// this would also invoke schema.OnLocated on all fields to be used by real parsers
 	if err := func() error {
 		dummyMetricFactory := promreg.NewMetricFactory("verify_", nil, nil)
-		dummyInputCounter := base.NewLogInputCounter(dummyMetricFactory)
+		dummyInputCounter := base.NewLogInputCounter(dummyMetricFactory, base.NewLogCustomMetricRegistry("verify_"))
 		dummyLogAllocator := base.NewLogAllocator(schema, 1)
 		_, err := syslogparser.NewParser(logger.Root(), dummyLogAllocator, schema, cfg.LevelMapping, dummyInputCounter)
 		return err
@@ -46,7 +46,7 @@ extractions:
 	mfactory := promreg.NewMetricFactory("test_", nil, nil)
 
 	// create and launch input (the server)
-	input, inputErr := config.NewInput(logger.Root(), allocator, schema, logAggregator, mfactory, stopInput)
+	input, inputErr := config.NewInput(logger.Root(), allocator, schema, logAggregator, mfactory, base.NewLogCustomMetricRegistry("test_"), stopInput)
 	if !assert.NoError(t, inputErr) {
 		return
 	}
@@ -179,7 +179,7 @@ func (parser *syslogParser) Parse(input []byte, timestamp time.Time) *base.LogRe
 
 func (parser *syslogParser) onMalformed(record *base.LogRecord, warning string, rawLog []byte) {
 	parser.inputCounter.CountRecordDrop(record)
-	parser.allocator.Release(record)
+	parser.allocator.Discard(record)
 	// TODO: omit repeated warnings
 	if len(rawLog) > maxLoggingMessageSize {
 		parser.logger.Warn(warning, ": ", util.StringFromBytes(rawLog[:maxLoggingMessageSize]), "...")
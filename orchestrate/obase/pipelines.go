@@ -11,6 +11,8 @@ import (
 
 type outputWorkerSettings struct {
 	name       string
+	logger     logger.Logger
+	transforms []bconfig.LogTransformConfigHolder
 	bufferer   base.ChunkBufferer
 	serializer base.LogSerializer
 	chunkMaker base.LogChunkMaker
@@ -61,6 +63,8 @@ func PrepareSequentialPipeline(args bconfig.PipelineArgs) PipelineStarter {
 
 			return outputWorkerSettings{
 				name:       pair.Name,
+				logger:     outputLogger,
+				transforms: pair.Transformations,
 				bufferer:   bufferer,
 				serializer: pair.OutputConfig.Value.NewSerializer(outputLogger, args.Schema, outputTag),
 				chunkMaker: pair.OutputConfig.Value.NewChunkMaker(outputLogger, outputTag),
@@ -70,26 +74,35 @@ func PrepareSequentialPipeline(args bconfig.PipelineArgs) PipelineStarter {
 
 		// then prepare processing worker which is at the head of pipeline
 		// output names slice order should match the outputs order in bsupport.NewLogProcessingWorker
-		procTracker := base.NewLogProcessCounter(metricCreator, args.Schema, args.MetricKeyLocators,
+		procTracker := base.NewLogProcessCounter(metricCreator, args.CustomMetrics, args.Schema, args.MetricKeyLocators,
 			lo.Map(outputSettingsSlice, func(outputSettings outputWorkerSettings, _ int) string { return outputSettings.name }),
 		)
 
+		transformStages, closeTransforms := bsupport.NewTransformStagesFromConfig(args.TransformConfigs, args.Schema, parentLogger, procTracker)
+		closeFuncs := []func(){closeTransforms}
 		procWorker := bsupport.NewLogProcessingWorker(
 			parentLogger,
 			input,
 			args.Deallocator,
 			procTracker,
-			bsupport.NewTransformsFromConfig(args.TransformConfigs, args.Schema, parentLogger, procTracker),
+			transformStages,
 			lo.Map(outputSettingsSlice, func(outputSettings outputWorkerSettings, _ int) bsupport.OutputInterface {
+				outputTransforms, closeOutputTransforms := bsupport.NewTransformsFromConfig(outputSettings.transforms, args.Schema, outputSettings.logger, procTracker)
+				closeFuncs = append(closeFuncs, closeOutputTransforms)
 				return bsupport.OutputInterface{
 					LogSerializer: outputSettings.serializer,
 					LogChunkMaker: outputSettings.chunkMaker,
 					Name:          outputSettings.name,
 					AcceptChunk:   outputSettings.bufferer.Accept,
+					Transforms:    outputTransforms,
 				}
 			}),
 		)
 		procWorker.Stopped().Next(func() {
+			// transforms are released after the worker has stopped calling them
+			lo.ForEach(closeFuncs, func(closeFunc func(), _ int) {
+				closeFunc()
+			})
 			lo.ForEach(outputSettingsSlice, func(settings outputWorkerSettings, _ int) {
 				settings.bufferer.Destroy()
 			})
@@ -10,14 +10,13 @@ import (
 
 	"github.com/relex/gotils/logger"
 	"github.com/relex/slog-agent/base"
-	"github.com/relex/slog-agent/output/shared"
+	"github.com/relex/slog-agent/output/httpjson"
 	"github.com/stretchr/testify/assert"
 )
 
 const (
 	testChunkMaxSizeBytes = 1000
 	testChunkMaxRecords   = 1000
-	testBufCapacity       = 1000
 	testChunkIDSuffix     = ".ddtest"
 )
 
@@ -27,9 +26,7 @@ func countExpectedPayloadLength(payload string, writeIterations int) int {
 
 func TestDatadogOutput_Succeeds_OnGzippedInput(t *testing.T) {
 	log := logger.Root()
-	newChunkFunc := buildNewChunkFunc(log, testChunkMaxRecords, testChunkMaxSizeBytes)
-	factory := shared.NewChunkFactory(testChunkIDSuffix, testBufCapacity, newChunkFunc)
-	packer := shared.NewMessagePacker(log, factory)
+	packer := httpjson.NewChunkMaker(log, testChunkIDSuffix, chunkFraming, chunkCompression, testChunkMaxRecords, testChunkMaxSizeBytes)
 
 	payload := `testPayload`
 	writeIterations := 5
@@ -54,9 +51,7 @@ func TestDatadogOutput_Flushes_OnMaxRecordsReached(t *testing.T) {
 	localChunkMaxRecords := 5
 
 	log := logger.Root()
-	newChunkFunc := buildNewChunkFunc(log, localChunkMaxRecords, testChunkMaxSizeBytes)
-	factory := shared.NewChunkFactory(testChunkIDSuffix, testBufCapacity, newChunkFunc)
-	packer := shared.NewMessagePacker(log, factory)
+	packer := httpjson.NewChunkMaker(log, testChunkIDSuffix, chunkFraming, chunkCompression, localChunkMaxRecords, testChunkMaxSizeBytes)
 
 	payload := base.LogStream("testPayload")
 	writeIterations := 50
@@ -80,9 +75,7 @@ func TestDatadogOutput_Flushes_OnMaxRecordsReached(t *testing.T) {
 
 func TestDatadogOutput_Flushes_OnMaxBytesReached(t *testing.T) {
 	log := logger.Root()
-	newChunkFunc := buildNewChunkFunc(log, testChunkMaxRecords, testChunkMaxSizeBytes)
-	factory := shared.NewChunkFactory(testChunkIDSuffix, testBufCapacity, newChunkFunc)
-	packer := shared.NewMessagePacker(log, factory)
+	packer := httpjson.NewChunkMaker(log, testChunkIDSuffix, chunkFraming, chunkCompression, testChunkMaxRecords, testChunkMaxSizeBytes)
 
 	payload := "10bytes..."
 	iterationsTillOverflow := (testChunkMaxSizeBytes - len("[]")) / (len(payload) + len(","))
@@ -108,9 +101,7 @@ func TestDatadogOutput_Flushes_OnMaxBytesReached(t *testing.T) {
 
 func TestDatadogOutput_FlushNoPanic_OnNilCurrentChunk(t *testing.T) {
 	log := logger.Root()
-	newChunkFunc := buildNewChunkFunc(log, testChunkMaxRecords, testChunkMaxSizeBytes)
-	factory := shared.NewChunkFactory(testChunkIDSuffix, testBufCapacity, newChunkFunc)
-	packer := shared.NewMessagePacker(log, factory)
+	packer := httpjson.NewChunkMaker(log, testChunkIDSuffix, chunkFraming, chunkCompression, testChunkMaxRecords, testChunkMaxSizeBytes)
 
 	assert.Nil(t, packer.FlushBuffer())
 }
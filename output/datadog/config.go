@@ -3,6 +3,7 @@ package datadog
 import (
 	"errors"
 	"io"
+	"os"
 	"strings"
 	"time"
 
@@ -10,6 +11,7 @@ import (
 	"github.com/relex/gotils/promexporter/promreg"
 	"github.com/relex/slog-agent/base"
 	"github.com/relex/slog-agent/base/bconfig"
+	"github.com/relex/slog-agent/output/httpjson"
 	"github.com/relex/slog-agent/output/shared"
 )
 
@@ -25,9 +27,9 @@ const (
 	// Can be 0 in case there's no limit.
 	chunkMaxRecords = 1000
 
-	// bufCapacity is the initial capacity for buffers used for chunk and compression.
-	// It only needs to be large enough to contain the largest compressed message.
-	bufCapacity = 1 * 1024 * 1024
+	// chunkFraming and chunkCompression are the names of framing and compression of chunks in 'http' output
+	chunkFraming     = "array"
+	chunkCompression = "gzip"
 )
 
 type Config struct {
@@ -36,9 +38,7 @@ type Config struct {
 	Upstream       UpstreamConfig      `yaml:"upstream"`
 }
 
-type SerializationConfig struct {
-	HiddenFields []string `yaml:"hiddenFields"`
-}
+type SerializationConfig = shared.JSONSerializationConfig
 
 type UpstreamConfig struct {
 	Address     string        `yaml:"address"`
@@ -60,13 +60,22 @@ func (cfg *Config) NewSerializer(parentLogger logger.Logger, schema base.LogSche
 
 //nolint:revive
 func (cfg *Config) NewChunkMaker(parentLogger logger.Logger, tag string) base.LogChunkMaker {
-	newChunkFunc := buildNewChunkFunc(parentLogger, chunkMaxRecords, chunkMaxSizeBytes)
-	chunkFactory := shared.NewChunkFactory(chunkIDSuffix, bufCapacity, newChunkFunc)
-	return shared.NewMessagePacker(parentLogger, chunkFactory)
+	return httpjson.NewChunkMaker(parentLogger, chunkIDSuffix, chunkFraming, chunkCompression, chunkMaxRecords, chunkMaxSizeBytes)
 }
 
 func (cfg *Config) NewForwarder(parentLogger logger.Logger, args base.ChunkConsumerArgs, metricCreator promreg.MetricCreator) base.ChunkConsumer {
-	return NewClientWorker(parentLogger, args, metricCreator, cfg.Upstream)
+	upstream := httpjson.UpstreamConfig{
+		URL:          cfg.Upstream.Address,
+		Method:       "",
+		Headers:      nil,
+		SuccessCodes: nil,
+		HTTPTimeout:  cfg.Upstream.HTTPTimeout,
+	}
+	// the API key is read at startup and the header is omitted if it's unset
+	if apiKey := os.Getenv("DD_API_KEY"); len(apiKey) > 0 {
+		upstream.Headers = []httpjson.HeaderConfig{{Name: "DD-API-KEY", Value: apiKey, ValueFromEnv: "", ValueFromFile: ""}}
+	}
+	return httpjson.NewClientWorker(parentLogger, args, metricCreator, upstream, chunkFraming, chunkCompression)
 }
 
 //nolint:revive
@@ -79,5 +88,5 @@ func (cfg *Config) VerifyConfig(schema base.LogSchema) error {
 		return errors.New("expected a valid datadog api timeout")
 	}
 
-	return nil
+	return shared.VerifyJSONSerializationConfig(cfg.Serialization, schema, ".serialization")
 }
@@ -4,13 +4,20 @@ import (
 	"encoding/json"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/relex/gotils/logger"
 	"github.com/relex/slog-agent/base"
 	"github.com/relex/slog-agent/output/shared"
+	"github.com/relex/slog-agent/rewrite"
+	"github.com/relex/slog-agent/util"
 	"github.com/stretchr/testify/assert"
 )
 
+func init() {
+	rewrite.Register()
+}
+
 var testCfg = SerializationConfig{
 	HiddenFields: []string{},
 }
@@ -38,3 +45,101 @@ func verifyLogStream(t *testing.T, stream base.LogStream, record *base.LogRecord
 		assert.Equal(t, expectedValue, actualData)
 	}
 }
+
+func TestSerializerExtraFields(t *testing.T) {
+	schema, serr := base.NewLogSchema([]string{"app", "message"}, 2, 2)
+	assert.NoError(t, serr)
+	serializer := NewEventSerializer(logger.Root(), schema, testCfg, "")
+	record := schema.NewTestRecord2(time.UnixMilli(1606818640000), base.LogFields{"myapp", "Hello"})
+	record.Extra.Set("user", "john")
+	record.Extra.Set("message", "not overriding")
+
+	var streamData map[string]string
+	assert.NoError(t, json.Unmarshal(serializer.SerializeRecord(record), &streamData))
+	assert.Equal(t, map[string]string{
+		"app":       "myapp",
+		"message":   "Hello",
+		"user":      "john",
+		"timestamp": "1606818640000",
+	}, streamData)
+}
+
+func TestSerializerRewriteFields(t *testing.T) {
+	schema, serr := base.NewLogSchema([]string{"class", "message"}, 2, 2)
+	assert.NoError(t, serr)
+	cfg := &Config{}
+	assert.NoError(t, util.UnmarshalYamlString(`
+type: datadog
+serialization:
+  hiddenFields: [class]
+  rewriteFields:
+    message:
+      - type: inline
+        field: class
+      - type: escapeJSON
+        unescape: true
+upstream:
+  address: https://localhost/api/v2/logs
+  httpTimeout: 30s
+`, cfg))
+	assert.NoError(t, cfg.VerifyConfig(schema))
+	serializer := NewEventSerializer(logger.Root(), schema, cfg.Serialization, "")
+	record := schema.NewTestRecord2(time.UnixMilli(1606818640000), base.LogFields{"main", `say "hi"\n<end>\x`})
+
+	var streamData map[string]string
+	assert.NoError(t, json.Unmarshal(serializer.SerializeRecord(record), &streamData))
+	assert.Equal(t, map[string]string{
+		"message":   "class=main say \"hi\"\n<end>\\x",
+		"timestamp": "1606818640000",
+	}, streamData)
+	assert.True(t, record.Unescaped)
+}
+
+func TestSerializerRewriteFieldsEscaping(t *testing.T) {
+	schema, serr := base.NewLogSchema([]string{"class", "message"}, 2, 2)
+	assert.NoError(t, serr)
+	cfg := &Config{}
+	assert.NoError(t, util.UnmarshalYamlString(`
+type: datadog
+serialization:
+  hiddenFields: [class]
+  rewriteFields:
+    message:
+      - type: inline
+        field: class
+      - type: escapeJSON
+upstream:
+  address: https://localhost/api/v2/logs
+  httpTimeout: 30s
+`, cfg))
+	assert.NoError(t, cfg.VerifyConfig(schema))
+	serializer := NewEventSerializer(logger.Root(), schema, cfg.Serialization, "")
+	record := schema.NewTestRecord2(time.UnixMilli(1606818640000), base.LogFields{`a"b\c`, `msg\n`})
+
+	stream := serializer.SerializeRecord(record)
+	assert.True(t, json.Valid(stream), string(stream))
+	var streamData map[string]string
+	assert.NoError(t, json.Unmarshal(stream, &streamData))
+	assert.Equal(t, map[string]string{
+		"message":   `class=a"b\c msg\n`,
+		"timestamp": "1606818640000",
+	}, streamData)
+	assert.False(t, record.Unescaped)
+}
+
+func TestSerializerRewriteFieldsConfig(t *testing.T) {
+	schema, serr := base.NewLogSchema([]string{"class", "message"}, 2, 2)
+	assert.NoError(t, serr)
+	cfg := &Config{}
+	assert.NoError(t, util.UnmarshalYamlString(`
+type: datadog
+serialization:
+  rewriteFields:
+    message:
+      - type: unescape
+upstream:
+  address: https://localhost/api/v2/logs
+  httpTimeout: 30s
+`, cfg))
+	assert.ErrorContains(t, cfg.VerifyConfig(schema), "the last rewriter must be 'escapeJSON'")
+}
@@ -0,0 +1,165 @@
+package datadog
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/relex/gotils/logger"
+	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/base/bsupport"
+	"github.com/relex/slog-agent/util"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/exp/slices"
+)
+
+// mapEventSerializer is the previous implementation by map and encoding/json, kept as reference for comparison
+type mapEventSerializer struct {
+	schema         base.LogSchema
+	fieldMasks     []bool
+	fieldRewriters []base.LogRewriter
+	ddtags         string
+}
+
+func newMapEventSerializer(schema base.LogSchema, config SerializationConfig, ddtags string) base.LogSerializer {
+	return &mapEventSerializer{
+		schema: schema,
+		fieldMasks: lo.Map(schema.GetFieldNames(), func(fieldName string, _ int) bool {
+			return len(fieldName) == 0 || slices.Contains(config.HiddenFields, fieldName)
+		}),
+		fieldRewriters: lo.Map(schema.GetFieldNames(), func(fieldName string, _ int) base.LogRewriter {
+			return bsupport.NewRewritersFromConfig(config.RewriteFields[fieldName], schema)
+		}),
+		ddtags: ddtags,
+	}
+}
+
+func (packer *mapEventSerializer) SerializeRecord(record *base.LogRecord) base.LogStream {
+	fieldNames := packer.schema.GetFieldNames()
+	outputMap := make(map[string]any, len(fieldNames)+4)
+
+	for i, fieldName := range fieldNames {
+		if packer.fieldMasks[i] || record.Fields[i] == "" {
+			continue
+		}
+		if headRewriter := packer.fieldRewriters[i]; headRewriter != nil {
+			value := record.Fields[i]
+			buffer := make([]byte, headRewriter.MaxFieldLength(value, record)+2)
+			buffer[0] = '"'
+			end := 1 + headRewriter.WriteFieldBody(value, record, buffer[1:])
+			buffer[end] = '"'
+			outputMap[fieldName] = json.RawMessage(buffer[:end+1])
+		} else {
+			outputMap[fieldName] = record.Fields[i]
+		}
+	}
+
+	for _, field := range record.Extra {
+		if len(field.Value) == 0 {
+			continue
+		}
+		if _, exists := outputMap[field.Key]; !exists {
+			outputMap[field.Key] = field.Value
+		}
+	}
+
+	outputMap["timestamp"] = strconv.FormatInt(record.Timestamp.UnixMilli(), 10)
+
+	if outputMap["ddtags"] == nil && len(packer.ddtags) > 0 {
+		outputMap["ddtags"] = packer.ddtags
+	}
+
+	data, _ := json.Marshal(outputMap) //nolint:errcheck
+	return data
+}
+
+var benchSchema = base.MustNewLogSchema([]string{"host", "app", "level", "timestamp", "ddtags", "log", "class", "user"})
+
+var singleFieldSchema = base.MustNewLogSchema([]string{"log"})
+
+var benchConfigYaml = `
+type: datadog
+serialization:
+  hiddenFields: [host, class]
+  rewriteFields:
+    log:
+      - type: inline
+        field: class
+      - type: escapeJSON
+        unescape: true
+upstream:
+  address: https://localhost/api/v2/logs
+  httpTimeout: 30s
+`
+
+func newBenchRecords() []*base.LogRecord {
+	tm := time.Date(2023, 5, 6, 7, 8, 9, 123456789, time.UTC)
+	newRecord := func(fields base.LogFields, extra ...string) *base.LogRecord {
+		record := benchSchema.NewTestRecord2(tm, fields)
+		record.Extra = make(base.LogExtraFields, 0, 8)
+		for i := 0; i+1 < len(extra); i += 2 {
+			record.Extra.Set(extra[i], extra[i+1])
+		}
+		return record
+	}
+	return []*base.LogRecord{
+		newRecord(base.LogFields{"web1", "nginx", "info", "", "", `GET /index.html?a=1&b=<2> "curl/7.0"`, "", ""}),
+		newRecord(base.LogFields{"web1", "nginx", "warn", "ignored", "env:prod", `multi\nline\tlog \x \\`, "Klass", ""},
+			"zone", "eu", "app", "not overriding", "ddtags", "not overriding either", "timestamp", "overridden"),
+		newRecord(base.LogFields{"", "db", "", "", "", "unicode 世界 \u2028\u2029 invalid \xff\xfe ctl \x01\x7f", "", ""},
+			"user", "extra user", "ddtags", "env:extra", "", "empty key", "Zeta", "capital", "ümlaut", "key \"quoted\""),
+		newRecord(base.LogFields{"", "", "", "", "", "", "", ""}, "empty", ""),
+	}
+}
+
+func TestSerializerOutputSameAsJSONMarshal(t *testing.T) {
+	cfg := &Config{}
+	assert.NoError(t, util.UnmarshalYamlString(benchConfigYaml, cfg))
+	assert.NoError(t, cfg.VerifyConfig(benchSchema))
+
+	for _, ddtags := range []string{"", "source:slog,env:\"test\""} {
+		expectedSerializer := newMapEventSerializer(benchSchema, cfg.Serialization, ddtags)
+		actualSerializer := NewEventSerializer(logger.Root(), benchSchema, cfg.Serialization, ddtags)
+		expectedRecords := newBenchRecords()
+		for i, record := range newBenchRecords() {
+			expected := string(expectedSerializer.SerializeRecord(expectedRecords[i]))
+			assert.Equal(t, expected, string(actualSerializer.SerializeRecord(record)), "record %d", i)
+			assert.True(t, json.Valid([]byte(expected)))
+		}
+	}
+
+	// buffer growth
+	serializer := NewEventSerializer(logger.Root(), singleFieldSchema, SerializationConfig{}, "")
+	record := singleFieldSchema.NewTestRecord1(base.LogFields{string(make([]byte, 1000000))})
+	expected := newMapEventSerializer(singleFieldSchema, SerializationConfig{}, "").SerializeRecord(record)
+	assert.Equal(t, string(expected), string(serializer.SerializeRecord(record)))
+}
+
+func BenchmarkSerializerByMap(b *testing.B) {
+	benchmarkSerializer(b, func(config SerializationConfig) base.LogSerializer {
+		return newMapEventSerializer(benchSchema, config, "source:slog")
+	})
+}
+
+func BenchmarkSerializer(b *testing.B) {
+	benchmarkSerializer(b, func(config SerializationConfig) base.LogSerializer {
+		return NewEventSerializer(logger.Root(), benchSchema, config, "source:slog")
+	})
+}
+
+func benchmarkSerializer(b *testing.B, newSerializer func(config SerializationConfig) base.LogSerializer) {
+	cfg := &Config{}
+	assert.NoError(b, util.UnmarshalYamlString(benchConfigYaml, cfg))
+	serializer := newSerializer(cfg.Serialization)
+	records := newBenchRecords()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for iter := 0; iter < b.N; iter++ {
+		record := records[iter%len(records)]
+		record.Unescaped = false
+		serializer.SerializeRecord(record)
+	}
+}
@@ -99,8 +99,9 @@ func (packer *eventSerializer) encodeRecord(record *base.LogRecord, buffer []byt
 	position = EncodeEventTime(buffer, position, record.Timestamp)
 	// root-array[1]: field-map length
 	reservedRootMapLenPosition := position // reserve space to be filled later
+	maxRootMapSize := len(fields) + len(record.Extra) + 1
 	switch {
-	case len(fields)+1 < 16:
+	case maxRootMapSize < 16:
 		position = fastmsgpack.ReserveLen4(position)
 	default:
 		position = fastmsgpack.ReserveLen16(position)
@@ -158,9 +159,32 @@ func (packer *eventSerializer) encodeRecord(record *base.LogRecord, buffer []byt
 			rootMapSize++
 		}
 	}
+	// root-array[1]: field-map key-value pairs of extra fields not defined in schema, never rewritten
+	for _, field := range record.Extra {
+		if len(field.Value) == 0 {
+			continue
+		}
+		switch {
+		case len(field.Key) < 16:
+			position = fastmsgpack.EncodeString4(buffer, position, field.Key)
+		case len(field.Key) < 65536:
+			position = fastmsgpack.EncodeString16(buffer, position, field.Key)
+		default:
+			position = fastmsgpack.EncodeString32(buffer, position, field.Key)
+		}
+		switch {
+		case len(field.Value) < 16:
+			position = fastmsgpack.EncodeString4(buffer, position, field.Value)
+		case len(field.Value) < 65536:
+			position = fastmsgpack.EncodeString16(buffer, position, field.Value)
+		default:
+			position = fastmsgpack.EncodeString32(buffer, position, field.Value)
+		}
+		rootMapSize++
+	}
 	// update root map size
 	switch {
-	case len(fields)+1 < 16: // use the same length type as reserved
+	case maxRootMapSize < 16: // use the same length type as reserved
 		fastmsgpack.EncodeMapLen4(buffer, reservedRootMapLenPosition, rootMapSize)
 	default:
 		fastmsgpack.EncodeMapLen16(buffer, reservedRootMapLenPosition, rootMapSize)
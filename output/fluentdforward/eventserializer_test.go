@@ -4,9 +4,11 @@ import (
 	"bytes"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/relex/fluentlib/protocol/forwardprotocol"
 	"github.com/relex/gotils/logger"
+	"github.com/relex/slog-agent/base"
 	"github.com/relex/slog-agent/base/bconfig"
 	"github.com/relex/slog-agent/output/shared"
 	"github.com/stretchr/testify/assert"
@@ -42,3 +44,25 @@ func testVerifyLogRecord(t *testing.T, nth int, decoder *msgpack.Decoder) {
 	assert.Equal(t, shared.TestInputRecords[nth].Timestamp.UnixNano(), entry.Time.UnixNano(), fmt.Sprintf("record[%d] timestamp", nth))
 	assert.Equal(t, shared.TestOutputFieldMaps[nth], entry.Record, fmt.Sprintf("record[%d] fields", nth))
 }
+
+func TestForwardLogEventSerializerExtraFields(t *testing.T) {
+	schema, serr := base.NewLogSchema([]string{"app", "message"}, 2, 2)
+	assert.NoError(t, serr)
+	serializer, err := NewEventSerializer(logger.Root(), schema, SerializationConfig{EnvironmentFields: []string{"app"}})
+	assert.NoError(t, err)
+	record := schema.NewTestRecord2(time.Date(2020, 12, 1, 10, 30, 40, 0, time.UTC), base.LogFields{"myapp", "Hello"})
+	assert.True(t, record.Extra.Set("user", "john"))
+	assert.True(t, record.Extra.Set("empty", ""))
+	assert.True(t, record.Extra.Set("user", "jane"))
+	assert.False(t, record.Extra.Set("overflow", "1"))
+
+	var entry forwardprotocol.EventEntry
+	assert.NoError(t, msgpack.NewDecoder(bytes.NewBuffer(serializer.SerializeRecord(record))).Decode(&entry))
+	assert.Equal(t, map[string]interface{}{
+		"message": "Hello",
+		"user":    "jane",
+		"environment": map[string]interface{}{
+			"app": "myapp",
+		},
+	}, entry.Record)
+}
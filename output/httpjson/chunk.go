@@ -0,0 +1,122 @@
+package httpjson
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/relex/gotils/logger"
+	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/output/shared"
+	"github.com/relex/slog-agent/util"
+)
+
+type intermediateChunk struct {
+	id                   string
+	framing              framing
+	numRecords, numBytes int
+	maxRecords, maxBytes int
+	compressor           io.WriteCloser // could be something like a gzip.Writer or nil to disable compression
+	writeBuffer          *bytes.Buffer  // an actual buffer that compressor writes to
+}
+
+// NewChunkMaker creates LogChunkMaker to put serialized records into chunks by the names of framing and compression
+//
+// Chunks are flushed when either maxRecords or maxBytes of uncompressed data is reached, if non-zero
+func NewChunkMaker(parentLogger logger.Logger, idSuffix string, framingName string, compressionName string,
+	maxRecords, maxBytes int,
+) base.LogChunkMaker {
+	var initCompressor shared.InitCompressorFunc
+	if newCompressorFunc := lookupCompression(parentLogger, compressionName).newCompressorFunc; newCompressorFunc != nil {
+		initCompressor = newCompressorFunc(parentLogger)
+	}
+	newChunkFunc := buildNewChunkFunc(parentLogger, lookupFraming(parentLogger, framingName), initCompressor, maxRecords, maxBytes)
+	chunkFactory := shared.NewChunkFactory(idSuffix, bufCapacity, newChunkFunc)
+	return shared.NewMessagePacker(parentLogger, chunkFactory)
+}
+
+func buildNewChunkFunc(log logger.Logger, framing framing, initCompressor shared.InitCompressorFunc, maxRecords, maxBytes int) shared.NewChunkFunc {
+	return func(id string, writeBuffer *bytes.Buffer) shared.Chunker {
+		chunk := &intermediateChunk{
+			id:          id,
+			framing:     framing,
+			maxRecords:  maxRecords,
+			maxBytes:    maxBytes,
+			compressor:  nil,
+			writeBuffer: writeBuffer,
+		}
+
+		if initCompressor != nil {
+			chunk.compressor = initCompressor(log, chunk.writeBuffer)
+		}
+
+		if _, err := chunk.writer().Write([]byte(framing.begin)); err != nil {
+			log.Error(err)
+		} else {
+			chunk.numBytes += len(framing.begin)
+		}
+
+		return chunk
+	}
+}
+
+func (chunk *intermediateChunk) writer() io.Writer {
+	if chunk.compressor != nil {
+		return chunk.compressor
+	}
+	return chunk.writeBuffer
+}
+
+// Write appends new log to log chunk
+func (chunk *intermediateChunk) Write(data base.LogStream) error {
+	writer := chunk.writer()
+
+	// separators go before every record other than the first one, and the last one is replaced by framing.end
+	if chunk.numRecords != 0 {
+		if _, err := writer.Write([]byte(chunk.framing.separator)); err != nil {
+			return err
+		}
+		chunk.numBytes += len(chunk.framing.separator)
+	}
+
+	_, err := writer.Write(data)
+	if err == nil {
+		chunk.numRecords++
+		chunk.numBytes += len(data)
+	}
+
+	return err
+}
+
+func (chunk *intermediateChunk) FinalizeChunk() (*base.LogChunk, error) {
+	defer chunk.writeBuffer.Reset()
+
+	if _, err := chunk.writer().Write([]byte(chunk.framing.end)); err != nil {
+		return nil, err
+	}
+	chunk.numBytes += len(chunk.framing.end)
+
+	if chunk.compressor != nil {
+		if err := chunk.compressor.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &base.LogChunk{
+		ID:    chunk.id,
+		Data:  util.CopySlice(chunk.writeBuffer.Bytes()),
+		Saved: false,
+	}, nil
+}
+
+func (chunk *intermediateChunk) CanAppendData(dataLength int) bool {
+	// flush when the amount of log records reaches max permitted amount, if it is defined
+	if chunk.maxRecords > 0 && chunk.numRecords >= chunk.maxRecords {
+		return false
+	}
+	// otherwise flush when the total size reaches max permitted amount
+	if chunk.maxBytes > 0 && chunk.numBytes+len(chunk.framing.separator)+dataLength+len(chunk.framing.end) > chunk.maxBytes {
+		return false
+	}
+
+	return true
+}
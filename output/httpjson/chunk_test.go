@@ -0,0 +1,81 @@
+package httpjson
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/relex/gotils/logger"
+	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/output/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChunkFramingAndCompression(t *testing.T) {
+	payloads := []string{`{"a":"1"}`, `{"b":"x\ny"}`, `{"c":"3"}`}
+	expectedBodies := map[string]string{
+		"ndjson": "{\"a\":\"1\"}\n{\"b\":\"x\\ny\"}\n{\"c\":\"3\"}\n",
+		"array":  `[{"a":"1"},{"b":"x\ny"},{"c":"3"}]`,
+	}
+
+	for framingName, expectedBody := range expectedBodies {
+		for compressionName := range compressionMap {
+			cfg := &Config{Framing: framingName, Compression: compressionName}
+			packer := cfg.NewChunkMaker(logger.Root(), "")
+			for _, payload := range payloads {
+				assert.Nil(t, packer.WriteStream(base.LogStream(payload)))
+			}
+			chunk := packer.FlushBuffer()
+			if !assert.NotNil(t, chunk) {
+				continue
+			}
+			assert.True(t, cfg.MatchChunkID(chunk.ID))
+			assert.True(t, strings.HasSuffix(chunk.ID, ".hj-"+compressionName+"-"+framingName), chunk.ID)
+			for otherCompressionName := range compressionMap {
+				otherCfg := &Config{Framing: framingName, Compression: otherCompressionName}
+				assert.Equal(t, otherCompressionName == compressionName, otherCfg.MatchChunkID(chunk.ID), chunk.ID)
+			}
+
+			body, err := decompressChunk(chunk.Data, compressionName)
+			assert.NoError(t, err)
+			assert.Equal(t, expectedBody, string(body), "%s, %s", framingName, compressionName)
+
+			var dump bytes.Buffer
+			info, derr := cfg.DecodeChunkToJSON(*chunk, []byte("|"), false, &dump)
+			assert.NoError(t, derr)
+			assert.Equal(t, len(payloads), info.NumRecords)
+			assert.Equal(t, `{"a":"1"}|{"b":"x\ny"}|{"c":"3"}`, dump.String(), "%s, %s", framingName, compressionName)
+		}
+	}
+}
+
+func TestChunkFlushesOnMaxBytesReached(t *testing.T) {
+	payload := `{"msg":"10 bytes"}` // 18 bytes
+	newChunkFunc := buildNewChunkFunc(logger.Root(), framingMap["array"], nil, 0, 100)
+	packer := shared.NewMessagePacker(logger.Root(), shared.NewChunkFactory(chunkIDSuffix, 1000, newChunkFunc))
+
+	// 1 + 5 * 18 + 4 + 1 = 96
+	for i := 0; i < 5; i++ {
+		assert.Nil(t, packer.WriteStream(base.LogStream(payload)))
+	}
+	chunk := packer.WriteStream(base.LogStream(payload))
+	if assert.NotNil(t, chunk) {
+		assert.Equal(t, 96, len(chunk.Data))
+	}
+}
+
+func TestChunkReusesCompressor(t *testing.T) {
+	for compressionName := range compressionMap {
+		packer := NewChunkMaker(logger.Root(), chunkIDSuffix, "ndjson", compressionName, 2, 0)
+		var bodies []string
+		for i := 0; i < 6; i++ {
+			if chunk := packer.WriteStream(base.LogStream(fmt.Sprintf(`{"n":%d}`, i))); chunk != nil {
+				body, err := decompressChunk(chunk.Data, compressionName)
+				assert.NoError(t, err, compressionName)
+				bodies = append(bodies, string(body))
+			}
+		}
+		assert.Equal(t, []string{"{\"n\":0}\n{\"n\":1}\n", "{\"n\":2}\n{\"n\":3}\n"}, bodies, compressionName)
+	}
+}
@@ -0,0 +1,118 @@
+package httpjson
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/relex/gotils/logger"
+	"github.com/relex/gotils/promexporter/promreg"
+	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/defs"
+	"github.com/relex/slog-agent/output/baseoutput"
+	"golang.org/x/exp/slices"
+)
+
+// maxErrorBodyLength is the max length of response bodies to be included in errors
+const maxErrorBodyLength = 1024
+
+type clientWorker struct {
+	logger       logger.Logger
+	client       *http.Client
+	request      *http.Request
+	successCodes []int // any 2xx if empty
+}
+
+// NewClientWorker creates ChunkConsumer to send chunks by HTTP requests, with Content-Type and Content-Encoding set by
+// the names of framing and compression used to make the chunks
+func NewClientWorker(parentLogger logger.Logger, args base.ChunkConsumerArgs, metricCreator promreg.MetricCreator,
+	cfg UpstreamConfig, framingName string, compressionName string,
+) base.ChunkConsumer {
+	clientLogger := parentLogger.WithField(defs.LabelComponent, "HTTPClient")
+
+	framing := lookupFraming(clientLogger, framingName)
+	compression := lookupCompression(clientLogger, compressionName)
+	worker, err := newClientWorker(clientLogger, cfg, framing, compression)
+	if err != nil {
+		clientLogger.Panic(err)
+	}
+
+	return baseoutput.NewClientWorker(
+		clientLogger,
+		args,
+		metricCreator,
+		func() (baseoutput.ClosableClientConnection, error) {
+			return worker, nil
+		},
+		0, // no reconnects are required for an http connection
+	)
+}
+
+func newClientWorker(clientLogger logger.Logger, cfg UpstreamConfig, framing framing, compression compression) (*clientWorker, error) {
+	method := cfg.Method
+	if len(method) == 0 {
+		method = http.MethodPost
+	}
+	rq, err := http.NewRequest(method, cfg.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	rq.Header.Set("Content-Type", framing.contentType)
+	if len(compression.contentEncoding) > 0 {
+		rq.Header.Set("Content-Encoding", compression.contentEncoding)
+	}
+	// custom headers may override the defaults above
+	for _, header := range cfg.Headers {
+		value, verr := header.resolveValue()
+		if verr != nil {
+			return nil, fmt.Errorf("header '%s': %w", header.Name, verr)
+		}
+		rq.Header.Set(header.Name, value)
+	}
+
+	return &clientWorker{
+		logger:       clientLogger,
+		client:       &http.Client{Timeout: cfg.HTTPTimeout},
+		request:      rq,
+		successCodes: cfg.SuccessCodes,
+	}, nil
+}
+
+func (worker *clientWorker) Logger() logger.Logger {
+	return worker.logger
+}
+
+func (worker *clientWorker) SendChunk(chunk base.LogChunk, _ time.Time) error {
+	worker.request.Body = io.NopCloser(bytes.NewReader(chunk.Data))
+	worker.request.ContentLength = int64(len(chunk.Data))
+	defer func() { worker.request.Body = nil }()
+
+	resp, err := worker.client.Do(worker.request)
+	if err != nil {
+		return fmt.Errorf("send chunk error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if !worker.isSuccess(resp.StatusCode) {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyLength))
+		if err != nil {
+			return fmt.Errorf("couldn't read response body: %w", err)
+		}
+		return fmt.Errorf("got a status %d with body %s", resp.StatusCode, body)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body) // drain for connection reuse
+	return nil
+}
+
+func (worker *clientWorker) isSuccess(statusCode int) bool {
+	if len(worker.successCodes) == 0 {
+		return statusCode >= 200 && statusCode < 300
+	}
+	return slices.Contains(worker.successCodes, statusCode)
+}
+
+func (worker *clientWorker) Close()                                          {}                 //nolint:revive
+func (worker *clientWorker) SendPing(deadline time.Time) error               { return nil }     //nolint:revive
+func (worker *clientWorker) ReadChunkAck(deadline time.Time) (string, error) { return "", nil } //nolint:revive
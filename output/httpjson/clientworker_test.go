@@ -0,0 +1,82 @@
+package httpjson
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/relex/gotils/logger"
+	"github.com/relex/slog-agent/base"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientWorkerSendChunk(t *testing.T) {
+	var lastRequest *http.Request
+	var lastBody []byte
+	statusCode := http.StatusAccepted
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastRequest = r
+		lastBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(statusCode)
+		_, _ = w.Write([]byte("rejected"))
+	}))
+	defer srv.Close()
+
+	t.Setenv("TEST_HTTP_TOKEN", "env-token")
+	secretPath := filepath.Join(t.TempDir(), "secret")
+	assert.NoError(t, os.WriteFile(secretPath, []byte("file-secret\n"), 0o600))
+
+	worker, err := newClientWorker(logger.Root(), UpstreamConfig{
+		URL:    srv.URL + "/ingest",
+		Method: http.MethodPut,
+		Headers: []HeaderConfig{
+			{Name: "Authorization", ValueFromEnv: "TEST_HTTP_TOKEN"},
+			{Name: "X-Secret", ValueFromFile: secretPath},
+			{Name: "X-Source", Value: "slog"},
+		},
+		SuccessCodes: []int{http.StatusAccepted},
+		HTTPTimeout:  5 * time.Second,
+	}, framingMap["ndjson"], compressionMap["zstd"])
+	assert.NoError(t, err)
+
+	assert.NoError(t, worker.SendChunk(base.LogChunk{ID: "1.hj", Data: []byte("data-1")}, time.Time{}))
+	assert.Equal(t, http.MethodPut, lastRequest.Method)
+	assert.Equal(t, "/ingest", lastRequest.URL.Path)
+	assert.Equal(t, "application/x-ndjson", lastRequest.Header.Get("Content-Type"))
+	assert.Equal(t, "zstd", lastRequest.Header.Get("Content-Encoding"))
+	assert.Equal(t, "env-token", lastRequest.Header.Get("Authorization"))
+	assert.Equal(t, "file-secret", lastRequest.Header.Get("X-Secret"))
+	assert.Equal(t, "slog", lastRequest.Header.Get("X-Source"))
+	assert.Equal(t, "data-1", string(lastBody))
+
+	// 2xx is not success unless listed
+	statusCode = http.StatusOK
+	assert.EqualError(t, worker.SendChunk(base.LogChunk{ID: "2.hj", Data: []byte("data-2")}, time.Time{}),
+		"got a status 200 with body rejected")
+	assert.Equal(t, "data-2", string(lastBody))
+}
+
+func TestClientWorkerDefaultSuccessCodes(t *testing.T) {
+	statusCode := http.StatusNoContent
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(statusCode)
+	}))
+	defer srv.Close()
+
+	worker, err := newClientWorker(logger.Root(), UpstreamConfig{
+		URL:         srv.URL,
+		HTTPTimeout: 5 * time.Second,
+	}, framingMap["array"], compressionMap["none"])
+	assert.NoError(t, err)
+	assert.Equal(t, http.MethodPost, worker.request.Method)
+	assert.Equal(t, "application/json", worker.request.Header.Get("Content-Type"))
+	assert.Empty(t, worker.request.Header.Get("Content-Encoding"))
+
+	assert.NoError(t, worker.SendChunk(base.LogChunk{ID: "1.hj", Data: []byte("[]")}, time.Time{}))
+	statusCode = http.StatusServiceUnavailable
+	assert.Error(t, worker.SendChunk(base.LogChunk{ID: "2.hj", Data: []byte("[]")}, time.Time{}))
+}
@@ -0,0 +1,220 @@
+// Package httpjson provides 'http' output, which sends logs in JSON objects to generic HTTP collectors, as
+// newline-delimited JSON or JSON arrays in request bodies
+//
+// Each log record is serialized the same way as 'datadog' output, with all non-empty fields on top level plus
+// "timestamp" in Unix milliseconds. The chunk maker and client worker here are also used by 'datadog' output, as JSON
+// arrays compressed by gzip.
+package httpjson
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/relex/gotils/logger"
+	"github.com/relex/gotils/promexporter/promreg"
+	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/base/bconfig"
+	"github.com/relex/slog-agent/output/shared"
+)
+
+const (
+	// chunkIDSuffix is an output-specific file extension for generated chunks, followed by the compression and framing
+	// of chunk data, e.g. ".hj-gzip-array", for chunks saved in a different format not to be sent after config changes.
+	chunkIDSuffix = ".hj"
+
+	// chunkMaxSizeBytes defines the max uncompressed data size of a LogChunk.
+	chunkMaxSizeBytes = 4 * 1024 * 1024
+
+	// chunkMaxRecords is the max amount of log entries a chunk can hold before flushing.
+	// Can be 0 in case there's no limit.
+	chunkMaxRecords = 0
+
+	// bufCapacity is the initial capacity for buffers used for chunk and compression.
+	// It only needs to be large enough to contain the largest compressed message.
+	bufCapacity = 1 * 1024 * 1024
+)
+
+// Config defines configuration for http output
+type Config struct {
+	bconfig.Header `yaml:",inline"`
+	Serialization  SerializationConfig `yaml:"serialization"`
+	Framing        string              `yaml:"framing"`     // see framingMap
+	Compression    string              `yaml:"compression"` // see compressionMap
+	Upstream       UpstreamConfig      `yaml:"upstream"`
+}
+
+// SerializationConfig defines the serialization section in config file
+type SerializationConfig = shared.JSONSerializationConfig
+
+// UpstreamConfig defines the upstream section in config file
+type UpstreamConfig struct {
+	URL          string         `yaml:"url"`
+	Method       string         `yaml:"method"` // default POST
+	Headers      []HeaderConfig `yaml:"headers"`
+	SuccessCodes []int          `yaml:"successCodes"` // default any 2xx
+	HTTPTimeout  time.Duration  `yaml:"httpTimeout"`
+}
+
+// HeaderConfig defines a request header, with the value from one of Value, ValueFromEnv or ValueFromFile
+type HeaderConfig struct {
+	Name          string `yaml:"name"`
+	Value         string `yaml:"value"`
+	ValueFromEnv  string `yaml:"valueFromEnv"`  // name of environment variable
+	ValueFromFile string `yaml:"valueFromFile"` // path of file, with surrounding spaces trimmed
+}
+
+// framing defines how serialized records are put together in request bodies
+type framing struct {
+	begin       string
+	separator   string
+	end         string
+	contentType string
+}
+
+var framingMap = map[string]framing{
+	"ndjson": {begin: "", separator: "\n", end: "\n", contentType: "application/x-ndjson"},
+	"array":  {begin: "[", separator: ",", end: "]", contentType: "application/json"},
+}
+
+// compression defines how chunks are compressed, and the value of Content-Encoding if compressed
+type compression struct {
+	newCompressorFunc func(parentLogger logger.Logger) shared.InitCompressorFunc // called once per chunk maker, nil to disable compression
+	contentEncoding   string
+}
+
+var compressionMap = map[string]compression{
+	"none": {newCompressorFunc: nil, contentEncoding: ""},
+	"gzip": {newCompressorFunc: func(logger.Logger) shared.InitCompressorFunc { return shared.InitGzipCompessor }, contentEncoding: "gzip"},
+	"zstd": {newCompressorFunc: shared.NewZstdCompressorFunc, contentEncoding: "zstd"},
+}
+
+func (cfg *Config) DecodeChunkToJSON(chunk base.LogChunk, separator []byte, indented bool, writer io.Writer) (base.LogChunkInfo, error) {
+	return dumpHTTPJSON(chunk, cfg.Framing, cfg.Compression, separator, indented, writer)
+}
+
+// MatchChunkID checks whether given ID is valid for a http chunk file
+func (cfg *Config) MatchChunkID(chunkID string) bool { //nolint:revive
+	return strings.HasSuffix(chunkID, cfg.chunkIDSuffix())
+}
+
+// NewSerializer creates LogSerializer
+func (cfg *Config) NewSerializer(parentLogger logger.Logger, schema base.LogSchema, tag string) base.LogSerializer {
+	return shared.NewJSONSerializer(parentLogger, schema, cfg.Serialization, nil)
+}
+
+// NewChunkMaker creates LogChunkMaker
+func (cfg *Config) NewChunkMaker(parentLogger logger.Logger, tag string) base.LogChunkMaker {
+	return NewChunkMaker(parentLogger, cfg.chunkIDSuffix(), cfg.Framing, cfg.Compression, chunkMaxRecords, chunkMaxSizeBytes)
+}
+
+// NewForwarder creates the forwarding client
+func (cfg *Config) NewForwarder(parentLogger logger.Logger, args base.ChunkConsumerArgs, metricCreator promreg.MetricCreator) base.ChunkConsumer {
+	return NewClientWorker(parentLogger, args, metricCreator, cfg.Upstream, cfg.Framing, cfg.Compression)
+}
+
+// VerifyConfig verifies the configuration
+func (cfg *Config) VerifyConfig(schema base.LogSchema) error {
+	if err := shared.VerifyJSONSerializationConfig(cfg.Serialization, schema, ".serialization"); err != nil {
+		return err
+	}
+
+	if len(cfg.Framing) == 0 {
+		return fmt.Errorf(".framing is unspecified")
+	}
+	if _, ok := framingMap[cfg.Framing]; !ok {
+		return fmt.Errorf(".framing '%s' is invalid, must be one of ndjson or array", cfg.Framing)
+	}
+
+	if len(cfg.Compression) == 0 {
+		return fmt.Errorf(".compression is unspecified")
+	}
+	if _, ok := compressionMap[cfg.Compression]; !ok {
+		return fmt.Errorf(".compression '%s' is invalid, must be one of none, gzip or zstd", cfg.Compression)
+	}
+
+	if len(cfg.Upstream.URL) == 0 {
+		return fmt.Errorf(".upstream.url is unspecified")
+	}
+	if u, err := url.Parse(cfg.Upstream.URL); err != nil {
+		return fmt.Errorf(".upstream.url is invalid: %w", err)
+	} else if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf(".upstream.url '%s' is invalid: scheme must be http or https", cfg.Upstream.URL)
+	}
+
+	for i, header := range cfg.Upstream.Headers {
+		if len(header.Name) == 0 {
+			return fmt.Errorf(".upstream.headers[%d].name is unspecified", i)
+		}
+		if _, err := header.resolveValue(); err != nil {
+			return fmt.Errorf(".upstream.headers[%d] '%s': %w", i, header.Name, err)
+		}
+	}
+
+	for i, code := range cfg.Upstream.SuccessCodes {
+		if code < 100 || code > 599 {
+			return fmt.Errorf(".upstream.successCodes[%d] %d is not a valid status code", i, code)
+		}
+	}
+
+	if cfg.Upstream.HTTPTimeout <= 0 {
+		return fmt.Errorf(".upstream.httpTimeout is unspecified")
+	}
+	return nil
+}
+
+// chunkIDSuffix returns the suffix of chunk IDs for the compression and framing in use
+func (cfg *Config) chunkIDSuffix() string {
+	return chunkIDSuffix + "-" + cfg.Compression + "-" + cfg.Framing
+}
+
+// lookupFraming returns the framing by name or panics if not found
+func lookupFraming(parentLogger logger.Logger, framingName string) framing {
+	f, ok := framingMap[framingName]
+	if !ok {
+		parentLogger.Panicf("unknown framing '%s'", framingName)
+	}
+	return f
+}
+
+// lookupCompression returns the compression by name or panics if not found
+func lookupCompression(parentLogger logger.Logger, compressionName string) compression {
+	c, ok := compressionMap[compressionName]
+	if !ok {
+		parentLogger.Panicf("unknown compression '%s'", compressionName)
+	}
+	return c
+}
+
+// resolveValue returns the value of header from its source
+func (header HeaderConfig) resolveValue() (string, error) {
+	numSources := 0
+	for _, source := range []string{header.Value, header.ValueFromEnv, header.ValueFromFile} {
+		if len(source) > 0 {
+			numSources++
+		}
+	}
+	if numSources != 1 {
+		return "", fmt.Errorf("exactly one of .value, .valueFromEnv or .valueFromFile must be specified")
+	}
+
+	switch {
+	case len(header.ValueFromEnv) > 0:
+		value, ok := os.LookupEnv(header.ValueFromEnv)
+		if !ok {
+			return "", fmt.Errorf(".valueFromEnv '%s' is not set", header.ValueFromEnv)
+		}
+		return value, nil
+	case len(header.ValueFromFile) > 0:
+		data, err := os.ReadFile(header.ValueFromFile)
+		if err != nil {
+			return "", fmt.Errorf(".valueFromFile: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	default:
+		return header.Value, nil
+	}
+}
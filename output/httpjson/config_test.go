@@ -0,0 +1,102 @@
+package httpjson
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/relex/gotils/logger"
+	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/rewrite"
+	"github.com/relex/slog-agent/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	rewrite.Register()
+}
+
+func TestConfigVerification(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"class", "log"})
+	baseYaml := `
+type: http
+serialization:
+  hiddenFields: [class]
+  rewriteFields:
+    log:
+      - type: escapeJSON
+        unescape: true
+framing: ndjson
+compression: gzip
+upstream:
+  url: https://localhost:8080/ingest
+  headers:
+    - name: Authorization
+      valueFromEnv: TEST_HTTP_CONFIG_TOKEN
+  successCodes: [200, 202]
+  httpTimeout: 30s
+`
+	t.Setenv("TEST_HTTP_CONFIG_TOKEN", "Bearer xyz")
+	cfg := &Config{}
+	assert.NoError(t, util.UnmarshalYamlString(baseYaml, cfg))
+	assert.NoError(t, cfg.VerifyConfig(schema))
+
+	for _, testCase := range []struct {
+		modify func(cfg *Config)
+		err    string
+	}{
+		{func(cfg *Config) { cfg.Framing = "" }, ".framing is unspecified"},
+		{func(cfg *Config) { cfg.Framing = "csv" }, ".framing 'csv' is invalid, must be one of ndjson or array"},
+		{func(cfg *Config) { cfg.Compression = "lz4" }, ".compression 'lz4' is invalid, must be one of none, gzip or zstd"},
+		{func(cfg *Config) { cfg.Upstream.URL = "localhost:8080" }, ".upstream.url 'localhost:8080' is invalid: scheme must be http or https"},
+		{func(cfg *Config) { cfg.Upstream.Headers[0].Value = "abc" }, ".upstream.headers[0] 'Authorization': exactly one of .value, .valueFromEnv or .valueFromFile must be specified"},
+		{func(cfg *Config) { cfg.Upstream.Headers[0].ValueFromEnv = "TEST_HTTP_CONFIG_UNDEFINED" }, ".upstream.headers[0] 'Authorization': .valueFromEnv 'TEST_HTTP_CONFIG_UNDEFINED' is not set"},
+		{func(cfg *Config) { cfg.Upstream.SuccessCodes = []int{2000} }, ".upstream.successCodes[0] 2000 is not a valid status code"},
+		{func(cfg *Config) { cfg.Upstream.HTTPTimeout = 0 }, ".upstream.httpTimeout is unspecified"},
+	} {
+		cfg := &Config{}
+		assert.NoError(t, util.UnmarshalYamlString(baseYaml, cfg))
+		testCase.modify(cfg)
+		assert.EqualError(t, cfg.VerifyConfig(schema), testCase.err)
+	}
+
+	cfg = &Config{}
+	assert.NoError(t, util.UnmarshalYamlString(baseYaml, cfg))
+	assert.NoError(t, util.UnmarshalYamlString(`
+log:
+  - type: unescape
+`, &cfg.Serialization.RewriteFields))
+	assert.ErrorContains(t, cfg.VerifyConfig(schema), ".serialization.rewriteFields[log]: the last rewriter must be 'escapeJSON'")
+}
+
+func TestSerializerTemplateEscaping(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"class", "task", "log"})
+	cfg := &Config{}
+	assert.NoError(t, util.UnmarshalYamlString(`
+type: http
+serialization:
+  hiddenFields: [class, task]
+  rewriteFields:
+    log:
+      - type: template
+        template: "[$class] ($task) "
+      - type: escapeJSON
+framing: ndjson
+compression: none
+upstream:
+  url: https://localhost:8080/ingest
+  httpTimeout: 30s
+`, cfg))
+	assert.NoError(t, cfg.VerifyConfig(schema))
+	serializer := cfg.NewSerializer(logger.Root(), schema, "")
+	record := schema.NewTestRecord2(time.UnixMilli(1606818640000), base.LogFields{`a"b`, "x\\y\t", `say "hi"`})
+
+	stream := serializer.SerializeRecord(record)
+	assert.True(t, json.Valid(stream), string(stream))
+	var streamData map[string]string
+	assert.NoError(t, json.Unmarshal(stream, &streamData))
+	assert.Equal(t, map[string]string{
+		"log":       "[a\"b] (x\\y\t) say \"hi\"",
+		"timestamp": "1606818640000",
+	}, streamData)
+}
@@ -0,0 +1,114 @@
+package httpjson
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip" // DO NOT use klauspost's gzip for verification
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/relex/slog-agent/base"
+)
+
+func dumpHTTPJSON(chunk base.LogChunk, framingName string, compressionName string, separator []byte, indented bool, writer io.Writer) (base.LogChunkInfo, error) {
+	body, derr := decompressChunk(chunk.Data, compressionName)
+	if derr != nil {
+		return base.LogChunkInfo{}, derr
+	}
+
+	records, rerr := splitRecords(body, framingName)
+	if rerr != nil {
+		return base.LogChunkInfo{}, rerr
+	}
+
+	info := base.LogChunkInfo{
+		Tag:        "",
+		NumRecords: len(records),
+	}
+
+	var outJSON bytes.Buffer
+	for i, record := range records {
+		if i > 0 {
+			if _, err := writer.Write(separator); err != nil {
+				return info, fmt.Errorf("failed to write separator: %w", err)
+			}
+		}
+
+		outJSON.Reset()
+		var outErr error
+		if indented {
+			outErr = json.Indent(&outJSON, record, "", "  ")
+		} else {
+			outErr = json.Compact(&outJSON, record)
+		}
+		if outErr != nil {
+			return base.LogChunkInfo{}, fmt.Errorf("failed to format record %d: %w", i, outErr)
+		}
+
+		if _, werr := writer.Write(outJSON.Bytes()); werr != nil {
+			return base.LogChunkInfo{}, fmt.Errorf("failed to write decoded JSON: %w", werr)
+		}
+	}
+	return info, nil
+}
+
+func decompressChunk(data []byte, compressionName string) ([]byte, error) {
+	switch compressionName {
+	case "none":
+		return data, nil
+	case "gzip":
+		gunzipStream, initErr := gzip.NewReader(bytes.NewReader(data)) // use builtin gzip library for verification
+		if initErr != nil {
+			return nil, fmt.Errorf("failed to create gzip Reader: %w", initErr)
+		}
+		body, gzErr := io.ReadAll(gunzipStream)
+		if gzErr != nil {
+			return nil, fmt.Errorf("failed to gunzip chunk: %w", gzErr)
+		}
+		return body, nil
+	case "zstd":
+		decoder, initErr := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1))
+		if initErr != nil {
+			return nil, fmt.Errorf("failed to create zstd Reader: %w", initErr)
+		}
+		defer decoder.Close()
+		body, zErr := decoder.DecodeAll(data, nil)
+		if zErr != nil {
+			return nil, fmt.Errorf("failed to decompress zstd chunk: %w", zErr)
+		}
+		return body, nil
+	default:
+		return nil, fmt.Errorf("unknown compression '%s'", compressionName)
+	}
+}
+
+func splitRecords(body []byte, framingName string) ([]json.RawMessage, error) {
+	var records []json.RawMessage
+	switch framingName {
+	case "ndjson":
+		scanner := bufio.NewScanner(bytes.NewReader(body))
+		scanner.Buffer(nil, len(body)+1)
+		for scanner.Scan() {
+			line := scanner.Bytes()
+			if len(bytes.TrimSpace(line)) == 0 {
+				continue
+			}
+			if !json.Valid(line) {
+				return nil, fmt.Errorf("invalid JSON line in chunk: %s", line)
+			}
+			records = append(records, json.RawMessage(line))
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read lines from chunk: %w", err)
+		}
+	case "array":
+		if err := json.Unmarshal(body, &records); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal JSON array chunk: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown framing '%s'", framingName)
+	}
+	return records, nil
+}
@@ -5,12 +5,14 @@ import (
 	"github.com/relex/slog-agent/base/bconfig"
 	"github.com/relex/slog-agent/output/datadog"
 	"github.com/relex/slog-agent/output/fluentdforward"
+	"github.com/relex/slog-agent/output/httpjson"
 )
 
 func init() {
 	bconfig.RegisterConfigConstructors(bconfig.LogOutputConfigCreatorTable{
 		"datadog":        func() bconfig.LogOutputConfig { return &datadog.Config{} },
 		"fluentdForward": func() bconfig.LogOutputConfig { return &fluentdforward.Config{} },
+		"http":           func() bconfig.LogOutputConfig { return &httpjson.Config{} },
 	})
 }
 
@@ -0,0 +1,302 @@
+package shared
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/relex/gotils/logger"
+	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/base/bconfig"
+	"github.com/relex/slog-agent/base/bsupport"
+	"github.com/relex/slog-agent/defs"
+	"github.com/relex/slog-agent/rewrite/rcopy"
+	"github.com/relex/slog-agent/rewrite/rescapejson"
+	"github.com/relex/slog-agent/rewrite/runescape"
+	"github.com/relex/slog-agent/util"
+	"github.com/relex/slog-agent/util/jsonstring"
+	"golang.org/x/exp/slices"
+)
+
+// JSONSerializationConfig defines the serialization section of outputs in JSON
+type JSONSerializationConfig struct {
+	HiddenFields  []string                                     `yaml:"hiddenFields"`
+	RewriteFields map[string][]bconfig.LogRewriterConfigHolder `yaml:"rewriteFields"` // must end with 'escapeJSON'
+}
+
+type jsonSerializer struct {
+	logger          logger.Logger
+	fieldRewriters  []base.LogRewriter // head writers for each of fields or nil, same length as LogRecords.Fields
+	escapeRewritten []bool             // whether outputs of fieldRewriters need to be escaped, same length as above
+	entries         []serializedEntry  // entries of visible fields, timestamp and default fields, sorted by name
+	extraOrder      []int              // reused buffer of indexes of extra fields, sorted by key
+	buffer          []byte             // reused buffer for output, grown if needed
+	scratch         []byte             // reused buffer for outputs of fieldRewriters to be escaped, grown if needed
+}
+
+// serializedEntry is a top-level entry of output objects, known before serialization
+type serializedEntry struct {
+	name  string
+	key   jsonBlock // pre-serialized key with colon
+	field int       // index of field in LogRecord.Fields, or entryTimestamp or entryDefault
+	value jsonBlock // pre-serialized value of entryDefault
+}
+
+type jsonBlock []byte
+
+const (
+	entryTimestamp = -1 // "timestamp" formatted from LogRecord.Timestamp, overriding any field of the same name
+	entryDefault   = -2 // default field, overridden by any field or extra field of the same name
+)
+
+// NewJSONSerializer creates a LogSerializer to serialize log records into JSON objects, with all non-empty fields
+// and extra fields on top level, plus "timestamp" in milliseconds and non-empty defaultFields if not set in records
+//
+// Keys are sorted in output, the same as the results from encoding/json.
+func NewJSONSerializer(parentLogger logger.Logger, schema base.LogSchema, config JSONSerializationConfig,
+	defaultFields map[string]string,
+) base.LogSerializer {
+	fieldNames := schema.GetFieldNames()
+
+	fieldRewriters := make([]base.LogRewriter, len(fieldNames))
+	escapeRewritten := make([]bool, len(fieldNames))
+	for i, name := range fieldNames {
+		rewriterConfigs, ok := config.RewriteFields[name]
+		if !ok {
+			continue
+		}
+		fieldRewriters[i], escapeRewritten[i] = newFieldRewriter(rewriterConfigs, schema)
+	}
+
+	entries := make([]serializedEntry, 0, len(fieldNames)+1+len(defaultFields))
+	for i, name := range fieldNames {
+		if len(name) == 0 || name == "timestamp" || slices.Contains(config.HiddenFields, name) {
+			continue
+		}
+		entries = append(entries, serializedEntry{name: name, key: serializeKey(name), field: i, value: nil})
+	}
+	entries = append(entries, serializedEntry{name: "timestamp", key: serializeKey("timestamp"), field: entryTimestamp, value: nil})
+	for name, value := range defaultFields {
+		if len(value) == 0 || name == "timestamp" {
+			continue
+		}
+		entries = append(entries, serializedEntry{name: name, key: serializeKey(name), field: entryDefault, value: serializeValue(value)})
+	}
+	// stable to keep fields before defaults of the same names
+	slices.SortStableFunc(entries, func(a, b serializedEntry) int { return strings.Compare(a.name, b.name) })
+
+	return &jsonSerializer{
+		logger:          parentLogger.WithField(defs.LabelComponent, "JSONSerializer"),
+		fieldRewriters:  fieldRewriters,
+		escapeRewritten: escapeRewritten,
+		entries:         entries,
+		extraOrder:      make([]int, 0, 16),
+		buffer:          make([]byte, 2*defs.InputLogMaxRecordBytes),
+		scratch:         nil,
+	}
+}
+
+// newFieldRewriter creates the rewriter chain of a field and returns whether its output needs to be escaped
+//
+// A chain of only 'escapeJSON' writes escaped values directly into output. In longer chains, text inserted by other
+// rewriters such as 'inline' needs to be escaped as well, so 'escapeJSON' is replaced by 'copy' or 'unescape' and the
+// whole output of the chain is escaped afterwards.
+func newFieldRewriter(rewriterConfigs []bconfig.LogRewriterConfigHolder, schema base.LogSchema) (base.LogRewriter, bool) {
+	lastI := len(rewriterConfigs) - 1
+	escapeConfig, ok := rewriterConfigs[lastI].Value.(*rescapejson.Config)
+	if !ok || lastI == 0 {
+		return bsupport.NewRewritersFromConfig(rewriterConfigs, schema), false
+	}
+	var tail bconfig.LogRewriterConfig
+	if escapeConfig.Unescape {
+		tail = &runescape.Config{Header: bconfig.Header{Type: "unescape"}}
+	} else {
+		tail = &rcopy.Config{Header: bconfig.Header{Type: "copy"}}
+	}
+	plainConfigs := make([]bconfig.LogRewriterConfigHolder, 0, len(rewriterConfigs))
+	plainConfigs = append(plainConfigs, rewriterConfigs[:lastI]...)
+	plainConfigs = append(plainConfigs, bconfig.LogRewriterConfigHolder{Location: rewriterConfigs[lastI].Location, Value: tail})
+	return bsupport.NewRewritersFromConfig(plainConfigs, schema), true
+}
+
+// VerifyJSONSerializationConfig verifies JSONSerializationConfig, which is by default under ".serialization"
+func VerifyJSONSerializationConfig(config JSONSerializationConfig, schema base.LogSchema, header string) error {
+	for field, rewriteConfig := range config.RewriteFields {
+		if _, err := schema.CreateFieldLocator(field); err != nil {
+			return fmt.Errorf("%s.rewriteFields[%s]: Field is invalid: %w", header, field, err)
+		}
+		fieldHeader := fmt.Sprintf("%s.rewriteFields[%s]", header, field)
+		if err := bsupport.VerifyRewriterConfigs(rewriteConfig, schema, fieldHeader); err != nil {
+			return err
+		}
+		if len(rewriteConfig) == 0 {
+			continue
+		}
+		if _, ok := rewriteConfig[len(rewriteConfig)-1].Value.(*rescapejson.Config); !ok {
+			return fmt.Errorf("%s: the last rewriter must be 'escapeJSON'", fieldHeader)
+		}
+	}
+	return nil
+}
+
+// SerializeRecord serializes log records into JSON objects
+func (packer *jsonSerializer) SerializeRecord(record *base.LogRecord) base.LogStream {
+	length := packer.encodeRecord(record)
+	return packer.buffer[:length]
+}
+
+// encodeRecord encodes the given log record to packer.buffer and returns the end position
+//
+// Fixed entries and extra fields are merged by names in order, and only the first non-empty one of the same name is
+// written, by the order of: timestamp, fields, extra fields and then defaults.
+func (packer *jsonSerializer) encodeRecord(record *base.LogRecord) int {
+	fields := record.Fields
+	extra := record.Extra
+	entries := packer.entries
+	extraOrder := packer.sortExtraFields(extra)
+	buffer := packer.buffer
+
+	buffer[0] = '{'
+	position := 1
+	lastName := ""
+	numWritten := 0
+
+	ei := 0
+	xi := 0
+	for ei < len(entries) || xi < len(extraOrder) {
+		// pick either the next entry or the next extra field; entries go first except defaults
+		if xi == len(extraOrder) || ei < len(entries) && (entries[ei].name < extra[extraOrder[xi]].Key ||
+			entries[ei].name == extra[extraOrder[xi]].Key && entries[ei].field != entryDefault) {
+			entry := &entries[ei]
+			ei++
+			if numWritten > 0 && entry.name == lastName {
+				continue
+			}
+			switch entry.field {
+			case entryTimestamp:
+				buffer = grow(buffer, position, 1+len(entry.key)+22+1) // 20 digits at most
+				position = writeComma(buffer, position, numWritten)
+				position += copy(buffer[position:], entry.key)
+				buffer[position] = '"'
+				position = len(strconv.AppendInt(buffer[:position+1], record.Timestamp.UnixMilli(), 10))
+				buffer[position] = '"'
+				position++
+			case entryDefault:
+				buffer = grow(buffer, position, 1+len(entry.key)+len(entry.value)+1)
+				position = writeComma(buffer, position, numWritten)
+				position += copy(buffer[position:], entry.key)
+				position += copy(buffer[position:], entry.value)
+			default:
+				value := fields[entry.field]
+				if len(value) == 0 {
+					continue
+				}
+				if headRewriter := packer.fieldRewriters[entry.field]; headRewriter != nil && packer.escapeRewritten[entry.field] {
+					// the chain writes plain text to be escaped between quotes
+					scratch := grow(packer.scratch, 0, headRewriter.MaxFieldLength(value, record))
+					rewritten := util.StringFromBytes(scratch[:headRewriter.WriteFieldBody(value, record, scratch)])
+					buffer = grow(buffer, position, 1+len(entry.key)+jsonstring.EscapedLength(rewritten)+2+1)
+					position = writeComma(buffer, position, numWritten)
+					position += copy(buffer[position:], entry.key)
+					position = writeString(buffer, position, rewritten)
+					packer.scratch = scratch
+				} else if headRewriter != nil {
+					// the chain ends with 'escapeJSON' and writes the escaped string body between quotes
+					buffer = grow(buffer, position, 1+len(entry.key)+headRewriter.MaxFieldLength(value, record)+2+1)
+					position = writeComma(buffer, position, numWritten)
+					position += copy(buffer[position:], entry.key)
+					buffer[position] = '"'
+					position++
+					position += headRewriter.WriteFieldBody(value, record, buffer[position:])
+					buffer[position] = '"'
+					position++
+				} else {
+					buffer = grow(buffer, position, 1+len(entry.key)+jsonstring.MaxEscapedLength(len(value))+2+1)
+					position = writeComma(buffer, position, numWritten)
+					position += copy(buffer[position:], entry.key)
+					position = writeString(buffer, position, value)
+				}
+			}
+			lastName = entry.name
+		} else {
+			field := extra[extraOrder[xi]]
+			xi++
+			if numWritten > 0 && field.Key == lastName {
+				continue
+			}
+			buffer = grow(buffer, position, 1+jsonstring.MaxEscapedLength(len(field.Key)+len(field.Value))+5+1)
+			position = writeComma(buffer, position, numWritten)
+			position = writeString(buffer, position, field.Key)
+			buffer[position] = ':'
+			position++
+			position = writeString(buffer, position, field.Value)
+			lastName = field.Key
+		}
+		numWritten++
+	}
+
+	buffer[position] = '}'
+	position++
+
+	packer.buffer = buffer
+	return position
+}
+
+// sortExtraFields returns the indexes of non-empty extra fields sorted by key, in the reused packer.extraOrder
+func (packer *jsonSerializer) sortExtraFields(extra base.LogExtraFields) []int {
+	order := packer.extraOrder[:0]
+	for i, field := range extra {
+		if len(field.Value) == 0 {
+			continue
+		}
+		// insertion sort, there are normally only a few extra fields
+		order = append(order, i)
+		for j := len(order) - 1; j > 0 && extra[order[j-1]].Key > field.Key; j-- {
+			order[j-1], order[j] = order[j], order[j-1]
+		}
+	}
+	packer.extraOrder = order
+	return order
+}
+
+// grow returns a buffer with at least n bytes of space after position, with contents before position kept
+func grow(buffer []byte, position int, n int) []byte {
+	if position+n <= len(buffer) {
+		return buffer
+	}
+	newBuffer := make([]byte, 2*(position+n))
+	copy(newBuffer, buffer[:position])
+	return newBuffer
+}
+
+func writeComma(buffer []byte, position int, numWritten int) int {
+	if numWritten == 0 {
+		return position
+	}
+	buffer[position] = ','
+	return position + 1
+}
+
+// writeString writes quoted and escaped value at position of buffer and returns the end position
+func writeString(buffer []byte, position int, value string) int {
+	buffer[position] = '"'
+	position++
+	position += jsonstring.WriteEscaped(buffer[position:], value)
+	buffer[position] = '"'
+	return position + 1
+}
+
+// serializeKey serializes name as JSON object key followed by colon
+func serializeKey(name string) jsonBlock {
+	buf := make(jsonBlock, jsonstring.EscapedLength(name)+3)
+	end := writeString(buf, 0, name)
+	buf[end] = ':'
+	return buf
+}
+
+// serializeValue serializes value as JSON string
+func serializeValue(value string) jsonBlock {
+	buf := make(jsonBlock, jsonstring.EscapedLength(value)+2)
+	writeString(buf, 0, value)
+	return buf
+}
@@ -0,0 +1,23 @@
+package shared
+
+import (
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/relex/gotils/logger"
+)
+
+// NewZstdCompressorFunc creates a zstd encoder and returns InitCompressorFunc which resets it to write to the new
+// writer on each call, so that consecutive chunks of a chunk maker share one encoder
+//
+// It panics if the encoder cannot be created, rather than making uncompressed chunks declared as zstd.
+func NewZstdCompressorFunc(parentLogger logger.Logger) InitCompressorFunc {
+	encoder, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedFastest), zstd.WithEncoderConcurrency(1))
+	if err != nil {
+		parentLogger.Panicf("failed to initialize zstd compressor: %s", err.Error())
+	}
+	return func(_ logger.Logger, w io.Writer) io.WriteCloser {
+		encoder.Reset(w)
+		return encoder
+	}
+}
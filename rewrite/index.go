@@ -4,15 +4,23 @@ package rewrite
 import (
 	"github.com/relex/slog-agent/base/bconfig"
 	"github.com/relex/slog-agent/rewrite/rcopy"
+	"github.com/relex/slog-agent/rewrite/rescapejson"
 	"github.com/relex/slog-agent/rewrite/rinline"
+	"github.com/relex/slog-agent/rewrite/rredactemail"
+	"github.com/relex/slog-agent/rewrite/rtemplate"
+	"github.com/relex/slog-agent/rewrite/rtruncate"
 	"github.com/relex/slog-agent/rewrite/runescape"
 )
 
 func init() {
 	bconfig.RegisterConfigConstructors(bconfig.LogRewriterConfigCreatorTable{
-		"copy":     func() bconfig.LogRewriterConfig { return &rcopy.Config{} },
-		"inline":   func() bconfig.LogRewriterConfig { return &rinline.Config{} },
-		"unescape": func() bconfig.LogRewriterConfig { return &runescape.Config{} },
+		"copy":        func() bconfig.LogRewriterConfig { return &rcopy.Config{} },
+		"escapeJSON":  func() bconfig.LogRewriterConfig { return &rescapejson.Config{} },
+		"inline":      func() bconfig.LogRewriterConfig { return &rinline.Config{} },
+		"redactEmail": func() bconfig.LogRewriterConfig { return &rredactemail.Config{} },
+		"template":    func() bconfig.LogRewriterConfig { return &rtemplate.Config{} },
+		"truncate":    func() bconfig.LogRewriterConfig { return &rtruncate.Config{} },
+		"unescape":    func() bconfig.LogRewriterConfig { return &runescape.Config{} },
 	})
 }
 
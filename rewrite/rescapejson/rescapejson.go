@@ -0,0 +1,92 @@
+// Package rescapejson provides 'escapeJSON' rewriter, which escapes field values to be embedded in JSON strings,
+// writing escaped bytes directly into output buffer of serialization.
+//
+// With 'unescape' enabled, syslog escape sequences are unescaped in the same pass as 'unescape' rewriter would do.
+// Sequences like "\n" are kept as they are valid JSON escapes with the same meaning, and other backslashes are escaped.
+//
+// The 'escapeJSON' rewriter serves as the last rewriter in the chain, in place of 'copy' or 'unescape'. It's required
+// by outputs serializing JSON, e.g. 'datadog'.
+//
+// When used alone, field values are escaped directly into the output buffer. After other rewriters such as 'inline',
+// JSON serializers replace it by 'copy' or 'unescape' and escape the whole output of the chain instead, so that inserted
+// text is escaped as well.
+package rescapejson
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/relex/gotils/logger"
+	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/base/bconfig"
+	"github.com/relex/slog-agent/util/jsonstring"
+)
+
+// Config for escapeJSONRewriter
+type Config struct {
+	bconfig.Header `yaml:",inline"`
+	Unescape       bool `yaml:"unescape"` // unescape syslog escape sequences before escaping, like 'unescape' rewriter
+}
+
+type escapeJSONRewriter struct {
+	unescape bool
+}
+
+// NewRewriter creates escapeJSONRewriter
+func (c *Config) NewRewriter(schema base.LogSchema, next base.LogRewriter) base.LogRewriter {
+	if next != nil {
+		logger.Panic("'escapeJSON' must be the last rewriter")
+	}
+	return &escapeJSONRewriter{
+		unescape: c.Unescape,
+	}
+}
+
+// VerifyConfig verifies escapeJSONRewriter config
+func (c *Config) VerifyConfig(schema base.LogSchema, hasNext bool) error {
+	if hasNext {
+		return fmt.Errorf("'escapeJSON' must be the last rewriter")
+	}
+	return nil
+}
+
+// MaxFieldLength returns the escaped length, which is never exceeded after unescaping since each syslog escape
+// sequence is either kept or replaced by a shorter JSON escape
+func (rw *escapeJSONRewriter) MaxFieldLength(value string, record *base.LogRecord) int {
+	return jsonstring.EscapedLength(value)
+}
+
+func (rw *escapeJSONRewriter) WriteFieldBody(value string, record *base.LogRecord, buffer []byte) int {
+	if !rw.unescape || record.Unescaped {
+		return jsonstring.WriteEscaped(buffer, value)
+	}
+	record.Unescaped = true
+	return writeUnescapedEscaped(buffer, value)
+}
+
+// writeUnescapedEscaped writes syslog-unescaped and then JSON-escaped value to dst in one pass
+func writeUnescapedEscaped(dst []byte, value string) int {
+	di := 0
+	for {
+		next := strings.IndexByte(value, '\\')
+		if next == -1 {
+			return di + jsonstring.WriteEscaped(dst[di:], value)
+		}
+		di += jsonstring.WriteEscaped(dst[di:], value[:next])
+		if next+1 < len(value) {
+			switch value[next+1] {
+			case 'b', 'f', 'n', 'r', 't', '\\': // same in JSON after unescaping
+				dst[di] = '\\'
+				dst[di+1] = value[next+1]
+				di += 2
+				value = value[next+2:]
+				continue
+			}
+		}
+		// unknown sequence or trailing backslash, kept by unescaping
+		dst[di] = '\\'
+		dst[di+1] = '\\'
+		di += 2
+		value = value[next+1:]
+	}
+}
@@ -0,0 +1,57 @@
+package rescapejson
+
+import (
+	"testing"
+
+	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEscapeJSONRewrite(t *testing.T) {
+	{
+		c := &Config{}
+		assert.NoError(t, util.UnmarshalYamlString(`
+type: escapeJSON
+`, c))
+		assert.NoError(t, c.VerifyConfig(base.LogSchema{}, false))
+		rw := c.NewRewriter(base.LogSchema{}, nil)
+		rec := &base.LogRecord{}
+		msg := "say \"hi\"\t<b>\\n"
+		result := `say \"hi\"\t\u003cb\u003e\\n`
+		buf := make([]byte, 100)
+		assert.Equal(t, len(result), rw.MaxFieldLength(msg, rec))
+		assert.Equal(t, len(result), rw.WriteFieldBody(msg, rec, buf))
+		assert.Equal(t, result, string(buf[:len(result)]))
+		assert.False(t, rec.Unescaped)
+	}
+	{
+		c := &Config{}
+		assert.NoError(t, util.UnmarshalYamlString(`
+type: escapeJSON
+unescape: true
+`, c))
+		rw := c.NewRewriter(base.LogSchema{}, nil)
+		rec := &base.LogRecord{}
+		msg := `dum\ndum\\ "\x" \`
+		result := `dum\ndum\\ \"\\x\" \\`
+		buf := make([]byte, 100)
+		assert.GreaterOrEqual(t, rw.MaxFieldLength(msg, rec), len(result))
+		assert.Equal(t, len(result), rw.WriteFieldBody(msg, rec, buf))
+		assert.Equal(t, result, string(buf[:len(result)]))
+		assert.True(t, rec.Unescaped)
+
+		// already unescaped, e.g. multi-line logs
+		result = `dum\\ndum\\\\ \"\\x\" \\`
+		assert.Equal(t, len(result), rw.WriteFieldBody(msg, rec, buf))
+		assert.Equal(t, result, string(buf[:len(result)]))
+	}
+}
+
+func TestEscapeJSONConfig(t *testing.T) {
+	c := &Config{}
+	assert.NoError(t, util.UnmarshalYamlString(`
+type: escapeJSON
+`, c))
+	assert.EqualError(t, c.VerifyConfig(base.LogSchema{}, true), "'escapeJSON' must be the last rewriter")
+}
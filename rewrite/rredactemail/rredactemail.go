@@ -0,0 +1,82 @@
+// Package rredactemail provides 'redactEmail' rewriter, which replaces email addresses in the value passed to the next
+// rewriter.
+//
+// The rewriter equals to 'redactEmail' transform, except it's done in serialization stage with no change to the record
+// and no metric. Values with email addresses are redacted into a reused buffer before being passed to the next rewriter.
+// The redaction done for MaxFieldLength is reused by the following WriteFieldBody of the same value.
+package rredactemail
+
+import (
+	"fmt"
+
+	"github.com/relex/gotils/logger"
+	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/base/bconfig"
+	"github.com/relex/slog-agent/util"
+	"github.com/relex/slog-agent/util/emailredact"
+)
+
+// Config for redactEmailRewriter
+type Config struct {
+	bconfig.Header `yaml:",inline"`
+}
+
+type redactEmailRewriter struct {
+	next     base.LogRewriter
+	buffer   []byte // reused buffer for redacted values
+	input    string // value passed to the last MaxFieldLength, cleared by WriteFieldBody
+	redacted string // redacted result of input, in buffer or the same as input
+}
+
+// NewRewriter creates redactEmailRewriter
+func (c *Config) NewRewriter(schema base.LogSchema, next base.LogRewriter) base.LogRewriter {
+	if next == nil {
+		logger.Panic("'redactEmail' cannot be the last rewriter")
+	}
+	return &redactEmailRewriter{
+		next:     next,
+		buffer:   nil,
+		input:    "",
+		redacted: "",
+	}
+}
+
+// VerifyConfig verifies redactEmailRewriter config
+func (c *Config) VerifyConfig(schema base.LogSchema, hasNext bool) error {
+	if !hasNext {
+		return fmt.Errorf("'redactEmail' cannot be the last rewriter")
+	}
+	return nil
+}
+
+func (rw *redactEmailRewriter) MaxFieldLength(value string, record *base.LogRecord) int {
+	rw.input = value
+	rw.redacted = rw.redact(value)
+	return rw.next.MaxFieldLength(rw.redacted, record)
+}
+
+func (rw *redactEmailRewriter) WriteFieldBody(value string, record *base.LogRecord, buffer []byte) int {
+	var redacted string
+	if value == rw.input {
+		redacted = rw.redacted
+	} else {
+		redacted = rw.redact(value)
+	}
+	rw.input = "" // don't keep references to the record
+	rw.redacted = ""
+	return rw.next.WriteFieldBody(redacted, record, buffer)
+}
+
+// redact returns the redacted value in the reused buffer, valid until the next call, or the original if no change
+func (rw *redactEmailRewriter) redact(value string) string {
+	first := emailredact.FindFirst(value)
+	if first == -1 {
+		return value
+	}
+	redacted, numRedacted := emailredact.AppendRedacted(rw.buffer[:0], value, first)
+	rw.buffer = redacted
+	if numRedacted == 0 {
+		return value
+	}
+	return util.StringFromBytes(redacted)
+}
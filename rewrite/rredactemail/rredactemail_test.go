@@ -0,0 +1,47 @@
+package rredactemail
+
+import (
+	"testing"
+
+	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/rewrite/rcopy"
+	"github.com/relex/slog-agent/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactEmailRewrite(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"msg"})
+	c := &Config{}
+	assert.NoError(t, util.UnmarshalYamlString(`
+type: redactEmail
+`, c))
+	assert.NoError(t, c.VerifyConfig(schema, true))
+	rw := c.NewRewriter(schema, rcopy.NewRewriter())
+
+	testCases := []struct {
+		Input    string
+		Expected string
+	}{
+		{"", ""},
+		{"reply_to: foo@bar.com, j@x.co something@else.org,", "reply_to: REDACTED, REDACTED REDACTED,"},
+		{"number: hello@123.456", "number: hello@123.456"},
+		{"no email", "no email"},
+	}
+	for i, test := range testCases {
+		record := schema.NewTestRecord1(base.LogFields{test.Input})
+		buf := make([]byte, 100)
+		maxLength := rw.MaxFieldLength(test.Input, record)
+		length := rw.WriteFieldBody(test.Input, record, buf)
+		assert.Equalf(t, test.Expected, string(buf[:length]), "resulted[%d] %s", i, test.Input)
+		assert.Equalf(t, length, maxLength, "length[%d] %s", i, test.Input)
+	}
+	{
+		record := schema.NewTestRecord1(base.LogFields{""})
+		buf := make([]byte, 100)
+		rw.MaxFieldLength("first: a@b.com", record)
+		length := rw.WriteFieldBody("second: c@d.com", record, buf)
+		assert.Equal(t, "second: REDACTED", string(buf[:length]))
+		length = rw.WriteFieldBody("third: e@f.com", record, buf)
+		assert.Equal(t, "third: REDACTED", string(buf[:length]))
+	}
+}
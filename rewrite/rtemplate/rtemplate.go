@@ -0,0 +1,64 @@
+// Package rtemplate provides 'template' rewriter, which inserts an expanded template at the beginning of the current field
+// value, e.g. "[$class] ($task) " or "${host[:8]}: "
+//
+// It's a generic version of 'inline', written directly to the serialization buffer without allocations as an
+// alternative to 'addFields' transform. In JSON outputs, the expanded text is escaped together with the value.
+package rtemplate
+
+import (
+	"fmt"
+
+	"github.com/relex/gotils/logger"
+	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/base/bconfig"
+	"github.com/relex/slog-agent/util/stringtemplate"
+)
+
+// Config for templateRewriter
+type Config struct {
+	bconfig.Header `yaml:",inline"`
+	Template       string `yaml:"template"`
+}
+
+type templateRewriter struct {
+	expander stringtemplate.Expander
+	next     base.LogRewriter
+}
+
+// NewRewriter creates templateRewriter
+func (c *Config) NewRewriter(schema base.LogSchema, next base.LogRewriter) base.LogRewriter {
+	if next == nil {
+		logger.Panic("'template' cannot be the last rewriter")
+	}
+	expander, err := stringtemplate.NewExpander(c.Template, schema.CreateTemplateVariableResolver)
+	if err != nil {
+		logger.Panicf("failed to create template '%s': %s", c.Template, err.Error())
+	}
+	return &templateRewriter{
+		expander: expander,
+		next:     next,
+	}
+}
+
+// VerifyConfig verifies templateRewriter config
+func (c *Config) VerifyConfig(schema base.LogSchema, hasNext bool) error {
+	if !hasNext {
+		return fmt.Errorf("'template' cannot be the last rewriter")
+	}
+	if len(c.Template) == 0 {
+		return fmt.Errorf(".template is unspecified")
+	}
+	if _, err := stringtemplate.NewExpander(c.Template, schema.CreateTemplateVariableResolver); err != nil {
+		return fmt.Errorf(".template '%s' is invalid: %w", c.Template, err)
+	}
+	return nil
+}
+
+func (rw *templateRewriter) MaxFieldLength(value string, record *base.LogRecord) int {
+	return rw.expander.Length(record.Fields) + rw.next.MaxFieldLength(value, record)
+}
+
+func (rw *templateRewriter) WriteFieldBody(value string, record *base.LogRecord, buffer []byte) int {
+	end := rw.expander.RunToBuffer(record.Fields, buffer)
+	return end + rw.next.WriteFieldBody(value, record, buffer[end:])
+}
@@ -0,0 +1,40 @@
+package rtemplate
+
+import (
+	"testing"
+
+	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/rewrite/rcopy"
+	"github.com/relex/slog-agent/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplateRewrite(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"class", "task", "msg"})
+	c := &Config{}
+	assert.NoError(t, util.UnmarshalYamlString(`
+type: template
+template: "[$class] (${task[:4]}) "
+`, c))
+	assert.NoError(t, c.VerifyConfig(schema, true))
+	rw := c.NewRewriter(schema, rcopy.NewRewriter())
+
+	msg := "message"
+	result := "[Main] (sche) message"
+	record := schema.NewTestRecord1(base.LogFields{"Main", "scheduler", msg})
+	buf := make([]byte, 100)
+	assert.Equal(t, len(result), rw.MaxFieldLength(msg, record))
+	assert.Equal(t, len(result), rw.WriteFieldBody(msg, record, buf))
+	assert.Equal(t, result, string(buf[:len(result)]))
+}
+
+func TestTemplateRewriteConfig(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"msg"})
+	c := &Config{}
+	assert.NoError(t, util.UnmarshalYamlString(`
+type: template
+template: "[$class] "
+`, c))
+	assert.EqualError(t, c.VerifyConfig(schema, true), ".template '[$class] ' is invalid: error creating resolver for $class: field 'class' is not defined in schema")
+	assert.EqualError(t, c.VerifyConfig(schema, false), "'template' cannot be the last rewriter")
+}
@@ -0,0 +1,71 @@
+// Package rtruncate provides 'truncate' rewriter, which truncates the value passed to the next rewriter if exceeding
+// certain limit and appends a suffix after it.
+//
+// The rewriter equals to 'truncate' transform, except it's done in serialization stage with no change to the record.
+// Placed before 'unescape', the limit applies to the escaped value.
+package rtruncate
+
+import (
+	"fmt"
+
+	"github.com/relex/gotils/logger"
+	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/base/bconfig"
+	"github.com/relex/slog-agent/util"
+)
+
+// Config for truncateRewriter
+type Config struct {
+	bconfig.Header `yaml:",inline"`
+	MaxLength      int    `yaml:"maxLen"`
+	Suffix         string `yaml:"suffix"`
+}
+
+type truncateRewriter struct {
+	maxLength int
+	suffix    string
+	next      base.LogRewriter
+}
+
+// NewRewriter creates truncateRewriter
+func (c *Config) NewRewriter(schema base.LogSchema, next base.LogRewriter) base.LogRewriter {
+	if next == nil {
+		logger.Panic("'truncate' cannot be the last rewriter")
+	}
+	return &truncateRewriter{
+		maxLength: c.MaxLength,
+		suffix:    c.Suffix,
+		next:      next,
+	}
+}
+
+// VerifyConfig verifies truncateRewriter config
+func (c *Config) VerifyConfig(schema base.LogSchema, hasNext bool) error {
+	if !hasNext {
+		return fmt.Errorf("'truncate' cannot be the last rewriter")
+	}
+	if c.MaxLength <= 0 {
+		return fmt.Errorf(".maxLen must be larger than zero: %d", c.MaxLength)
+	}
+	if len(c.Suffix) == 0 {
+		return fmt.Errorf(".suffix is unspecified")
+	}
+	return nil
+}
+
+func (rw *truncateRewriter) MaxFieldLength(value string, record *base.LogRecord) int {
+	end := util.FindTruncationEnd(value, rw.maxLength, len(rw.suffix))
+	if end == -1 {
+		return rw.next.MaxFieldLength(value, record)
+	}
+	return rw.next.MaxFieldLength(value[:end], record) + len(rw.suffix)
+}
+
+func (rw *truncateRewriter) WriteFieldBody(value string, record *base.LogRecord, buffer []byte) int {
+	end := util.FindTruncationEnd(value, rw.maxLength, len(rw.suffix))
+	if end == -1 {
+		return rw.next.WriteFieldBody(value, record, buffer)
+	}
+	bodyEnd := rw.next.WriteFieldBody(value[:end], record, buffer)
+	return bodyEnd + copy(buffer[bodyEnd:], rw.suffix)
+}
@@ -0,0 +1,45 @@
+package rtruncate
+
+import (
+	"testing"
+
+	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/rewrite/rcopy"
+	"github.com/relex/slog-agent/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTruncateRewrite(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"msg"})
+	c := &Config{}
+	assert.NoError(t, util.UnmarshalYamlString(`
+type: truncate
+maxLen: 5
+suffix: ...
+`, c))
+	assert.NoError(t, c.VerifyConfig(schema, true))
+	rw := c.NewRewriter(schema, rcopy.NewRewriter())
+
+	testCases := []struct {
+		Input    string
+		Expected string
+	}{ // the same as in ttruncate
+		{"", ""},
+		{"Foo", "Foo"},
+		{"Hello123", "Hello123"},
+		{"HelloWorld", "Hello..."},
+		{"1234ЛWorld", "1234..."},
+		{"1234世界World", "1234..."},
+		{"123世界World", "123..."},
+		{"12世界World", "12世..."},
+	}
+	for i, test := range testCases {
+		record := schema.NewTestRecord1(base.LogFields{test.Input})
+		buf := make([]byte, 100)
+		maxLength := rw.MaxFieldLength(test.Input, record)
+		length := rw.WriteFieldBody(test.Input, record, buf)
+		assert.Equalf(t, test.Expected, string(buf[:length]), "resulted[%d] %s", i, test.Input)
+		assert.Equalf(t, length, maxLength, "length[%d] %s", i, test.Input)
+		assert.Equalf(t, test.Input, record.Fields[0], "unchanged[%d] %s", i, test.Input)
+	}
+}
@@ -56,8 +56,9 @@ func (holder *AnchorsConfig) UnmarshalYAML(value *yaml.Node) error { //nolint:re
 
 // SchemaConfig defines the schema section in config file
 type SchemaConfig struct {
-	Fields    []string `yaml:"fields"`
-	MaxFields int      `yaml:"maxFields"`
+	Fields         []string `yaml:"fields"`
+	MaxFields      int      `yaml:"maxFields"`
+	MaxExtraFields int      `yaml:"maxExtraFields,omitempty"` // optional, max number of fields not defined in schema per record
 }
 
 // ParseConfigFile loads config from the path, creates the schema and verify all configurations
@@ -97,7 +98,7 @@ func ParseConfigFile(filepath string) (Config, base.LogSchema, ConfigStats, erro
 		return conf, schema, stats, err
 	}
 
-	if err := bsupport.VerifyTransformConfigs(conf.Transformations, schema, "transforms"); err != nil {
+	if err := bsupport.VerifyTopLevelTransformConfigs(conf.Transformations, schema, "transforms"); err != nil {
 		return conf, schema, stats, err
 	}
 
@@ -111,6 +112,10 @@ func ParseConfigFile(filepath string) (Config, base.LogSchema, ConfigStats, erro
 		if err := pair.VerifyConfig(schema); err != nil {
 			return conf, schema, stats, err
 		}
+
+		if err := bsupport.VerifyTransformConfigs(pair.Transformations, schema, fmt.Sprintf("outputBufferPairs[%s].transformations", pair.Name)); err != nil {
+			return conf, schema, stats, err
+		}
 	}
 
 	statsBuilder.Finish(&stats)
@@ -126,7 +131,7 @@ func checkAndCreateSchema(conf Config) (base.LogSchema, error) {
 	}
 
 	logger.Infof("create schema with fields: [%s]", strings.Join(conf.Schema.Fields, ", "))
-	schema, schemaErr := base.NewLogSchema(conf.Schema.Fields, conf.Schema.MaxFields)
+	schema, schemaErr := base.NewLogSchema(conf.Schema.Fields, conf.Schema.MaxFields, conf.Schema.MaxExtraFields)
 	if schemaErr != nil {
 		return base.LogSchema{}, fmt.Errorf("schema: %w", schemaErr)
 	}
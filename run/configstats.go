@@ -10,6 +10,7 @@ type ConfigStats struct {
 	FixedFields       []string // Fixed fields are those used by inputs and orchestration that cannot be moved or renamed
 	UnusedFields      []string
 	OrchestrationKeys []string
+	CustomMetrics     []base.LogCustomMetricSpec // specs of custom metrics registered by transforms
 }
 
 // Log logs important information or warnings if there is any
@@ -21,20 +22,35 @@ func (stats ConfigStats) Log(parentLogger logger.Logger) {
 
 // ConfigStatsBuilder helps building ConfigStats
 type ConfigStatsBuilder struct {
-	schema      *base.LogSchema
-	fieldsFixed []bool
-	fieldsInUse []bool
+	schema        *base.LogSchema
+	fieldsFixed   []bool
+	fieldsInUse   []bool
+	customMetrics []base.LogCustomMetricSpec
 }
 
 // NewConfigStatsBuilder creates a ConfigStatsTracker for the given schema
 //
 // Hook(s) in the schema will be used for tracking, and as a result a schema must have multiple trackers associated
 func NewConfigStatsBuilder(schema *base.LogSchema) *ConfigStatsBuilder {
-	return &ConfigStatsBuilder{
-		schema:      schema,
-		fieldsFixed: make([]bool, len(schema.GetFieldNames())),
-		fieldsInUse: make([]bool, len(schema.GetFieldNames())),
+	tracker := &ConfigStatsBuilder{
+		schema:        schema,
+		fieldsFixed:   make([]bool, len(schema.GetFieldNames())),
+		fieldsInUse:   make([]bool, len(schema.GetFieldNames())),
+		customMetrics: nil,
 	}
+	schema.OnCustomMetric = tracker.addCustomMetric
+	return tracker
+}
+
+// addCustomMetric tracks the spec of a custom metric, or returns error if it conflicts with any tracked one
+func (tracker *ConfigStatsBuilder) addCustomMetric(spec base.LogCustomMetricSpec) error {
+	for _, other := range tracker.customMetrics {
+		if err := spec.CheckConflict(other); err != nil {
+			return err
+		}
+	}
+	tracker.customMetrics = append(tracker.customMetrics, spec)
+	return nil
 }
 
 // BeginTrackingFixedFields begins tracking of fields that must remain fixed during config reload, not removed or moved
@@ -55,6 +71,7 @@ func (tracker *ConfigStatsBuilder) BeginTrackingFields() {
 // Finish ends all tracking and exports results to the given ConfigStats
 func (tracker *ConfigStatsBuilder) Finish(confStats *ConfigStats) {
 	tracker.schema.OnLocated = nil
+	tracker.schema.OnCustomMetric = nil
 
 	fixedFields := make([]string, 0, len(tracker.fieldsFixed))
 	unusedFields := make([]string, 0, len(tracker.fieldsInUse))
@@ -73,4 +90,5 @@ func (tracker *ConfigStatsBuilder) Finish(confStats *ConfigStats) {
 
 	confStats.FixedFields = fixedFields
 	confStats.UnusedFields = unusedFields
+	confStats.CustomMetrics = tracker.customMetrics
 }
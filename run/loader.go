@@ -76,6 +76,7 @@ func NewLoaderFromConfigFile(filepath string, metricPrefix string) (*Loader, err
 			Schema:              schema,
 			Deallocator:         base.NewLogAllocator(schema, len(config.OutputBuffersPairs)),
 			MetricKeyLocators:   schema.MustCreateFieldLocators(config.MetricKeys), // should have been verified in config parsing
+			CustomMetrics:       base.NewLogCustomMetricRegistry(metricPrefix),
 			TransformConfigs:    config.Transformations,
 			OutputBufferPairs:   config.OutputBuffersPairs,
 			NewConsumerOverride: nil,
@@ -123,7 +124,7 @@ func (loader *Loader) LaunchInputs(orchestrator base.Orchestrator) ([]string, fu
 
 	for index, inputConfig := range loader.Inputs {
 		input, ierr := inputConfig.Value.NewInput(logger.Root(), loader.PipelineArgs.Deallocator, loader.PipelineArgs.Schema,
-			orchestrator, loader.inputMetricFactory, stopRequest)
+			orchestrator, loader.inputMetricFactory, loader.PipelineArgs.CustomMetrics, stopRequest)
 		if ierr != nil {
 			loader.logger.Fatalf("input[%d]: %s", index, ierr.Error())
 		}
@@ -144,10 +145,11 @@ func (loader *Loader) GetConfigStats() ConfigStats {
 	return loader.ConfigStats
 }
 
-// GetMetricGatherer returns a metric gatherer containing default metrics and metrics from both input and pipeline(s)
+// GetMetricGatherer returns a metric gatherer containing default metrics, custom metrics and metrics from both input
+// and pipeline(s)
 func (loader *Loader) GetMetricGatherer() prometheus.Gatherer {
-	gset := make(prometheus.Gatherers, 0, 3)
-	gset = append(gset, prometheus.DefaultGatherer)
+	gset := make(prometheus.Gatherers, 0, 4)
+	gset = append(gset, prometheus.DefaultGatherer, loader.PipelineArgs.CustomMetrics)
 	if mf := loader.inputMetricFactory; mf != nil {
 		gset = append(gset, mf)
 	}
@@ -30,6 +30,10 @@ inputs:
     extractions:
       - type: delFields
         keys: [facility, pid]
+      - type: metric
+        name: syslog_records
+        kind: counter
+        labels: [app]
 `
 
 const sampleOrchestrationConf = `
@@ -136,6 +140,8 @@ func TestLoader(t *testing.T) {
 			map[string]string{"label": "emailFilter"}))
 		assert.Equal(t, float64(2), getMetricValue(t, metricFamilies, "input_passed_records_total",
 			map[string]string{"protocol": "syslog"}))
+		assert.Equal(t, float64(2), getMetricValue(t, metricFamilies, "custom_syslog_records_total",
+			map[string]string{"app": "appServ/foo.com"}))
 	})
 }
 
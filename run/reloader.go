@@ -55,6 +55,12 @@ func (reloader *Reloader) initiateDownstreamReload() (CompleteReloadingFunc, err
 		newLoader.Config, newLoader.PipelineArgs.Schema, newLoader.ConfigStats); err != nil {
 		return nil, err
 	}
+	// custom metrics are kept across reloads since inputs keep registering and updating them in the same registry
+	for _, spec := range newLoader.ConfigStats.CustomMetrics {
+		if err := reloader.Loader.PipelineArgs.CustomMetrics.CheckSpec(spec); err != nil {
+			return nil, err
+		}
+	}
 	newLoader.ConfigStats.Log(reloader.logger)
 
 	return func() base.Orchestrator {
@@ -62,6 +68,7 @@ func (reloader *Reloader) initiateDownstreamReload() (CompleteReloadingFunc, err
 		defer reloader.reloadingLock.Unlock()
 
 		newLoader.PipelineArgs.Deallocator = reloader.Loader.PipelineArgs.Deallocator
+		newLoader.PipelineArgs.CustomMetrics = reloader.Loader.PipelineArgs.CustomMetrics
 		newLoader.inputMetricFactory = reloader.Loader.inputMetricFactory // which also forbids new inputs from being launched
 		newLoader.pipelineMetricFactory = nil                             // will be new
 
@@ -91,6 +98,11 @@ func checkConfigCompatibility(
 		if oldMax != newMax {
 			return fmt.Errorf("schema/maxFields must not change: old=%d, new=%d", oldMax, newMax)
 		}
+		oldMaxExtra := oldConf.Schema.MaxExtraFields
+		newMaxExtra := newConf.Schema.MaxExtraFields
+		if oldMaxExtra != newMaxExtra {
+			return fmt.Errorf("schema/maxExtraFields must not change: old=%d, new=%d", oldMaxExtra, newMaxExtra)
+		}
 	}
 	{
 		var err error
@@ -108,6 +108,8 @@ func TestReloader(t *testing.T) {
 			assert.NoError(tt, promErr)
 			assert.Equal(tt, float64(numInput), getMetricValue(t, metricFamilies, "input_passed_records_total", map[string]string{"protocol": "syslog"}))
 			assert.Equal(tt, float64(outStat.NumNew), getMetricValue(t, metricFamilies, "process_passed_records_total", nil))
+			// custom metrics from inputs are kept
+			assert.Equal(tt, float64(numInput), getMetricValue(t, metricFamilies, "custom_syslog_records_total", nil))
 
 			labelledCounters := findMetricFamily(t, metricFamilies, "process_labelled_records_total")
 
@@ -187,6 +189,23 @@ func testReloadInvalidConfig(t *testing.T, orc *ReloadableOrchestrator, writeCon
 		assert.Nil(t, f)
 		assert.EqualError(t, err, "orchestration/keys must not change: old=[app], new=[app source]")
 	}
+	{
+		assert.NoError(t, writeConf(assembleConfig(
+			sampleSchemaConf,
+			sampleInputConf,
+			sampleOrchestrationConf,
+			sampleTransformationConf+`
+  - type: metric
+    name: syslog_records
+    kind: counter
+    labels: [host]
+`,
+			sampleOutputConf,
+		)))
+		f, err := orc.initiateReload()
+		assert.Nil(t, f)
+		assert.ErrorContains(t, err, "custom metric 'syslog_records' is already registered with a different kind, labels or buckets")
+	}
 }
 
 type outputStatistics struct {
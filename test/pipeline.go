@@ -8,22 +8,20 @@ import (
 	"github.com/relex/slog-agent/base"
 	"github.com/relex/slog-agent/base/bconfig"
 	"github.com/relex/slog-agent/base/bsupport"
+	"github.com/relex/slog-agent/defs"
 	"github.com/relex/slog-agent/run"
 	"github.com/samber/lo"
 )
 
 type testPipeline struct {
-	deallocator       base.LogAllocator
 	fallbackTimestamp time.Time
 	inputCounter      *base.LogInputCounterSet
 	inputParser       base.LogParser
 	outputNames       []string
-	outputDecoders    []base.ChunkDecoder
 	outputSavers      []chunkSaver
 	procCounter       *base.LogProcessCounterSet
-	transforms        []base.LogTransformFunc
-	serializers       []base.LogSerializer
-	chunkMakers       []base.LogChunkMaker
+	processor         *bsupport.LogProcessor
+	closeFuncs        []func() // to release transforms and parser
 }
 
 func preparePipeline(configFile string, tagOverride string, metricCreator promreg.MetricCreator,
@@ -40,7 +38,8 @@ func preparePipeline(configFile string, tagOverride string, metricCreator promre
 
 	inputConfig := conf.Inputs[0].Value // we support only one input for testing
 	allocator := base.NewLogAllocator(schema, len(conf.OutputBuffersPairs))
-	inputCounter := base.NewLogInputCounter(metricCreator.AddOrGetPrefix("input_", nil, nil))
+	customMetrics := base.NewLogCustomMetricRegistry("")
+	inputCounter := base.NewLogInputCounter(metricCreator.AddOrGetPrefix("input_", nil, nil), customMetrics)
 
 	parser, perr := inputConfig.NewParser(logger.Root(), allocator, schema, inputCounter)
 	if perr != nil {
@@ -52,31 +51,48 @@ func preparePipeline(configFile string, tagOverride string, metricCreator promre
 	})
 	procCounter := base.NewLogProcessCounter(
 		metricCreator.AddOrGetPrefix("process_", nil, nil),
+		customMetrics,
 		schema,
 		schema.MustCreateFieldLocators(conf.MetricKeys),
 		outputNames,
 	)
 
+	var closeFuncs []func()
+	if closer, ok := parser.(base.LogParserCloser); ok {
+		closeFuncs = append(closeFuncs, closer.Close)
+	}
+	transformStages, closeTransforms := bsupport.NewTransformStagesFromConfig(conf.Transformations, schema, logger.Root(), procCounter)
+	closeFuncs = append(closeFuncs, closeTransforms)
+
+	outputSavers := lo.Map(outputNames, func(outputName string, _ int) chunkSaver {
+		return newChunkSaver(outputName)
+	})
+	outputInterfaces := lo.Map(conf.OutputBuffersPairs, func(pair bconfig.OutputBufferConfig, i int) bsupport.OutputInterface {
+		outputLogger := logger.WithField("output", pair.Name)
+		outputTransforms, closeOutputTransforms := bsupport.NewTransformsFromConfig(pair.Transformations, schema, outputLogger, procCounter)
+		closeFuncs = append(closeFuncs, closeOutputTransforms)
+		decoder := pair.OutputConfig.Value
+		saver := outputSavers[i]
+		return bsupport.OutputInterface{
+			LogSerializer: pair.OutputConfig.Value.NewSerializer(logger.Root(), schema, tagOverride),
+			LogChunkMaker: pair.OutputConfig.Value.NewChunkMaker(logger.Root(), tagOverride),
+			Name:          pair.Name,
+			AcceptChunk: func(chunk base.LogChunk) {
+				saver.Write(chunk, decoder)
+			},
+			Transforms: outputTransforms,
+		}
+	})
+
 	return &testPipeline{
-		deallocator:       *allocator,
 		fallbackTimestamp: time.Now(),
 		inputCounter:      inputCounter,
 		inputParser:       parser,
 		outputNames:       outputNames,
-		outputDecoders: lo.Map(conf.OutputBuffersPairs, func(pair bconfig.OutputBufferConfig, _ int) base.ChunkDecoder {
-			return pair.OutputConfig.Value
-		}),
-		outputSavers: lo.Map(outputNames, func(outputName string, _ int) chunkSaver {
-			return newChunkSaver(outputName)
-		}),
-		procCounter: procCounter,
-		transforms:  bsupport.NewTransformsFromConfig(conf.Transformations, schema, logger.Root(), procCounter),
-		serializers: lo.Map(conf.OutputBuffersPairs, func(pair bconfig.OutputBufferConfig, _ int) base.LogSerializer {
-			return pair.OutputConfig.Value.NewSerializer(logger.Root(), schema, tagOverride)
-		}),
-		chunkMakers: lo.Map(conf.OutputBuffersPairs, func(pair bconfig.OutputBufferConfig, _ int) base.LogChunkMaker {
-			return pair.OutputConfig.Value.NewChunkMaker(logger.Root(), tagOverride)
-		}),
+		outputSavers:      outputSavers,
+		procCounter:       procCounter,
+		processor:         bsupport.NewLogProcessor(allocator, procCounter, transformStages, outputInterfaces),
+		closeFuncs:        closeFuncs,
 	}
 }
 
@@ -84,66 +100,41 @@ func (p *testPipeline) GetOutputNames() []string {
 	return p.outputNames
 }
 
+// Run processes the input lines repeatedly and releases the pipeline at the end. It can only be called once.
+//
+// Records are processed in buffers like in real pipelines, by the same LogProcessor used in LogProcessingWorker.
 func (p *testPipeline) Run(inputLines [][]byte, repeat int) {
 	for _, saver := range p.outputSavers {
 		defer saver.Close()
 	}
+	for _, closeFunc := range p.closeFuncs {
+		defer closeFunc()
+	}
 
-	chunkHolder := make([]*base.LogChunk, len(p.chunkMakers))
+	buffer := make([]*base.LogRecord, 0, defs.IntermediateBufferMaxNumLogs)
 	for n := 0; n < repeat; n++ {
 		for _, line := range inputLines {
-			p.process(line, chunkHolder)
-			p.save(chunkHolder, p.outputSavers)
-		}
-	}
-	p.flush(chunkHolder)
-	p.save(chunkHolder, p.outputSavers)
-}
-
-func (p *testPipeline) process(s []byte, chunkHolder []*base.LogChunk) {
-	if s[len(s)-1] == '\n' {
-		s = s[:len(s)-1]
-	}
-	record := p.inputParser.Parse(s, p.fallbackTimestamp)
-	if record == nil {
-		return
-	}
-	icounter := p.procCounter.SelectMetricKeySet(record)
-	if bsupport.RunTransforms(record, p.transforms) == base.DROP {
-		icounter.CountRecordDrop(record)
-		p.deallocator.Release(record)
-		return
-	}
-	icounter.CountRecordPass(record)
-	for outputIndex, serializer := range p.serializers {
-		stream := serializer.SerializeRecord(record)
-		p.procCounter.CountStream(outputIndex, stream)
-
-		maybeChunk := p.chunkMakers[outputIndex].WriteStream(stream)
-		if maybeChunk != nil {
-			p.procCounter.CountChunk(outputIndex, maybeChunk)
-		}
-		chunkHolder[outputIndex] = maybeChunk
-	}
-}
-
-func (p *testPipeline) flush(chunkHolder []*base.LogChunk) {
-	for outputIndex, chunkMaker := range p.chunkMakers {
-		maybeChunk := chunkMaker.FlushBuffer()
-		if maybeChunk != nil {
-			p.procCounter.CountChunk(outputIndex, maybeChunk)
+			record := p.parse(line)
+			if record == nil {
+				continue
+			}
+			buffer = append(buffer, record)
+			if len(buffer) == cap(buffer) {
+				p.processor.ProcessBuffer(buffer)
+				buffer = buffer[:0]
+			}
 		}
-		chunkHolder[outputIndex] = maybeChunk
 	}
+	p.processor.ProcessBuffer(buffer)
+	p.processor.FlushTransforms()
+	p.processor.FlushChunks()
 	p.inputCounter.UpdateMetrics()
 	p.procCounter.UpdateMetrics()
 }
 
-func (p *testPipeline) save(chunkHolder []*base.LogChunk, outputSavers []chunkSaver) {
-	for outputIndex, decoder := range p.outputDecoders {
-		maybeChunk := chunkHolder[outputIndex]
-		if maybeChunk != nil {
-			outputSavers[outputIndex].Write(*maybeChunk, decoder)
-		}
+func (p *testPipeline) parse(s []byte) *base.LogRecord {
+	if s[len(s)-1] == '\n' {
+		s = s[:len(s)-1]
 	}
+	return p.inputParser.Parse(s, p.fallbackTimestamp)
 }
@@ -2,6 +2,7 @@ package test
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/relex/gotils/logger"
@@ -30,6 +31,21 @@ func TestDataGeneration(t *testing.T) {
 	}
 
 	assert.NoError(t, os.WriteFile("../testdata/development/all-pipeline.prom", []byte(promext.DumpMetrics("", true, true, mfactory)), 0644))
+
+	t.Log("regenerate log outputs for output transformations...")
+	generateFixtureOutputs(testdata.GetOutputTransformsDir())
+
+	t.Log("regenerate log outputs for transform stages...")
+	generateFixtureOutputs(testdata.GetTransformStagesDir())
+}
+
+// generateFixtureOutputs runs the input of a fixture dir by its config and saves outputs as expected results
+func generateFixtureOutputs(dir string) {
+	pipeline := preparePipeline(filepath.Join(dir, "config.yml"), "", promreg.NewMetricFactory("testpipeline_", nil, nil),
+		func(outName string) chunkSaver {
+			return newChunkSaver(outName, filepath.Join(dir, "output-$OUTPUT.json"))
+		})
+	pipeline.Run(loadInputRecords(filepath.Join(dir, "input.log")), 1)
 }
 
 func TestPipeline(t *testing.T) {
@@ -77,3 +93,37 @@ func TestPipeline(t *testing.T) {
 		}
 	})
 }
+
+func TestPipelineOutputTransformations(t *testing.T) {
+	if util.IsTestGenerationMode() {
+		return
+	}
+	testFixtureOutputs(t, testdata.GetOutputTransformsDir(), 2)
+}
+
+func TestPipelineTransformStages(t *testing.T) {
+	if util.IsTestGenerationMode() {
+		return
+	}
+	testFixtureOutputs(t, testdata.GetTransformStagesDir(), 1)
+}
+
+// testFixtureOutputs runs the input of a fixture dir by its config and compares outputs with expected results
+func testFixtureOutputs(t *testing.T, dir string, numOutputs int) {
+	actualOutputGetters := make(map[string]func() string)
+	pipeline := preparePipeline(filepath.Join(dir, "config.yml"), "", promreg.NewMetricFactory("testpipeline_", nil, nil),
+		func(outName string) chunkSaver {
+			saver, getter := newInMemoryChunkSaver(logger.WithField("output", outName))
+			actualOutputGetters[outName] = getter
+			return saver
+		})
+	pipeline.Run(loadInputRecords(filepath.Join(dir, "input.log")), 1)
+
+	assert.Len(t, actualOutputGetters, numOutputs)
+	for outName, outGetter := range actualOutputGetters {
+		expected, err := os.ReadFile(filepath.Join(dir, "output-"+outName+".json"))
+		if assert.NoError(t, err, "known output %s", outName) {
+			assert.Equal(t, string(expected), outGetter(), "known output %s", outName)
+		}
+	}
+}
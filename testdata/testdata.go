@@ -25,6 +25,16 @@ func GetConfigDumpPath() string {
 	return filepath.Join(absoluteDirPath, "config_sample_dump.yml")
 }
 
+// GetOutputTransformsDir returns the dir of config, input and expected outputs to test per-output transformations
+func GetOutputTransformsDir() string {
+	return filepath.Join(absoluteDirPath, "outputtransforms")
+}
+
+// GetTransformStagesDir returns the dir of config, input and expected outputs to test batch and expand transforms
+func GetTransformStagesDir() string {
+	return filepath.Join(absoluteDirPath, "transformstages")
+}
+
 var inputExtPattern = regexp.MustCompile(`-input\.log$`)
 
 func ListInputFiles(t *testing.T) []string {
@@ -5,37 +5,74 @@ import (
 	"github.com/relex/slog-agent/base/bconfig"
 	"github.com/relex/slog-agent/transform/taddfields"
 	"github.com/relex/slog-agent/transform/tblock"
+	"github.com/relex/slog-agent/transform/tclamptime"
+	"github.com/relex/slog-agent/transform/tcopyfields"
+	"github.com/relex/slog-agent/transform/tdecode"
+	"github.com/relex/slog-agent/transform/tdedup"
 	"github.com/relex/slog-agent/transform/tdelfields"
 	"github.com/relex/slog-agent/transform/tdrop"
 	"github.com/relex/slog-agent/transform/textract"
 	"github.com/relex/slog-agent/transform/textractspecial"
+	"github.com/relex/slog-agent/transform/tfingerprint"
+	"github.com/relex/slog-agent/transform/tgeoip"
 	"github.com/relex/slog-agent/transform/tif"
+	"github.com/relex/slog-agent/transform/tlookup"
 	"github.com/relex/slog-agent/transform/tmapvalue"
+	"github.com/relex/slog-agent/transform/tmask"
+	"github.com/relex/slog-agent/transform/tmetric"
+	"github.com/relex/slog-agent/transform/tnormalize"
+	"github.com/relex/slog-agent/transform/tparsekv"
 	"github.com/relex/slog-agent/transform/tparsetime"
+	"github.com/relex/slog-agent/transform/tparseuseragent"
 	"github.com/relex/slog-agent/transform/tredactemail"
+	"github.com/relex/slog-agent/transform/tredactquery"
 	"github.com/relex/slog-agent/transform/treplace"
+	"github.com/relex/slog-agent/transform/tsanitize"
+	"github.com/relex/slog-agent/transform/tsplit"
 	"github.com/relex/slog-agent/transform/tswitch"
+	"github.com/relex/slog-agent/transform/tthrottle"
 	"github.com/relex/slog-agent/transform/ttruncate"
 	"github.com/relex/slog-agent/transform/tunescape"
+	"github.com/relex/slog-agent/transform/twasm"
 )
 
 func init() {
 	bconfig.RegisterConfigConstructors(bconfig.LogTransformConfigCreatorTable{
-		"addFields":   func() bconfig.LogTransformConfig { return &taddfields.Config{} },
-		"block":       func() bconfig.LogTransformConfig { return &tblock.Config{} },
-		"delFields":   func() bconfig.LogTransformConfig { return &tdelfields.Config{} },
-		"drop":        func() bconfig.LogTransformConfig { return &tdrop.Config{} },
-		"extract":     func() bconfig.LogTransformConfig { return &textract.Config{} },
-		"extractHead": func() bconfig.LogTransformConfig { return &textractspecial.Config{} },
-		"extractTail": func() bconfig.LogTransformConfig { return &textractspecial.Config{} },
-		"if":          func() bconfig.LogTransformConfig { return &tif.Config{} },
-		"mapValue":    func() bconfig.LogTransformConfig { return &tmapvalue.Config{} },
-		"parseTime":   func() bconfig.LogTransformConfig { return &tparsetime.Config{} },
-		"redactEmail": func() bconfig.LogTransformConfig { return &tredactemail.Config{} },
-		"replace":     func() bconfig.LogTransformConfig { return &treplace.Config{} },
-		"switch":      func() bconfig.LogTransformConfig { return &tswitch.Config{} },
-		"truncate":    func() bconfig.LogTransformConfig { return &ttruncate.Config{} },
-		"unescape":    func() bconfig.LogTransformConfig { return &tunescape.Config{} },
+		"addFields":      func() bconfig.LogTransformConfig { return &taddfields.Config{} },
+		"block":          func() bconfig.LogTransformConfig { return &tblock.Config{} },
+		"clampTime":      func() bconfig.LogTransformConfig { return &tclamptime.Config{} },
+		"copyFields":     func() bconfig.LogTransformConfig { return &tcopyfields.Config{} },
+		"decode":         func() bconfig.LogTransformConfig { return &tdecode.Config{} },
+		"dedup":          func() bconfig.LogTransformConfig { return &tdedup.Config{} },
+		"delFields":      func() bconfig.LogTransformConfig { return &tdelfields.Config{} },
+		"drop":           func() bconfig.LogTransformConfig { return &tdrop.Config{} },
+		"extract":        func() bconfig.LogTransformConfig { return &textract.Config{} },
+		"extractHead":    func() bconfig.LogTransformConfig { return &textractspecial.Config{} },
+		"extractTail":    func() bconfig.LogTransformConfig { return &textractspecial.Config{} },
+		"fingerprint":    func() bconfig.LogTransformConfig { return &tfingerprint.Config{} },
+		"geoip":          func() bconfig.LogTransformConfig { return &tgeoip.Config{} },
+		"if":             func() bconfig.LogTransformConfig { return &tif.Config{} },
+		"lookup":         func() bconfig.LogTransformConfig { return &tlookup.Config{} },
+		"lowercase":      func() bconfig.LogTransformConfig { return &tnormalize.Config{} },
+		"mask":           func() bconfig.LogTransformConfig { return &tmask.Config{} },
+		"mapValue":       func() bconfig.LogTransformConfig { return &tmapvalue.Config{} },
+		"metric":         func() bconfig.LogTransformConfig { return &tmetric.Config{} },
+		"moveFields":     func() bconfig.LogTransformConfig { return &tcopyfields.Config{} },
+		"parseKV":        func() bconfig.LogTransformConfig { return &tparsekv.Config{} },
+		"parseTime":      func() bconfig.LogTransformConfig { return &tparsetime.Config{} },
+		"parseUserAgent": func() bconfig.LogTransformConfig { return &tparseuseragent.Config{} },
+		"redactEmail":    func() bconfig.LogTransformConfig { return &tredactemail.Config{} },
+		"redactQuery":    func() bconfig.LogTransformConfig { return &tredactquery.Config{} },
+		"replace":        func() bconfig.LogTransformConfig { return &treplace.Config{} },
+		"sanitize":       func() bconfig.LogTransformConfig { return &tsanitize.Config{} },
+		"split":          func() bconfig.LogTransformConfig { return &tsplit.Config{} },
+		"switch":         func() bconfig.LogTransformConfig { return &tswitch.Config{} },
+		"throttle":       func() bconfig.LogTransformConfig { return &tthrottle.Config{} },
+		"trim":           func() bconfig.LogTransformConfig { return &tnormalize.Config{} },
+		"truncate":       func() bconfig.LogTransformConfig { return &ttruncate.Config{} },
+		"unescape":       func() bconfig.LogTransformConfig { return &tunescape.Config{} },
+		"uppercase":      func() bconfig.LogTransformConfig { return &tnormalize.Config{} },
+		"wasm":           func() bconfig.LogTransformConfig { return &twasm.Config{} },
 	})
 }
 
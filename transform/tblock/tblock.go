@@ -17,13 +17,16 @@ type Config struct {
 }
 
 type blockTransform struct {
-	steps []base.LogTransformFunc
+	steps      []base.LogTransformFunc
+	closeSteps func()
 }
 
 // NewTransform creates blockTransform
 func (c *Config) NewTransform(schema base.LogSchema, parentLogger logger.Logger, customCounterRegistry base.LogCustomCounterRegistry) base.LogTransform {
+	steps, closeSteps := bsupport.NewTransformsFromConfig(c.Steps, schema, parentLogger, customCounterRegistry)
 	return &blockTransform{
-		steps: bsupport.NewTransformsFromConfig(c.Steps, schema, parentLogger, customCounterRegistry),
+		steps:      steps,
+		closeSteps: closeSteps,
 	}
 }
 
@@ -38,3 +41,7 @@ func (c *Config) VerifyConfig(schema base.LogSchema) error {
 func (tf *blockTransform) Transform(record *base.LogRecord) base.FilterResult {
 	return bsupport.RunTransforms(record, tf.steps)
 }
+
+func (tf *blockTransform) Close() {
+	tf.closeSteps()
+}
@@ -0,0 +1,110 @@
+// Package tclamptime provides 'clampTime' transform, which corrects timestamps too far in the past or future compared
+// to the time of processing, e.g. from hosts with broken clocks.
+//
+// The transform should be placed after parseTime. Records are processed shortly after being received, so the time of
+// processing is used as the receive time.
+package tclamptime
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/relex/gotils/logger"
+	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/base/bconfig"
+)
+
+// Config for clampTimeTransform
+type Config struct {
+	bconfig.Header `yaml:",inline"`
+	MaxPast        time.Duration `yaml:"maxPast"`     // max age of timestamps, unlimited if zero
+	MaxFuture      time.Duration `yaml:"maxFuture"`   // max distance of timestamps in future, unlimited if zero
+	Mode           string        `yaml:"mode"`        // "now" to replace by the receive time, "clamp" to set to the closest bound
+	OriginalKey    string        `yaml:"originalKey"` // optional field to store the original timestamp in RFC 3339
+	MetricLabel    string        `yaml:"metricLabel"`
+}
+
+type clampTimeTransform struct {
+	maxPast         time.Duration
+	maxFuture       time.Duration
+	clamp           bool
+	originalLocator base.LogFieldLocator // MissingFieldLocator if unspecified
+	countCorrected  func(length int)
+	now             func() time.Time
+}
+
+const (
+	modeNow   = "now"
+	modeClamp = "clamp"
+)
+
+// NewTransform creates clampTimeTransform
+func (cfg *Config) NewTransform(schema base.LogSchema, _ logger.Logger, customCounterRegistry base.LogCustomCounterRegistry) base.LogTransform {
+	return newClampTimeTransform(cfg, schema, customCounterRegistry, time.Now)
+}
+
+// VerifyConfig verifies clampTimeTransform config
+func (cfg *Config) VerifyConfig(schema base.LogSchema) error {
+	if cfg.MaxPast < 0 {
+		return fmt.Errorf(".maxPast cannot be negative: %s", cfg.MaxPast)
+	}
+	if cfg.MaxFuture < 0 {
+		return fmt.Errorf(".maxFuture cannot be negative: %s", cfg.MaxFuture)
+	}
+	if cfg.MaxPast == 0 && cfg.MaxFuture == 0 {
+		return fmt.Errorf(".maxPast and .maxFuture are both unspecified")
+	}
+	switch cfg.Mode {
+	case modeNow, modeClamp:
+	default:
+		return fmt.Errorf(".mode must be '%s' or '%s': '%s'", modeNow, modeClamp, cfg.Mode)
+	}
+	if len(cfg.OriginalKey) > 0 {
+		if _, err := schema.CreateFieldLocator(cfg.OriginalKey); err != nil {
+			return fmt.Errorf(".originalKey '%s' is invalid: %w", cfg.OriginalKey, err)
+		}
+	}
+	if len(cfg.MetricLabel) == 0 {
+		return fmt.Errorf(".metricLabel is unspecified")
+	}
+	return nil
+}
+
+func newClampTimeTransform(cfg *Config, schema base.LogSchema, customCounterRegistry base.LogCustomCounterRegistry, now func() time.Time) *clampTimeTransform {
+	tf := &clampTimeTransform{
+		maxPast:         cfg.MaxPast,
+		maxFuture:       cfg.MaxFuture,
+		clamp:           cfg.Mode == modeClamp,
+		originalLocator: base.MissingFieldLocator,
+		countCorrected:  customCounterRegistry.RegisterCustomCounter(cfg.MetricLabel),
+		now:             now,
+	}
+	if len(cfg.OriginalKey) > 0 {
+		tf.originalLocator = schema.MustCreateFieldLocator(cfg.OriginalKey)
+	}
+	return tf
+}
+
+func (tf *clampTimeTransform) Transform(record *base.LogRecord) base.FilterResult {
+	now := tf.now()
+	var bound time.Time
+	switch {
+	case tf.maxPast > 0 && now.Sub(record.Timestamp) > tf.maxPast:
+		bound = now.Add(-tf.maxPast)
+	case tf.maxFuture > 0 && record.Timestamp.Sub(now) > tf.maxFuture:
+		bound = now.Add(tf.maxFuture)
+	default:
+		return base.PASS
+	}
+
+	tf.countCorrected(record.RawLength)
+	if tf.originalLocator != base.MissingFieldLocator {
+		tf.originalLocator.Set(record.Fields, record.Timestamp.Format(time.RFC3339Nano))
+	}
+	if tf.clamp {
+		record.Timestamp = bound
+	} else {
+		record.Timestamp = now
+	}
+	return base.PASS
+}
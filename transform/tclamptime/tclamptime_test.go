@@ -0,0 +1,84 @@
+package tclamptime
+
+import (
+	"testing"
+	"time"
+
+	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/base/btest"
+	"github.com/relex/slog-agent/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClampTimeTransform(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"log", "origTime"})
+	now := time.Date(2022, 8, 15, 3, 48, 20, 0, time.UTC)
+	for _, mode := range []string{"now", "clamp"} {
+		c := &Config{}
+		if !assert.NoError(t, util.UnmarshalYamlString(`
+type: clampTime
+maxPast: 24h
+maxFuture: 10m
+mode: `+mode+`
+originalKey: origTime
+metricLabel: skewed
+`, c)) {
+			return
+		}
+		if !assert.NoError(t, c.VerifyConfig(schema)) {
+			return
+		}
+		reg, lookup := btest.NewStubLogCustomCounterRegistry()
+		tf := newClampTimeTransform(c, schema, reg, func() time.Time { return now })
+
+		run := func(timestamp time.Time) (time.Time, string) {
+			r := schema.NewTestRecord2(timestamp, base.LogFields{"hello", ""})
+			r.RawLength = 10
+			assert.Equal(t, base.PASS, tf.Transform(r))
+			return r.Timestamp, r.Fields[1]
+		}
+
+		inRange := now.Add(-23 * time.Hour)
+		tm, orig := run(inRange)
+		assert.Equal(t, inRange, tm)
+		assert.Equal(t, "", orig)
+
+		tm, orig = run(now.Add(5 * time.Minute))
+		assert.Equal(t, now.Add(5*time.Minute), tm)
+		assert.Equal(t, "", orig)
+
+		tm, orig = run(time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC))
+		if mode == "clamp" {
+			assert.Equal(t, now.Add(-24*time.Hour), tm)
+		} else {
+			assert.Equal(t, now, tm)
+		}
+		assert.Equal(t, "2019-01-02T03:04:05Z", orig)
+
+		tm, orig = run(now.Add(time.Hour))
+		if mode == "clamp" {
+			assert.Equal(t, now.Add(10*time.Minute), tm)
+		} else {
+			assert.Equal(t, now, tm)
+		}
+		assert.Equal(t, "2022-08-15T04:48:20Z", orig)
+
+		count, length := lookup("skewed")
+		assert.Equal(t, int64(2), count, mode)
+		assert.Equal(t, int64(20), length, mode)
+	}
+}
+
+func TestClampTimeTransformVerify(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"log"})
+	c := &Config{}
+	assert.NoError(t, util.UnmarshalYamlString("type: clampTime\nmode: now\nmetricLabel: skewed", c))
+	assert.EqualError(t, c.VerifyConfig(schema), ".maxPast and .maxFuture are both unspecified")
+	c.MaxFuture = time.Minute
+	assert.NoError(t, c.VerifyConfig(schema))
+	c.Mode = "fix"
+	assert.EqualError(t, c.VerifyConfig(schema), ".mode must be 'now' or 'clamp': 'fix'")
+	c.Mode = "clamp"
+	c.OriginalKey = "time"
+	assert.ErrorContains(t, c.VerifyConfig(schema), ".originalKey 'time' is invalid")
+}
@@ -0,0 +1,110 @@
+// Package tcopyfields provides 'copyFields' and 'moveFields' transforms, which assign values of fields directly to other
+// fields without going through string templates as in 'addFields', e.g. "service: vhost" to copy vhost to service.
+//
+// Sources prefixed by "extra." are looked up from extra fields if enabled by schema's maxExtraFields, e.g. "extra.user"
+// to promote some of parsed keys into the schema for matching.
+package tcopyfields
+
+import (
+	"fmt"
+
+	"github.com/relex/gotils/logger"
+	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/base/bconfig"
+)
+
+// Config for copyFieldsTransform
+type Config struct {
+	bconfig.Header `yaml:",inline"`
+	Fields         map[string]string `yaml:"fields"` // destination key => source key
+}
+
+type copyFieldsTransform struct {
+	sources      []base.LogFieldLocator // MissingFieldLocator for extra fields
+	extraSources []string               // keys of extra fields or empty, same length as sources
+	destinations []base.LogFieldLocator
+	values       []string // buffer of source values, so that all pairs are assigned as if simultaneously
+	move         bool     // whether to clear source fields
+}
+
+// NewTransform creates copyFieldsTransform
+func (c *Config) NewTransform(schema base.LogSchema, _ logger.Logger, _ base.LogCustomCounterRegistry) base.LogTransform {
+	tf := &copyFieldsTransform{
+		sources:      make([]base.LogFieldLocator, 0, len(c.Fields)),
+		extraSources: make([]string, 0, len(c.Fields)),
+		destinations: make([]base.LogFieldLocator, 0, len(c.Fields)),
+		values:       make([]string, len(c.Fields)),
+		move:         c.isMove(),
+	}
+	for dstKey, srcKey := range c.Fields {
+		src, extraKey, err := schema.CreateFieldOrExtraLocator(srcKey)
+		if err != nil {
+			logger.Panicf("invalid source '%s': %s", srcKey, err.Error())
+		}
+		tf.sources = append(tf.sources, src)
+		tf.extraSources = append(tf.extraSources, extraKey)
+		tf.destinations = append(tf.destinations, schema.MustCreateFieldLocator(dstKey))
+	}
+	return tf
+}
+
+// VerifyConfig verifies copyFieldsTransform config
+func (c *Config) VerifyConfig(schema base.LogSchema) error {
+	if len(c.Fields) == 0 {
+		return fmt.Errorf(".fields is empty")
+	}
+	for dstKey, srcKey := range c.Fields {
+		if _, err := schema.CreateFieldLocator(dstKey); err != nil {
+			return fmt.Errorf(".fields[%s] is invalid: %w", dstKey, err)
+		}
+		if _, _, err := schema.CreateFieldOrExtraLocator(srcKey); err != nil {
+			return fmt.Errorf(".fields[%s] has invalid source '%s': %w", dstKey, srcKey, err)
+		}
+		if dstKey == srcKey {
+			return fmt.Errorf(".fields[%s] has the same source", dstKey)
+		}
+	}
+	return nil
+}
+
+func (c *Config) isMove() bool {
+	switch c.Type {
+	case "copyFields":
+		return false
+	case "moveFields":
+		return true
+	default:
+		panic(fmt.Sprintf("unsupported type '%s'", c.Type))
+	}
+}
+
+// Transform copies or moves values of source fields to destinations
+//
+// Empty source values leave their destinations unchanged, the same as in 'addFields'
+func (tf *copyFieldsTransform) Transform(record *base.LogRecord) base.FilterResult {
+	fields := record.Fields
+	values := tf.values
+	for i, src := range tf.sources {
+		if src == base.MissingFieldLocator {
+			values[i] = record.Extra.Get(tf.extraSources[i])
+		} else {
+			values[i] = src.Get(fields)
+		}
+	}
+	if tf.move {
+		for i, src := range tf.sources {
+			if src == base.MissingFieldLocator {
+				record.Extra.Del(tf.extraSources[i])
+			} else {
+				src.Del(fields)
+			}
+		}
+	}
+	for i, dst := range tf.destinations {
+		if len(values[i]) > 0 {
+			dst.Set(fields, values[i])
+		}
+		values[i] = ""
+	}
+	return base.PASS
+}
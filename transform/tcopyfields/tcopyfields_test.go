@@ -0,0 +1,87 @@
+package tcopyfields
+
+import (
+	"testing"
+
+	"github.com/relex/gotils/logger"
+	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCopyFieldsTransform(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"a", "b", "c", "d"})
+	c := &Config{}
+	assert.NoError(t, util.UnmarshalYamlString(`
+type: copyFields
+fields:
+  a: b
+  b: a
+  c: d
+`, c))
+	assert.NoError(t, c.VerifyConfig(schema))
+	tf := c.NewTransform(schema, logger.Root(), nil)
+
+	record := schema.NewTestRecord1(base.LogFields{"foo", "bar", "xxx", ""})
+	assert.Equal(t, base.PASS, tf.Transform(record))
+	assert.Equal(t, base.LogFields{"bar", "foo", "xxx", ""}, record.Fields[:4])
+}
+
+func TestMoveFieldsTransform(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"a", "b", "c", "d"})
+	c := &Config{}
+	assert.NoError(t, util.UnmarshalYamlString(`
+type: moveFields
+fields:
+  c: a
+  d: b
+`, c))
+	assert.NoError(t, c.VerifyConfig(schema))
+	tf := c.NewTransform(schema, logger.Root(), nil)
+
+	record := schema.NewTestRecord1(base.LogFields{"foo", "", "xxx", "yyy"})
+	assert.Equal(t, base.PASS, tf.Transform(record))
+	assert.Equal(t, base.LogFields{"", "", "foo", "yyy"}, record.Fields[:4])
+}
+
+func TestCopyFieldsConfig(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"a", "b"})
+	c := &Config{}
+	assert.NoError(t, util.UnmarshalYamlString(`
+type: copyFields
+fields:
+  a: x
+`, c))
+	assert.EqualError(t, c.VerifyConfig(schema), ".fields[a] has invalid source 'x': field 'x' is not defined in schema")
+
+	c = &Config{}
+	assert.NoError(t, util.UnmarshalYamlString(`
+type: moveFields
+fields:
+  b: b
+`, c))
+	assert.EqualError(t, c.VerifyConfig(schema), ".fields[b] has the same source")
+}
+
+func TestMoveFieldsTransformFromExtra(t *testing.T) {
+	schema, err := base.NewLogSchema([]string{"a", "b"}, 2, 4)
+	assert.NoError(t, err)
+	c := &Config{}
+	assert.NoError(t, util.UnmarshalYamlString(`
+type: moveFields
+fields:
+  a: extra.user
+  b: extra.missing
+`, c))
+	assert.NoError(t, c.VerifyConfig(schema))
+	tf := c.NewTransform(schema, logger.Root(), nil)
+
+	record := schema.NewTestRecord1(base.LogFields{"", "bar"})
+	record.Extra.Set("user", "john")
+	assert.Equal(t, base.PASS, tf.Transform(record))
+	assert.Equal(t, base.LogFields{"john", "bar"}, record.Fields)
+	assert.Equal(t, "", record.Extra.Get("user"))
+
+	c.Fields = map[string]string{"a": "user"}
+	assert.EqualError(t, c.VerifyConfig(schema), ".fields[a] has invalid source 'user': field 'user' is not defined in schema")
+}
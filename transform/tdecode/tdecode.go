@@ -0,0 +1,159 @@
+// Package tdecode provides 'decode' transform to decode base64, hex or URL-encoded fields, e.g. payloads encoded by apps
+// to be put in syslog messages.
+//
+// Decoded values are always shorter than the encoded ones and written back in place into the backing buffer of records,
+// without new allocations, unless the values are shared with other fields or not from the backing buffer, in which case
+// they are copied. Invalid values or values not decoded to valid UTF-8 are left as-is and counted by metric.
+package tdecode
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/relex/gotils/logger"
+	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/base/bconfig"
+	"github.com/relex/slog-agent/util"
+)
+
+// Config for decodeTransform
+type Config struct {
+	bconfig.Header `yaml:",inline"`
+	Key            string `yaml:"key"`
+	Mode           string `yaml:"mode"`        // see decoderMap
+	MetricLabel    string `yaml:"metricLabel"` // label for failures
+}
+
+type decodeTransform struct {
+	keyLocator base.LogFieldLocator
+	decoder    decoder
+	buffer     []byte // reused buffer for decoding
+	counter    func(length int)
+}
+
+// decoder decodes src to dst and returns the length, or -1 if src is invalid. dst must be as long as src.
+type decoder func(dst []byte, src string) int
+
+var decoderMap = map[string]decoder{
+	"base64":    newBase64Decoder(base64.RawStdEncoding),
+	"base64url": newBase64Decoder(base64.RawURLEncoding),
+	"hex":       decodeHex,
+	"url":       decodeURL,
+}
+
+// NewTransform creates decodeTransform
+func (cfg *Config) NewTransform(schema base.LogSchema, _ logger.Logger, customCounterRegistry base.LogCustomCounterRegistry) base.LogTransform {
+	return &decodeTransform{
+		keyLocator: schema.MustCreateFieldLocator(cfg.Key),
+		decoder:    decoderMap[cfg.Mode],
+		buffer:     nil,
+		counter:    customCounterRegistry.RegisterCustomCounter(cfg.MetricLabel),
+	}
+}
+
+// VerifyConfig verifies decodeTransform config
+func (cfg *Config) VerifyConfig(schema base.LogSchema) error {
+	if len(cfg.Key) == 0 {
+		return fmt.Errorf(".key is unspecified")
+	}
+	if _, err := schema.CreateFieldLocator(cfg.Key); err != nil {
+		return fmt.Errorf(".key '%s' is invalid: %w", cfg.Key, err)
+	}
+	if len(cfg.Mode) == 0 {
+		return fmt.Errorf(".mode is unspecified")
+	}
+	if _, ok := decoderMap[cfg.Mode]; !ok {
+		return fmt.Errorf(".mode '%s' is invalid, must be one of base64, base64url, hex or url", cfg.Mode)
+	}
+	if len(cfg.MetricLabel) == 0 {
+		return fmt.Errorf(".metricLabel is unspecified")
+	}
+	return nil
+}
+
+func (tf *decodeTransform) Transform(record *base.LogRecord) base.FilterResult {
+	value := tf.keyLocator.Get(record.Fields)
+	if len(value) == 0 {
+		return base.PASS
+	}
+	if cap(tf.buffer) < len(value) {
+		tf.buffer = make([]byte, len(value))
+	}
+	length := tf.decoder(tf.buffer[:len(value)], value)
+	if length == -1 || !utf8.Valid(tf.buffer[:length]) {
+		tf.counter(record.RawLength)
+		return base.PASS
+	}
+	if length == len(value) && util.StringFromBytes(tf.buffer[:length]) == value { // nothing to decode in URL
+		return base.PASS
+	}
+	var decoded []byte
+	if record.IsValueExclusive(value) {
+		decoded = util.OverwriteNTruncate(util.BytesFromString(value), 0, util.StringFromBytes(tf.buffer[:length]))
+	} else {
+		decoded = make([]byte, length)
+		copy(decoded, tf.buffer[:length])
+	}
+	tf.keyLocator.Set(record.Fields, util.StringFromBytes(decoded))
+	return base.PASS
+}
+
+// newBase64Decoder creates a decoder for the given encoding without padding, to accept values with or without padding
+func newBase64Decoder(encoding *base64.Encoding) decoder {
+	return func(dst []byte, src string) int {
+		n, err := encoding.Decode(dst, util.BytesFromString(strings.TrimRight(src, "=")))
+		if err != nil {
+			return -1
+		}
+		return n
+	}
+}
+
+func decodeHex(dst []byte, src string) int {
+	n, err := hex.Decode(dst, util.BytesFromString(src))
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// decodeURL decodes %XX escapes and '+' as space, the same as url.QueryUnescape
+func decodeURL(dst []byte, src string) int {
+	di := 0
+	for si := 0; si < len(src); si++ {
+		switch c := src[si]; c {
+		case '%':
+			if si+2 >= len(src) {
+				return -1
+			}
+			high, hok := unhex(src[si+1])
+			low, lok := unhex(src[si+2])
+			if !hok || !lok {
+				return -1
+			}
+			dst[di] = high<<4 | low
+			si += 2
+		case '+':
+			dst[di] = ' '
+		default:
+			dst[di] = c
+		}
+		di++
+	}
+	return di
+}
+
+func unhex(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
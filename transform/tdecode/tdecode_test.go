@@ -0,0 +1,114 @@
+package tdecode
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/relex/gotils/logger"
+	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/base/btest"
+	"github.com/relex/slog-agent/transform/tcopyfields"
+	"github.com/relex/slog-agent/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeTransform(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"log"})
+	reg, lookupCounter := btest.NewStubLogCustomCounterRegistry()
+
+	tests := []struct {
+		mode     string
+		input    string
+		expected string
+	}{
+		{"base64", "aGVsbG8gd29ybGQ=", "hello world"},
+		{"base64", "aGVsbG8gd29ybGQ", "hello world"},
+		{"base64", "aGVsbG8*", "aGVsbG8*"},
+		{"base64", "/w==", "/w=="}, // not UTF-8
+		{"base64url", "PDw_Pz8-Pg", "<<???>>"},
+		{"hex", "48656c6c6f", "Hello"},
+		{"hex", "4865z", "4865z"},
+		{"url", "a%20b+c%2Fd", "a b c/d"},
+		{"url", "plain", "plain"},
+		{"url", "100%", "100%"},
+	}
+	for _, test := range tests {
+		c := &Config{}
+		assert.NoError(t, util.UnmarshalYamlString(`
+type: decode
+key: log
+mode: `+test.mode+`
+metricLabel: decode-failed
+`, c))
+		assert.NoError(t, c.VerifyConfig(schema))
+		tf := c.NewTransform(schema, logger.Root(), reg)
+
+		record := schema.NewTestRecord1(base.LogFields{util.StringFromBytes([]byte(test.input))})
+		record.RawLength = len(test.input)
+		assert.Equal(t, base.PASS, tf.Transform(record))
+		assert.Equal(t, test.expected, record.Fields[0], test.mode+": "+test.input)
+	}
+	count, length := lookupCounter("decode-failed")
+	assert.EqualValues(t, 4, count)
+	assert.EqualValues(t, 21, length)
+}
+
+func TestDecodeSharedValue(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"log", "raw", "pad"})
+	reg, _ := btest.NewStubLogCustomCounterRegistry()
+	cc := &tcopyfields.Config{}
+	assert.NoError(t, util.UnmarshalYamlString(`
+type: copyFields
+fields:
+  raw: log
+`, cc))
+	copyTf := cc.NewTransform(schema, logger.Root(), reg)
+	dc := &Config{}
+	assert.NoError(t, util.UnmarshalYamlString(`
+type: decode
+key: log
+mode: base64
+metricLabel: decode-failed
+`, dc))
+	decodeTf := dc.NewTransform(schema, logger.Root(), reg)
+
+	// pooled backing buffer of input, which is only used for long inputs
+	alloc := base.NewLogAllocator(schema, 1)
+	padding := strings.Repeat(" ", 2000)
+	record, input := alloc.NewRecord([]byte("aGVsbG8gd29ybGQ=" + padding))
+	record.Fields[0] = input[:16]
+	record.Fields[2] = input[16:]
+
+	// shared by copyFields
+	assert.Equal(t, base.PASS, copyTf.Transform(record))
+	assert.Equal(t, base.PASS, decodeTf.Transform(record))
+	assert.Equal(t, "hello world", record.Fields[0])
+	assert.Equal(t, "aGVsbG8gd29ybGQ=", record.Fields[1])
+	assert.Equal(t, "aGVsbG8gd29ybGQ=", input[:16])
+
+	// exclusive, decoded in place
+	record.Fields[0] = input[:16]
+	record.Fields[1] = ""
+	assert.Equal(t, base.PASS, decodeTf.Transform(record))
+	assert.Equal(t, "hello world", record.Fields[0])
+	assert.Equal(t, "hello world", input[:11])
+	assert.Equal(t, padding, record.Fields[2])
+
+	// constant
+	record.Fields[0] = "aGVsbG8gd29ybGQ="
+	assert.Equal(t, base.PASS, decodeTf.Transform(record))
+	assert.Equal(t, "hello world", record.Fields[0])
+	alloc.Release(record)
+}
+
+func TestDecodeConfig(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"log"})
+	c := &Config{}
+	assert.NoError(t, util.UnmarshalYamlString(`
+type: decode
+key: log
+mode: base32
+metricLabel: failed
+`, c))
+	assert.EqualError(t, c.VerifyConfig(schema), ".mode 'base32' is invalid, must be one of base64, base64url, hex or url")
+}
@@ -0,0 +1,200 @@
+// Package tdedup provides 'dedup' transform, which suppresses repeated log records identified by a hash of selected
+// fields, e.g. long runs of the same message from flapping connections.
+//
+// The first record of a run is passed immediately and the following duplicates within the window are suppressed. In
+// top-level transformations, the first suppressed duplicate is kept and passed with the count of suppressed records
+// when the run ends: when the window has passed, which is checked once per window on the arrival of any record, when
+// the run is evicted to track other hashes, or when the pipeline stops including for config reloading. The kept record
+// is counted in metrics under the metric keys of the duplicate it's copied from. Elsewhere, e.g. inside "if", the count can only be written to the next
+// duplicate after the window and is lost if there is none.
+//
+// Duplicates are tracked for up to maxKeys different hashes at the same time. If maxKeys is 1, only consecutive
+// duplicates are detected since any different record would end the current run.
+package tdedup
+
+import (
+	"fmt"
+	"hash/maphash"
+	"strconv"
+	"time"
+
+	"github.com/relex/gotils/logger"
+	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/base/bconfig"
+	"github.com/relex/slog-agent/util/lrucache"
+)
+
+// Config for dedupTransform
+type Config struct {
+	bconfig.Header `yaml:",inline"`
+	Keys           []string      `yaml:"keys"`
+	Window         time.Duration `yaml:"window"`
+	MaxKeys        int           `yaml:"maxKeys"`
+	RepeatedKey    string        `yaml:"repeatedKey"`
+	MetricLabel    string        `yaml:"metricLabel"`
+}
+
+type dedupTransform struct {
+	keyLocators     []base.LogFieldLocator
+	repeatedLocator base.LogFieldLocator
+	hasher          maphash.Hash // hasher of random seed, reused for each record
+	runs            *lrucache.LRUCache[uint64, dedupRun]
+	window          time.Duration
+	nextSweep       time.Time         // when to look for runs ended by window
+	ended           []*base.LogRecord // kept records of ended runs to be flushed
+	countSuppressed func(length int)
+	now             func() time.Time
+}
+
+// dedupRun tracks a run of duplicates of the same hash
+type dedupRun struct {
+	start      time.Time
+	suppressed int
+	kept       *base.LogRecord // the first suppressed duplicate, to be passed with the count when the run ends
+}
+
+// NewTransform creates dedupTransform
+func (cfg *Config) NewTransform(schema base.LogSchema, _ logger.Logger, customCounterRegistry base.LogCustomCounterRegistry) base.LogTransform {
+	return newDedupTransform(cfg, schema, customCounterRegistry, time.Now)
+}
+
+// VerifyConfig verifies dedupTransform config
+func (cfg *Config) VerifyConfig(schema base.LogSchema) error {
+	if len(cfg.Keys) == 0 {
+		return fmt.Errorf(".keys is empty")
+	}
+	if _, err := schema.CreateFieldLocators(cfg.Keys); err != nil {
+		return fmt.Errorf(".keys: %w", err)
+	}
+	if cfg.Window <= 0 {
+		return fmt.Errorf(".window must be larger than zero: %s", cfg.Window)
+	}
+	if cfg.MaxKeys <= 0 {
+		return fmt.Errorf(".maxKeys must be larger than zero: %d", cfg.MaxKeys)
+	}
+	if len(cfg.RepeatedKey) == 0 {
+		return fmt.Errorf(".repeatedKey is unspecified")
+	}
+	if _, err := schema.CreateFieldLocator(cfg.RepeatedKey); err != nil {
+		return fmt.Errorf(".repeatedKey '%s' is invalid: %w", cfg.RepeatedKey, err)
+	}
+	if len(cfg.MetricLabel) == 0 {
+		return fmt.Errorf(".metricLabel is unspecified")
+	}
+	return nil
+}
+
+func newDedupTransform(cfg *Config, schema base.LogSchema, customCounterRegistry base.LogCustomCounterRegistry, now func() time.Time) *dedupTransform {
+	return &dedupTransform{
+		keyLocators:     schema.MustCreateFieldLocators(cfg.Keys),
+		repeatedLocator: schema.MustCreateFieldLocator(cfg.RepeatedKey),
+		hasher:          maphash.Hash{},
+		runs:            lrucache.NewLRUCache[uint64, dedupRun](cfg.MaxKeys),
+		window:          cfg.Window,
+		nextSweep:       now().Add(cfg.Window),
+		ended:           nil,
+		countSuppressed: customCounterRegistry.RegisterCustomCounter(cfg.MetricLabel),
+		now:             now,
+	}
+}
+
+func (tf *dedupTransform) Transform(record *base.LogRecord) base.FilterResult {
+	hash := tf.hash(record)
+	now := tf.now()
+	run := tf.runs.Get(hash)
+	if run == nil {
+		tf.runs.Add(hash, dedupRun{start: now, suppressed: 0, kept: nil}, nil)
+		return base.PASS
+	}
+	if now.Sub(run.start) < tf.window {
+		run.suppressed++
+		tf.countSuppressed(record.RawLength)
+		return base.DROP
+	}
+
+	if run.suppressed > 0 {
+		tf.repeatedLocator.Set(record.Fields, strconv.Itoa(run.suppressed))
+	}
+	*run = dedupRun{start: now, suppressed: 0, kept: nil}
+	return base.PASS
+}
+
+// ExpandRecord passes or suppresses the input. Kept records of runs ended here are passed by FlushRecords.
+func (tf *dedupTransform) ExpandRecord(input *base.LogRecord, allocator *base.LogAllocator, outputs []*base.LogRecord) []*base.LogRecord {
+	hash := tf.hash(input)
+	now := tf.now()
+	if !now.Before(tf.nextSweep) {
+		tf.sweep(now)
+	}
+
+	pass := true
+	run := tf.runs.Get(hash)
+	switch {
+	case run == nil:
+		tf.runs.Add(hash, dedupRun{start: now, suppressed: 0, kept: nil}, tf.onEvict)
+	case now.Sub(run.start) < tf.window:
+		run.suppressed++
+		if run.kept == nil {
+			run.kept = allocator.DeriveRecord(input)
+		} else {
+			tf.countSuppressed(input.RawLength)
+		}
+		pass = false
+	default:
+		tf.endRun(run)
+		*run = dedupRun{start: now, suppressed: 0, kept: nil}
+	}
+
+	if pass {
+		outputs = append(outputs, input)
+	}
+	return outputs
+}
+
+// FlushRecords passes kept records of the runs which have ended, or of all runs if final
+func (tf *dedupTransform) FlushRecords(outputs []*base.LogRecord, final bool) []*base.LogRecord {
+	if final {
+		tf.runs.Walk(func(_ uint64, run *dedupRun) {
+			tf.endRun(run)
+			run.suppressed = 0
+		})
+	}
+	outputs = append(outputs, tf.ended...)
+	clear(tf.ended)
+	tf.ended = tf.ended[:0]
+	return outputs
+}
+
+func (tf *dedupTransform) hash(record *base.LogRecord) uint64 {
+	tf.hasher.Reset()
+	for _, loc := range tf.keyLocators {
+		_, _ = tf.hasher.WriteString(loc.Get(record.Fields))
+		_ = tf.hasher.WriteByte(0)
+	}
+	return tf.hasher.Sum64()
+}
+
+// sweep ends all runs whose window has passed
+func (tf *dedupTransform) sweep(now time.Time) {
+	tf.runs.Walk(func(_ uint64, run *dedupRun) {
+		if run.kept != nil && now.Sub(run.start) >= tf.window {
+			tf.endRun(run)
+			run.suppressed = 0
+		}
+	})
+	tf.nextSweep = now.Add(tf.window)
+}
+
+// endRun queues the kept record of the run with the count of suppressed duplicates
+func (tf *dedupTransform) endRun(run *dedupRun) {
+	if run.kept == nil {
+		return
+	}
+	tf.repeatedLocator.Set(run.kept.Fields, strconv.Itoa(run.suppressed))
+	tf.ended = append(tf.ended, run.kept)
+	run.kept = nil
+}
+
+func (tf *dedupTransform) onEvict(_ uint64, run *dedupRun) {
+	tf.endRun(run)
+}
@@ -0,0 +1,155 @@
+package tdedup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/base/btest"
+	"github.com/relex/slog-agent/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDedupTransform(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"class", "log", "repeated"})
+	c := &Config{}
+	if !assert.NoError(t, util.UnmarshalYamlString(`
+type: dedup
+keys: [class, log]
+window: 10s
+maxKeys: 3
+repeatedKey: repeated
+metricLabel: dedup
+`, c)) {
+		return
+	}
+	if !assert.NoError(t, c.VerifyConfig(schema)) {
+		return
+	}
+	reg, lookup := btest.NewStubLogCustomCounterRegistry()
+	now := time.Date(2022, 8, 15, 3, 48, 20, 0, time.UTC)
+	tf := newDedupTransform(c, schema, reg, func() time.Time { return now })
+
+	run := func(class string, log string) (base.FilterResult, string) {
+		r := schema.NewTestRecord1(base.LogFields{class, log, ""})
+		r.RawLength = 10
+		result := tf.Transform(r)
+		return result, r.Fields[2]
+	}
+	assertResult := func(expectedResult base.FilterResult, expectedRepeated string, class string, log string) {
+		result, repeated := run(class, log)
+		assert.Equal(t, expectedResult, result, class+"|"+log)
+		assert.Equal(t, expectedRepeated, repeated, class+"|"+log)
+	}
+
+	assertResult(base.PASS, "", "A", "connection lost")
+	assertResult(base.DROP, "", "A", "connection lost")
+	assertResult(base.PASS, "", "A", "connection restored")
+	assertResult(base.DROP, "", "A", "connection lost") // windowed duplicate
+	assertResult(base.PASS, "", "Aconnection", " lost")
+	now = now.Add(5 * time.Second)
+	assertResult(base.DROP, "", "A", "connection restored")
+
+	now = now.Add(6 * time.Second)
+	assertResult(base.PASS, "1", "A", "connection restored")
+	assertResult(base.DROP, "", "A", "connection restored")
+
+	assertResult(base.PASS, "2", "A", "connection lost")
+
+	// "Aconnection| lost" is evicted and starts a new run
+	assertResult(base.PASS, "", "B", "connection lost")
+	assertResult(base.PASS, "", "Aconnection", " lost")
+
+	count, length := lookup("dedup")
+	assert.Equal(t, int64(4), count)
+	assert.Equal(t, int64(40), length)
+}
+
+func TestDedupTransformConsecutive(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"log", "repeated"})
+	c := &Config{}
+	assert.NoError(t, util.UnmarshalYamlString("type: dedup\nkeys: [log]\nwindow: 1m\nmaxKeys: 1\nrepeatedKey: repeated\nmetricLabel: dedup", c))
+	reg, _ := btest.NewStubLogCustomCounterRegistry()
+	now := time.Date(2022, 8, 15, 3, 48, 20, 0, time.UTC)
+	tf := newDedupTransform(c, schema, reg, func() time.Time { return now })
+
+	assert.Equal(t, base.PASS, tf.Transform(schema.NewTestRecord1(base.LogFields{"foo", ""})))
+	assert.Equal(t, base.DROP, tf.Transform(schema.NewTestRecord1(base.LogFields{"foo", ""})))
+	assert.Equal(t, base.PASS, tf.Transform(schema.NewTestRecord1(base.LogFields{"bar", ""})))
+	assert.Equal(t, base.PASS, tf.Transform(schema.NewTestRecord1(base.LogFields{"foo", ""})))
+}
+
+func TestDedupTransformExpandEndedRun(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"log", "seq", "repeated"})
+	c := &Config{}
+	assert.NoError(t, util.UnmarshalYamlString("type: dedup\nkeys: [log]\nwindow: 10s\nmaxKeys: 10\nrepeatedKey: repeated\nmetricLabel: dedup", c))
+	reg, lookup := btest.NewStubLogCustomCounterRegistry()
+	allocator := base.NewLogAllocator(schema, 1)
+	now := time.Date(2022, 8, 15, 3, 48, 20, 0, time.UTC)
+	tf := newDedupTransform(c, schema, reg, func() time.Time { return now })
+
+	expand := func(log string, seq string) []base.LogFields {
+		outputs := tf.ExpandRecord(schema.NewTestRecord1(base.LogFields{log, seq, ""}), allocator, nil)
+		outputs = tf.FlushRecords(outputs, false)
+		fieldsList := make([]base.LogFields, len(outputs))
+		for i, r := range outputs {
+			fieldsList[i] = r.Fields
+		}
+		return fieldsList
+	}
+
+	assert.Equal(t, []base.LogFields{{"foo", "1", ""}}, expand("foo", "1"))
+	assert.Empty(t, expand("foo", "2"))
+	assert.Empty(t, expand("foo", "3"))
+	assert.Empty(t, expand("foo", "4"))
+
+	// "foo" never recurs; its run ends on the first record after the window
+	now = now.Add(10 * time.Second)
+	assert.Equal(t, []base.LogFields{{"bar", "5", ""}, {"foo", "2", "3"}}, expand("bar", "5"))
+	assert.Empty(t, expand("bar", "6"))
+
+	// "foo" starts a new run and the kept "bar" is passed at the next sweep
+	now = now.Add(10 * time.Second)
+	assert.Equal(t, []base.LogFields{{"foo", "7", ""}, {"bar", "6", "1"}}, expand("foo", "7"))
+	assert.Empty(t, expand("foo", "8"))
+	assert.Empty(t, expand("foo", "9"))
+
+	// the ongoing run of "foo" is passed when the pipeline stops
+	flushed := tf.FlushRecords(nil, true)
+	if assert.Len(t, flushed, 1) {
+		assert.Equal(t, base.LogFields{"foo", "8", "2"}, flushed[0].Fields)
+	}
+	assert.Empty(t, tf.FlushRecords(nil, true))
+
+	count, _ := lookup("dedup")
+	assert.Equal(t, int64(3), count)
+}
+
+func TestDedupTransformExpandEvictedRun(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"log", "repeated"})
+	c := &Config{}
+	assert.NoError(t, util.UnmarshalYamlString("type: dedup\nkeys: [log]\nwindow: 1m\nmaxKeys: 1\nrepeatedKey: repeated\nmetricLabel: dedup", c))
+	reg, _ := btest.NewStubLogCustomCounterRegistry()
+	allocator := base.NewLogAllocator(schema, 1)
+	now := time.Date(2022, 8, 15, 3, 48, 20, 0, time.UTC)
+	tf := newDedupTransform(c, schema, reg, func() time.Time { return now })
+
+	expand := func(log string) []base.LogFields {
+		outputs := tf.ExpandRecord(schema.NewTestRecord1(base.LogFields{log, ""}), allocator, nil)
+		outputs = tf.FlushRecords(outputs, false)
+		fieldsList := make([]base.LogFields, len(outputs))
+		for i, r := range outputs {
+			fieldsList[i] = r.Fields
+		}
+		return fieldsList
+	}
+
+	// alternating messages: each run is evicted by the other one
+	assert.Equal(t, []base.LogFields{{"foo", ""}}, expand("foo"))
+	assert.Empty(t, expand("foo"))
+	assert.Empty(t, expand("foo"))
+	assert.Equal(t, []base.LogFields{{"bar", ""}, {"foo", "2"}}, expand("bar"))
+	assert.Empty(t, expand("bar"))
+	assert.Equal(t, []base.LogFields{{"foo", ""}, {"bar", "1"}}, expand("foo"))
+	assert.Equal(t, []base.LogFields{{"bar", ""}}, expand("bar"))
+}
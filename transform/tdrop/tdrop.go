@@ -47,7 +47,7 @@ func (cfg *Config) NewTransform(schema base.LogSchema, _ logger.Logger, customCo
 
 // VerifyConfig verifies dropTransform config
 func (cfg *Config) VerifyConfig(schema base.LogSchema) error {
-	if len(cfg.Match) == 0 {
+	if cfg.Match.IsEmpty() {
 		return fmt.Errorf(".match is empty")
 	}
 	if err := cfg.Match.VerifyConfig(schema); err != nil {
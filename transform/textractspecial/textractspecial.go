@@ -8,6 +8,7 @@ import (
 	"github.com/relex/gotils/logger"
 	"github.com/relex/slog-agent/base"
 	"github.com/relex/slog-agent/base/bconfig"
+	"github.com/relex/slog-agent/util/stringextract"
 )
 
 // Config for extractSpecialTransform
@@ -22,13 +23,13 @@ type Config struct {
 type extractSpecialTransform struct {
 	schema      base.LogSchema
 	srcLocator  base.LogFieldLocator
-	extractor   stringExtractor
+	extractor   stringextract.Extractor
 	destLocator base.LogFieldLocator
 }
 
 // NewTransform creates extractSpecialTransform
 func (c *Config) NewTransform(schema base.LogSchema, _ logger.Logger, _ base.LogCustomCounterRegistry) base.LogTransform {
-	ex, err := newStringExtractorSimple(c.getPosition(), c.Pattern, c.MaxLength)
+	ex, err := stringextract.NewExtractor(c.getPosition(), c.Pattern, c.MaxLength)
 	if err != nil {
 		panic(err)
 	}
@@ -51,7 +52,7 @@ func (c *Config) VerifyConfig(schema base.LogSchema) error {
 	if len(c.Pattern) == 0 {
 		return fmt.Errorf(".pattern is unspecified")
 	}
-	if _, err := splitPattern(c.Pattern); err != nil {
+	if _, err := stringextract.NewExtractor(c.getPosition(), c.Pattern, c.MaxLength); err != nil {
 		return fmt.Errorf(".pattern is invalid: %w", err)
 	}
 	if c.MaxLength <= 0 {
@@ -66,12 +67,12 @@ func (c *Config) VerifyConfig(schema base.LogSchema) error {
 	return nil
 }
 
-func (c *Config) getPosition() stringExtractorPosition {
+func (c *Config) getPosition() stringextract.Position {
 	switch c.Type {
 	case "extractHead":
-		return extractFromStart
+		return stringextract.FromStart
 	case "extractTail":
-		return extractFromEnd
+		return stringextract.FromEnd
 	default:
 		panic(fmt.Sprintf("unsupported position type '%s'", c.Type))
 	}
@@ -0,0 +1,159 @@
+// Package tfingerprint provides 'fingerprint' transform, which computes a short ID of messages containing stack traces,
+// so that the same errors can be grouped by downstream dedup or alerting regardless of variable parts.
+//
+// Messages are normalized by replacing all words (runs of ASCII letters and digits) containing digits, or made of hex
+// digits only and at least 8 long, by a single placeholder, which covers numbers, hex addresses and hashes, UUIDs and
+// line numbers in stack frames, before being hashed by 64-bit FNV-1a. The normalized message is never built in memory.
+//
+// Messages without any of the stack trace markers are skipped, which costs only substring searches.
+package tfingerprint
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/relex/gotils/logger"
+	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/base/bconfig"
+)
+
+// Config for fingerprintTransform
+type Config struct {
+	bconfig.Header `yaml:",inline"`
+	Key            string   `yaml:"key"`
+	DestKey        string   `yaml:"destKey"`
+	Markers        []string `yaml:"markers"` // substrings to detect stack traces, see defaultMarkers
+}
+
+type fingerprintTransform struct {
+	keyLocator  base.LogFieldLocator
+	destLocator base.LogFieldLocator
+	markers     []string
+}
+
+// defaultMarkers detects stack frames of Java, .NET and Python, as raw text or escaped in syslog
+var defaultMarkers = []string{
+	"\tat ",
+	`\tat `,
+	"\n   at ",
+	`\n   at `,
+	"Traceback (most recent call last):",
+}
+
+const (
+	fnvOffset64 = 14695981039346656037
+	fnvPrime64  = 1099511628211
+	placeholder = '#'
+	hexDigits   = "0123456789abcdef"
+
+	minHexWordLength = 8 // min length of words of hex letters only to be replaced, e.g. "deadbeef" but not "face"
+)
+
+// NewTransform creates fingerprintTransform
+func (c *Config) NewTransform(schema base.LogSchema, _ logger.Logger, _ base.LogCustomCounterRegistry) base.LogTransform {
+	markers := c.Markers
+	if len(markers) == 0 {
+		markers = defaultMarkers
+	}
+	return &fingerprintTransform{
+		keyLocator:  schema.MustCreateFieldLocator(c.Key),
+		destLocator: schema.MustCreateFieldLocator(c.DestKey),
+		markers:     markers,
+	}
+}
+
+// VerifyConfig verifies fingerprintTransform config
+func (c *Config) VerifyConfig(schema base.LogSchema) error {
+	if len(c.Key) == 0 {
+		return fmt.Errorf(".key is unspecified")
+	}
+	if _, err := schema.CreateFieldLocator(c.Key); err != nil {
+		return fmt.Errorf(".key '%s' is invalid: %w", c.Key, err)
+	}
+	if len(c.DestKey) == 0 {
+		return fmt.Errorf(".destKey is unspecified")
+	}
+	if _, err := schema.CreateFieldLocator(c.DestKey); err != nil {
+		return fmt.Errorf(".destKey '%s' is invalid: %w", c.DestKey, err)
+	}
+	for i, marker := range c.Markers {
+		if len(marker) == 0 {
+			return fmt.Errorf(".markers[%d] is empty", i)
+		}
+	}
+	return nil
+}
+
+func (tf *fingerprintTransform) Transform(record *base.LogRecord) base.FilterResult {
+	value := tf.keyLocator.Get(record.Fields)
+	if !tf.hasStackTrace(value) {
+		return base.PASS
+	}
+	tf.destLocator.Set(record.Fields, formatHash(hashNormalized(value)))
+	return base.PASS
+}
+
+func (tf *fingerprintTransform) hasStackTrace(value string) bool {
+	for _, marker := range tf.markers {
+		if strings.Contains(value, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// hashNormalized hashes the value as if all words containing digits or long enough of hex digits only were replaced by
+// placeholders
+func hashNormalized(value string) uint64 {
+	var hash uint64 = fnvOffset64
+	wordStart := -1
+	wordHasDigit := false
+	wordAllHex := true
+	flushWord := func(end int) {
+		if wordHasDigit || (wordAllHex && end-wordStart >= minHexWordLength) {
+			hash = (hash ^ placeholder) * fnvPrime64
+		} else {
+			for i := wordStart; i < end; i++ {
+				hash = (hash ^ uint64(value[i])) * fnvPrime64
+			}
+		}
+		wordStart = -1
+		wordHasDigit = false
+		wordAllHex = true
+	}
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c >= '0' && c <= '9':
+			if wordStart < 0 {
+				wordStart = i
+			}
+			wordHasDigit = true
+		case (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+			if wordStart < 0 {
+				wordStart = i
+			}
+			if c|0x20 > 'f' { // letter after f in either case
+				wordAllHex = false
+			}
+		default:
+			if wordStart >= 0 {
+				flushWord(i)
+			}
+			hash = (hash ^ uint64(c)) * fnvPrime64
+		}
+	}
+	if wordStart >= 0 {
+		flushWord(len(value))
+	}
+	return hash
+}
+
+func formatHash(hash uint64) string {
+	var buf [16]byte
+	for i := len(buf) - 1; i >= 0; i-- {
+		buf[i] = hexDigits[hash&0xf]
+		hash >>= 4
+	}
+	return string(buf[:])
+}
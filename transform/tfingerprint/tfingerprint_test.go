@@ -0,0 +1,91 @@
+package tfingerprint
+
+import (
+	"testing"
+
+	"github.com/relex/gotils/logger"
+	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/util"
+	"github.com/stretchr/testify/assert"
+)
+
+const testJavaTrace1 = `java.lang.IllegalStateException: Invalid session 123e4567-e89b-12d3-a456-426614174000
+	at com.example.SessionManager.check(SessionManager.java:42)
+	at com.example.Handler$1.run(Handler.java:118)
+	at java.lang.Thread.run(Thread.java:829)`
+
+const testJavaTrace2 = `java.lang.IllegalStateException: Invalid session 00000000-1111-2222-3333-444444444444
+	at com.example.SessionManager.check(SessionManager.java:45)
+	at com.example.Handler$2.run(Handler.java:120)
+	at java.lang.Thread.run(Thread.java:829)`
+
+const testJavaTrace3 = `java.lang.NullPointerException: Invalid session 123e4567-e89b-12d3-a456-426614174000
+	at com.example.SessionManager.check(SessionManager.java:42)
+	at com.example.Handler$1.run(Handler.java:118)
+	at java.lang.Thread.run(Thread.java:829)`
+
+func TestFingerprintTransform(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"log", "fingerprint"})
+	c := &Config{}
+	assert.NoError(t, util.UnmarshalYamlString(`
+type: fingerprint
+key: log
+destKey: fingerprint
+`, c))
+	assert.NoError(t, c.VerifyConfig(schema))
+	tf := c.NewTransform(schema, logger.Root(), nil)
+
+	run := func(message string) string {
+		record := schema.NewTestRecord1(base.LogFields{message, ""})
+		assert.Equal(t, base.PASS, tf.Transform(record))
+		return record.Fields[1]
+	}
+
+	fp1 := run(testJavaTrace1)
+	assert.Len(t, fp1, 16)
+	assert.Equal(t, fp1, run(testJavaTrace2))
+	assert.NotEqual(t, fp1, run(testJavaTrace3))
+
+	// escaped in syslog
+	assert.Equal(t, "50b6ce7573dfdf90", run(`System.Exception: Failed at 0x7ffe2c1d\n   at Foo.Bar() in C:\src\Foo.cs:line 42`))
+	assert.Equal(t, "50b6ce7573dfdf90", run(`System.Exception: Failed at 0x0000beef\n   at Foo.Bar() in C:\src\Foo.cs:line 7`))
+
+	assert.Equal(t, "", run("User 123 logged in"))
+}
+
+func TestFingerprintTransformNoAllocation(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"log", "fingerprint"})
+	c := &Config{}
+	assert.NoError(t, util.UnmarshalYamlString(`
+type: fingerprint
+key: log
+destKey: fingerprint
+`, c))
+	tf := c.NewTransform(schema, logger.Root(), nil)
+	record := schema.NewTestRecord1(base.LogFields{"GET /index.html 200 0.005", ""})
+	assert.Zero(t, testing.AllocsPerRun(100, func() {
+		tf.Transform(record)
+	}))
+}
+
+func TestHashNormalized(t *testing.T) {
+	assert.Equal(t, hashNormalized("at Foo.java:#)"), hashNormalized("at Foo.java:42)"))
+	assert.Equal(t, hashNormalized("# #-#"), hashNormalized("0x7f9594d83ae0 a1-b2"))
+	assert.NotEqual(t, hashNormalized("at Foo.java:42)"), hashNormalized("at Bar.java:42)"))
+	assert.Equal(t, hashNormalized("object # at #"), hashNormalized("object deadbeef at 0xCAFEBABE"))
+	assert.Equal(t, hashNormalized("commit #"), hashNormalized("commit fedcbaABCDEFabcdef"))
+	assert.NotEqual(t, hashNormalized("# added"), hashNormalized("decade added"), "short words of hex letters are kept")
+	assert.NotEqual(t, hashNormalized("#"), hashNormalized("deadbeefs"), "words with other letters are kept")
+	assert.Equal(t, "0000000000000001", formatHash(1))
+}
+
+func BenchmarkFingerprintTransform(b *testing.B) {
+	schema := base.MustNewLogSchema([]string{"log", "fingerprint"})
+	c := &Config{Key: "log", DestKey: "fingerprint"}
+	tf := c.NewTransform(schema, logger.Root(), nil)
+	record := schema.NewTestRecord1(base.LogFields{testJavaTrace1, ""})
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tf.Transform(record)
+	}
+}
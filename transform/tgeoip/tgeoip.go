@@ -0,0 +1,246 @@
+// Package tgeoip provides 'geoip' transform, which enriches log records with the location and network owner of an IP
+// address field, looked up from a local MaxMind database (.mmdb), e.g. GeoLite2-City or GeoLite2-ASN.
+//
+// The database file is checked periodically and reloaded in background if changed. Results are cached per worker for
+// hot IPs, and the cache is cleared when a new database is picked up.
+package tgeoip
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/oschwald/maxminddb-golang"
+	"github.com/relex/gotils/logger"
+	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/base/bconfig"
+	"github.com/relex/slog-agent/util/lrucache"
+	"github.com/relex/slog-agent/util/watchedfile"
+	"github.com/samber/lo"
+)
+
+// Config for geoipTransform
+type Config struct {
+	bconfig.Header `yaml:",inline"`
+	Path           string            `yaml:"path"`
+	Key            string            `yaml:"key"`
+	Fields         map[string]string `yaml:"fields"`    // destination field to attribute, see attributeGetters
+	CacheSize      int               `yaml:"cacheSize"` // default 1000
+	ErrorLabel     string            `yaml:"errorLabel"`
+}
+
+type geoipTransform struct {
+	keyLocator   base.LogFieldLocator
+	destLocators []base.LogFieldLocator
+	getters      []attributeGetter
+	database     *watchedfile.Value[geoipDatabase]
+	lastDatabase *geoipDatabase // database used by cached results
+	cache        *lrucache.LRUCache[netip.Addr, []string]
+	ipBuffer     [16]byte
+	result       geoipRecord
+	logger       logger.Logger
+	errorCounter func(length int)
+	errorLogged  bool // whether a lookup error has been logged for lastDatabase
+}
+
+// geoipDatabase wraps an in-memory database reader, which is safe to be dropped at any time for garbage collection
+type geoipDatabase struct {
+	reader *maxminddb.Reader
+}
+
+// geoipRecord contains the attributes to decode from City, Country or ASN databases
+type geoipRecord struct {
+	Continent struct {
+		Code string `maxminddb:"code"`
+	} `maxminddb:"continent"`
+	Country struct {
+		ISOCode string            `maxminddb:"iso_code"`
+		Names   map[string]string `maxminddb:"names"`
+	} `maxminddb:"country"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	ASN    uint   `maxminddb:"autonomous_system_number"`
+	ASNOrg string `maxminddb:"autonomous_system_organization"`
+}
+
+type attributeGetter func(record *geoipRecord) string
+
+var attributeGetters = map[string]attributeGetter{
+	"continent":   func(r *geoipRecord) string { return r.Continent.Code },
+	"country":     func(r *geoipRecord) string { return r.Country.ISOCode },
+	"countryName": func(r *geoipRecord) string { return r.Country.Names["en"] },
+	"city":        func(r *geoipRecord) string { return r.City.Names["en"] },
+	"asn": func(r *geoipRecord) string {
+		if r.ASN == 0 {
+			return ""
+		}
+		return strconv.FormatUint(uint64(r.ASN), 10)
+	},
+	"asnOrg": func(r *geoipRecord) string { return r.ASNOrg },
+}
+
+const defaultCacheSize = 1000
+
+// NewTransform creates geoipTransform
+func (c *Config) NewTransform(schema base.LogSchema, parentLogger logger.Logger, customCounterRegistry base.LogCustomCounterRegistry) base.LogTransform {
+	destFields, attributes := c.getSortedFieldsAndAttributes()
+	database, err := watchedfile.Watch("geoip:"+c.Path, c.Path, loadDatabase)
+	if err != nil {
+		logger.Panicf("failed to load geoip database '%s': %s", c.Path, err.Error())
+	}
+	cacheSize := c.CacheSize
+	if cacheSize == 0 {
+		cacheSize = defaultCacheSize
+	}
+	return &geoipTransform{
+		keyLocator:   schema.MustCreateFieldLocator(c.Key),
+		destLocators: schema.MustCreateFieldLocators(destFields),
+		getters: lo.Map(attributes, func(attr string, _ int) attributeGetter {
+			return attributeGetters[attr]
+		}),
+		database:     database,
+		cache:        lrucache.NewLRUCache[netip.Addr, []string](cacheSize),
+		logger:       parentLogger.WithField("path", c.Path),
+		errorCounter: customCounterRegistry.RegisterCustomCounter(c.ErrorLabel),
+		errorLogged:  false,
+	}
+}
+
+// VerifyConfig verifies geoipTransform config
+func (c *Config) VerifyConfig(schema base.LogSchema) error {
+	if len(c.Path) == 0 {
+		return fmt.Errorf(".path is unspecified")
+	}
+	if len(c.Key) == 0 {
+		return fmt.Errorf(".key is unspecified")
+	}
+	if _, err := schema.CreateFieldLocator(c.Key); err != nil {
+		return fmt.Errorf(".key: %w", err)
+	}
+	if len(c.Fields) == 0 {
+		return fmt.Errorf(".fields is empty")
+	}
+	for dstKey, attr := range c.Fields {
+		if _, err := schema.CreateFieldLocator(dstKey); err != nil {
+			return fmt.Errorf(".fields[%s] is invalid: %w", dstKey, err)
+		}
+		if _, ok := attributeGetters[attr]; !ok {
+			return fmt.Errorf(".fields[%s] has unknown attribute '%s'", dstKey, attr)
+		}
+	}
+	if c.CacheSize < 0 {
+		return fmt.Errorf(".cacheSize cannot be negative")
+	}
+	if len(c.ErrorLabel) == 0 {
+		return fmt.Errorf(".errorLabel is unspecified")
+	}
+	if _, err := loadDatabase(c.Path); err != nil {
+		return fmt.Errorf(".path '%s': %w", c.Path, err)
+	}
+	return nil
+}
+
+// getSortedFieldsAndAttributes returns destination fields and their attributes in a stable order
+func (c *Config) getSortedFieldsAndAttributes() ([]string, []string) {
+	destFields := lo.Keys(c.Fields)
+	sort.Strings(destFields)
+	attributes := lo.Map(destFields, func(field string, _ int) string {
+		return c.Fields[field]
+	})
+	return destFields, attributes
+}
+
+// loadDatabase reads the whole database into memory, instead of mmap which requires explicit closing
+func loadDatabase(path string) (geoipDatabase, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return geoipDatabase{}, err
+	}
+	reader, rerr := maxminddb.FromBytes(data)
+	if rerr != nil {
+		return geoipDatabase{}, rerr
+	}
+	return geoipDatabase{reader: reader}, nil
+}
+
+func (tf *geoipTransform) Transform(record *base.LogRecord) base.FilterResult {
+	fields := record.Fields
+	addr, perr := netip.ParseAddr(tf.keyLocator.Get(fields))
+	if perr != nil {
+		return base.PASS
+	}
+	addr = addr.Unmap().WithZone("")
+
+	database := tf.database.Get()
+	if database != tf.lastDatabase {
+		tf.cache.Clear()
+		tf.lastDatabase = database
+		tf.errorLogged = false
+	}
+
+	var values []string
+	if cached := tf.cache.Get(addr); cached != nil {
+		values = *cached
+	} else {
+		var err error
+		values, err = tf.lookup(database, addr)
+		if err != nil {
+			tf.errorCounter(record.RawLength)
+			if !tf.errorLogged {
+				tf.logger.Warnf("failed to look up '%s', further errors are only counted: %s", addr, err.Error())
+				tf.errorLogged = true
+			}
+		}
+		tf.cache.Add(addr, values, nil)
+	}
+	if values == nil {
+		return base.PASS
+	}
+	for i, loc := range tf.destLocators {
+		if value := values[i]; len(value) > 0 {
+			loc.Set(fields, value)
+		}
+	}
+	return base.PASS
+}
+
+// Close releases the watched database
+func (tf *geoipTransform) Close() {
+	tf.database.Release()
+}
+
+// lookup returns the values of destination fields for the given address, or nil if not found
+func (tf *geoipTransform) lookup(database *geoipDatabase, addr netip.Addr) ([]string, error) {
+	if addr.Is6() && database.reader.Metadata.IPVersion == 4 {
+		return nil, nil
+	}
+	var ip net.IP
+	if addr.Is4() {
+		ip4 := addr.As4()
+		ip = tf.ipBuffer[:copy(tf.ipBuffer[:], ip4[:])]
+	} else {
+		ip16 := addr.As16()
+		ip = tf.ipBuffer[:copy(tf.ipBuffer[:], ip16[:])]
+	}
+
+	tf.result = geoipRecord{}
+	offset, err := database.reader.LookupOffset(ip)
+	if err == nil && offset != maxminddb.NotFound {
+		err = database.reader.Decode(offset, &tf.result)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if offset == maxminddb.NotFound {
+		return nil, nil
+	}
+	values := make([]string, len(tf.getters))
+	for i, getter := range tf.getters {
+		values[i] = getter(&tf.result)
+	}
+	return values, nil
+}
@@ -0,0 +1,123 @@
+package tgeoip
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/relex/gotils/logger"
+	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/base/btest"
+	"github.com/relex/slog-agent/defs"
+	"github.com/relex/slog-agent/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGeoIPTransform(t *testing.T) {
+	defs.WatchedFileCheckInterval = 20 * time.Millisecond
+	// test-city.mmdb: 81.2.69.0/24 in London, 2001:218::/32 in Japan (no city), 89.160.20.0/24 in Linköping
+	path := filepath.Join(t.TempDir(), "test-city.mmdb")
+	copyTestDatabase(t, "testdata/test-city.mmdb", path)
+
+	schema := base.MustNewLogSchema([]string{"ip", "continent", "country", "countryName", "city"})
+	c := &Config{}
+	if !assert.NoError(t, util.UnmarshalYamlString(fmt.Sprintf(`
+type: geoip
+path: %s
+key: ip
+fields:
+  continent: continent
+  country: country
+  countryName: countryName
+  city: city
+cacheSize: 2
+errorLabel: geoipError
+`, path), c)) {
+		return
+	}
+	if !assert.NoError(t, c.VerifyConfig(schema)) {
+		return
+	}
+	reg, lookup := btest.NewStubLogCustomCounterRegistry()
+	tf := c.NewTransform(schema, logger.Root(), reg)
+	defer tf.(base.LogTransformCloser).Close()
+	for i := 0; i < 2; i++ { // second round from cache
+		{
+			record := schema.NewTestRecord1(base.LogFields{"81.2.69.142", "", "", "", ""})
+			assert.Equal(t, base.PASS, tf.Transform(record))
+			assert.Equal(t, base.LogFields{"81.2.69.142", "EU", "GB", "United Kingdom", "London"}, record.Fields)
+		}
+		{
+			record := schema.NewTestRecord1(base.LogFields{"2001:218:85a3::8a2e:370:7334", "", "", "", "old"})
+			assert.Equal(t, base.PASS, tf.Transform(record))
+			assert.Equal(t, base.LogFields{"2001:218:85a3::8a2e:370:7334", "AS", "JP", "Japan", "old"}, record.Fields)
+		}
+		{
+			record := schema.NewTestRecord1(base.LogFields{"::ffff:89.160.20.112", "", "", "", ""})
+			assert.Equal(t, base.PASS, tf.Transform(record))
+			assert.Equal(t, base.LogFields{"::ffff:89.160.20.112", "EU", "SE", "Sweden", "Linköping"}, record.Fields)
+		}
+		{
+			record := schema.NewTestRecord1(base.LogFields{"10.0.0.1", "", "", "", ""})
+			assert.Equal(t, base.PASS, tf.Transform(record))
+			assert.Equal(t, base.LogFields{"10.0.0.1", "", "", "", ""}, record.Fields)
+		}
+		{
+			record := schema.NewTestRecord1(base.LogFields{"not-an-ip", "", "", "", ""})
+			assert.Equal(t, base.PASS, tf.Transform(record))
+			assert.Equal(t, base.LogFields{"not-an-ip", "", "", "", ""}, record.Fields)
+		}
+	}
+
+	errorCount, _ := lookup("geoipError")
+	assert.Equal(t, int64(0), errorCount)
+
+	// test-city-updated.mmdb: 81.2.69.0/24 in Boxford
+	newPath := path + ".new"
+	copyTestDatabase(t, "testdata/test-city-updated.mmdb", newPath)
+	assert.NoError(t, os.Rename(newPath, path))
+	assert.Eventually(t, func() bool {
+		record := schema.NewTestRecord1(base.LogFields{"81.2.69.142", "", "", "", ""})
+		tf.Transform(record)
+		return record.Fields[4] == "Boxford"
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestGeoIPTransformASN(t *testing.T) {
+	// test-asn.mmdb: 1.128.0.0/11 of AS1221 Telstra Pty Ltd
+	path := "testdata/test-asn.mmdb"
+
+	schema := base.MustNewLogSchema([]string{"ip", "asn", "org"})
+	c := &Config{}
+	assert.NoError(t, util.UnmarshalYamlString(fmt.Sprintf("type: geoip\npath: %s\nkey: ip\nfields: {asn: asn, org: asnOrg}\nerrorLabel: geoipError", path), c))
+	if !assert.NoError(t, c.VerifyConfig(schema)) {
+		return
+	}
+	reg, _ := btest.NewStubLogCustomCounterRegistry()
+	tf := c.NewTransform(schema, logger.Root(), reg)
+	defer tf.(base.LogTransformCloser).Close()
+	record := schema.NewTestRecord1(base.LogFields{"1.128.0.1", "", ""})
+	assert.Equal(t, base.PASS, tf.Transform(record))
+	assert.Equal(t, base.LogFields{"1.128.0.1", "1221", "Telstra Pty Ltd"}, record.Fields)
+
+	c.Fields = map[string]string{"asn": "zip"}
+	assert.EqualError(t, c.VerifyConfig(schema), ".fields[asn] has unknown attribute 'zip'")
+	c.Fields = map[string]string{"asn": "asn"}
+	c.ErrorLabel = ""
+	assert.EqualError(t, c.VerifyConfig(schema), ".errorLabel is unspecified")
+	c.ErrorLabel = "geoipError"
+	c.Path += ".missing"
+	assert.ErrorContains(t, c.VerifyConfig(schema), "no such file")
+}
+
+func copyTestDatabase(t *testing.T, srcPath string, dstPath string) {
+	data, err := os.ReadFile(srcPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dstPath, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
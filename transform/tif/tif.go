@@ -1,4 +1,5 @@
-// Package tif provides 'if' transform, performing optional steps if the given conditions are satisfied
+// Package tif provides 'if' transform, performing optional steps if the given conditions are satisfied, or other
+// optional steps if not
 package tif
 
 import (
@@ -16,24 +17,33 @@ type Config struct {
 	bconfig.Header `yaml:",inline"`
 	Match          bmatch.LogMatcherConfig            `yaml:"match"`
 	Then           []bconfig.LogTransformConfigHolder `yaml:"then"`
+	Else           []bconfig.LogTransformConfigHolder `yaml:"else,omitempty"`
 }
 
 type ifTransform struct {
 	matcher   bmatch.LogMatcher
 	thenSteps []base.LogTransformFunc
+	elseSteps []base.LogTransformFunc // optional
+	closeThen func()
+	closeElse func()
 }
 
 // NewTransform creates ifTransform
 func (c *Config) NewTransform(schema base.LogSchema, parentLogger logger.Logger, customCounterRegistry base.LogCustomCounterRegistry) base.LogTransform {
+	thenSteps, closeThen := bsupport.NewTransformsFromConfig(c.Then, schema, parentLogger, customCounterRegistry)
+	elseSteps, closeElse := bsupport.NewTransformsFromConfig(c.Else, schema, parentLogger, customCounterRegistry)
 	return &ifTransform{
 		matcher:   c.Match.NewMatcher(schema),
-		thenSteps: bsupport.NewTransformsFromConfig(c.Then, schema, parentLogger, customCounterRegistry),
+		thenSteps: thenSteps,
+		elseSteps: elseSteps,
+		closeThen: closeThen,
+		closeElse: closeElse,
 	}
 }
 
 // VerifyConfig verifies ifTransform config
 func (c *Config) VerifyConfig(schema base.LogSchema) error {
-	if len(c.Match) == 0 {
+	if c.Match.IsEmpty() {
 		return fmt.Errorf(".match is empty")
 	}
 	if err := c.Match.VerifyConfig(schema); err != nil {
@@ -43,12 +53,20 @@ func (c *Config) VerifyConfig(schema base.LogSchema) error {
 		return fmt.Errorf(".then is empty")
 	}
 
-	return bsupport.VerifyTransformConfigs(c.Then, schema, ".then")
+	if err := bsupport.VerifyTransformConfigs(c.Then, schema, ".then"); err != nil {
+		return err
+	}
+	return bsupport.VerifyTransformConfigs(c.Else, schema, ".else")
 }
 
 func (tf *ifTransform) Transform(record *base.LogRecord) base.FilterResult {
 	if tf.matcher.Match(record) {
 		return bsupport.RunTransforms(record, tf.thenSteps)
 	}
-	return base.PASS
+	return bsupport.RunTransforms(record, tf.elseSteps)
+}
+
+func (tf *ifTransform) Close() {
+	tf.closeThen()
+	tf.closeElse()
 }
@@ -52,4 +52,33 @@ then:
 			assert.Equal(t, "", record.Fields[iFruit])
 		}
 	}
+	{
+		c := &Config{}
+		assert.NoError(t, util.UnmarshalYamlString(`
+type: if
+match:
+  any:
+    - type: Fruit
+    - name: Tomato
+then:
+  - type: addFields
+    fields:
+      fruit: $name
+else:
+  - type: delFields
+    keys: [name]
+`, c))
+		assert.NoError(t, c.VerifyConfig(schema))
+		tf := c.NewTransform(schema, logger.Root(), nil)
+		{
+			record := schema.NewTestRecord1(base.LogFields{"Vegetable", "Tomato", ""})
+			assert.Equal(t, base.PASS, tf.Transform(record))
+			assert.Equal(t, base.LogFields{"Vegetable", "Tomato", "Tomato"}, record.Fields)
+		}
+		{
+			record := schema.NewTestRecord1(base.LogFields{"Animal", "Cat", ""})
+			assert.Equal(t, base.PASS, tf.Transform(record))
+			assert.Equal(t, base.LogFields{"Animal", "", ""}, record.Fields)
+		}
+	}
 }
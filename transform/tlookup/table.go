@@ -0,0 +1,121 @@
+package tlookup
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/relex/slog-agent/util"
+)
+
+// lookupTable maps merged key values to the values of destination columns
+type lookupTable map[string][]string
+
+// lookupTableLoader loads lookup tables of certain format and columns
+type lookupTableLoader struct {
+	format        string
+	keyColumns    []string
+	valueColumns  []string
+	loadRowsByFmt func(path string) ([]map[string]string, error)
+}
+
+func newLookupTableLoader(format string, keyColumns []string, valueColumns []string) (*lookupTableLoader, error) {
+	var loadRows func(path string) ([]map[string]string, error)
+	switch format {
+	case "csv":
+		loadRows = loadCSVRows
+	case "yaml":
+		loadRows = loadYAMLRows
+	default:
+		return nil, fmt.Errorf("unsupported format '%s'", format)
+	}
+	return &lookupTableLoader{
+		format:        format,
+		keyColumns:    keyColumns,
+		valueColumns:  valueColumns,
+		loadRowsByFmt: loadRows,
+	}, nil
+}
+
+// ID returns an unique ID of the table to be loaded from the given path
+func (ld *lookupTableLoader) ID(path string) string {
+	return fmt.Sprintf("lookup:%s:%s:%s=%s", ld.format, path, strings.Join(ld.keyColumns, ","), strings.Join(ld.valueColumns, ","))
+}
+
+// Load loads and indexes the table at the given path
+func (ld *lookupTableLoader) Load(path string) (lookupTable, error) {
+	rows, err := ld.loadRowsByFmt(path)
+	if err != nil {
+		return nil, err
+	}
+	table := make(lookupTable, len(rows))
+	keyBuffer := make([]byte, 0, 200)
+	for i, row := range rows {
+		keyBuffer = keyBuffer[:0]
+		for _, col := range ld.keyColumns {
+			value, found := row[col]
+			if !found {
+				return nil, fmt.Errorf("row %d: missing key column '%s'", i+1, col)
+			}
+			keyBuffer = appendKeyPart(keyBuffer, value)
+		}
+		values := make([]string, len(ld.valueColumns))
+		for j, col := range ld.valueColumns {
+			value, found := row[col]
+			if !found {
+				return nil, fmt.Errorf("row %d: missing column '%s'", i+1, col)
+			}
+			values[j] = value
+		}
+		key := string(keyBuffer)
+		if _, exists := table[key]; exists {
+			return nil, fmt.Errorf("row %d: duplicated key '%s'", i+1, strings.ReplaceAll(strings.TrimSuffix(key, "\x00"), "\x00", ","))
+		}
+		table[key] = values
+	}
+	return table, nil
+}
+
+// appendKeyPart appends a value to merged key, separated by zero byte to avoid collision
+func appendKeyPart(keyBuffer []byte, value string) []byte { // xx:inline
+	return append(append(keyBuffer, value...), 0)
+}
+
+// loadCSVRows loads rows from CSV file with column names in the first row
+func loadCSVRows(path string) ([]map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.Comment = '#'
+	records, rerr := reader.ReadAll()
+	if rerr != nil {
+		return nil, rerr
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("missing header in CSV")
+	}
+	header := records[0]
+	rows := make([]map[string]string, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := make(map[string]string, len(header))
+		for i, name := range header {
+			row[strings.TrimSpace(name)] = record[i]
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// loadYAMLRows loads rows from YAML file containing a list of column-value maps
+func loadYAMLRows(path string) ([]map[string]string, error) {
+	var rows []map[string]string
+	if err := util.UnmarshalYamlFile(path, &rows); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
@@ -0,0 +1,134 @@
+// Package tlookup provides 'lookup' transform, which enriches log records with fields from a static table loaded from
+// CSV or YAML file, e.g. team and oncall by vhost.
+//
+// The table file is checked periodically and reloaded in background if changed. The new table is swapped in atomically
+// and picked up by the next record, without locking.
+package tlookup
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/relex/gotils/logger"
+	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/base/bconfig"
+	"github.com/relex/slog-agent/util/watchedfile"
+	"github.com/samber/lo"
+)
+
+// Config for lookupTransform
+type Config struct {
+	bconfig.Header `yaml:",inline"`
+	Path           string            `yaml:"path"`
+	Format         string            `yaml:"format"`
+	Keys           []string          `yaml:"keys"`
+	Fields         map[string]string `yaml:"fields"`
+}
+
+type lookupTransform struct {
+	keyLocators  []base.LogFieldLocator
+	destLocators []base.LogFieldLocator
+	keyBuffer    []byte
+	table        *watchedfile.Value[lookupTable]
+}
+
+// NewTransform creates lookupTransform
+func (c *Config) NewTransform(schema base.LogSchema, _ logger.Logger, _ base.LogCustomCounterRegistry) base.LogTransform {
+	destFields, columns := c.getSortedFieldsAndColumns()
+	loader, lerr := newLookupTableLoader(c.getFormat(), c.Keys, columns)
+	if lerr != nil {
+		logger.Panic(lerr)
+	}
+	table, werr := watchedfile.Watch(loader.ID(c.Path), c.Path, loader.Load)
+	if werr != nil {
+		logger.Panicf("failed to load lookup table '%s': %s", c.Path, werr.Error())
+	}
+	return &lookupTransform{
+		keyLocators:  schema.MustCreateFieldLocators(c.Keys),
+		destLocators: schema.MustCreateFieldLocators(destFields),
+		keyBuffer:    make([]byte, 0, 200),
+		table:        table,
+	}
+}
+
+// VerifyConfig verifies lookupTransform config
+func (c *Config) VerifyConfig(schema base.LogSchema) error {
+	if len(c.Path) == 0 {
+		return fmt.Errorf(".path is unspecified")
+	}
+	if len(c.Keys) == 0 {
+		return fmt.Errorf(".keys is empty")
+	}
+	if _, err := schema.CreateFieldLocators(c.Keys); err != nil {
+		return fmt.Errorf(".keys: %w", err)
+	}
+	if len(c.Fields) == 0 {
+		return fmt.Errorf(".fields is empty")
+	}
+	for dstKey, column := range c.Fields {
+		if _, err := schema.CreateFieldLocator(dstKey); err != nil {
+			return fmt.Errorf(".fields[%s] is invalid: %w", dstKey, err)
+		}
+		if len(column) == 0 {
+			return fmt.Errorf(".fields[%s] has no column name", dstKey)
+		}
+	}
+	_, columns := c.getSortedFieldsAndColumns()
+	loader, lerr := newLookupTableLoader(c.getFormat(), c.Keys, columns)
+	if lerr != nil {
+		return fmt.Errorf(".format: %w", lerr)
+	}
+	if _, err := loader.Load(c.Path); err != nil {
+		return fmt.Errorf(".path '%s': %w", c.Path, err)
+	}
+	return nil
+}
+
+// getFormat returns the configured format or guesses it from the file extension
+func (c *Config) getFormat() string {
+	if len(c.Format) > 0 {
+		return c.Format
+	}
+	switch filepath.Ext(c.Path) {
+	case ".yml", ".yaml":
+		return "yaml"
+	default:
+		return "csv"
+	}
+}
+
+// getSortedFieldsAndColumns returns destination fields and their source columns in a stable order
+func (c *Config) getSortedFieldsAndColumns() ([]string, []string) {
+	destFields := lo.Keys(c.Fields)
+	sort.Strings(destFields)
+	columns := lo.Map(destFields, func(field string, _ int) string {
+		return c.Fields[field]
+	})
+	return destFields, columns
+}
+
+func (tf *lookupTransform) Transform(record *base.LogRecord) base.FilterResult {
+	fields := record.Fields
+	tempMergedKey := tf.keyBuffer
+	for _, loc := range tf.keyLocators {
+		tempMergedKey = appendKeyPart(tempMergedKey, loc.Get(fields))
+	}
+	tf.keyBuffer = tempMergedKey[:0]
+
+	values, found := (*tf.table.Get())[string(tempMergedKey)]
+	if !found {
+		return base.PASS
+	}
+	for i, loc := range tf.destLocators {
+		if value := values[i]; len(value) > 0 {
+			loc.Set(fields, value)
+		}
+	}
+	return base.PASS
+}
+
+// Close releases the watched table
+func (tf *lookupTransform) Close() {
+	tf.table.Release()
+}
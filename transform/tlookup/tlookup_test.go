@@ -0,0 +1,103 @@
+package tlookup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/relex/gotils/logger"
+	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/defs"
+	"github.com/relex/slog-agent/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookupTransformCSV(t *testing.T) {
+	defs.WatchedFileCheckInterval = 20 * time.Millisecond
+	path := filepath.Join(t.TempDir(), "vhosts.csv")
+	assert.NoError(t, os.WriteFile(path, []byte(`# comment
+app,vhost,team,cost_center,oncall
+appServ,foo.com,alpha,CC-1,alice
+appServ,bar.com,beta,,bob
+`), 0o644))
+
+	schema := base.MustNewLogSchema([]string{"app", "vhost", "team", "cost", "oncall"})
+	c := &Config{}
+	if !assert.NoError(t, util.UnmarshalYamlString(fmt.Sprintf(`
+type: lookup
+path: %s
+keys: [app, vhost]
+fields:
+  team: team
+  cost: cost_center
+  oncall: oncall
+`, path), c)) {
+		return
+	}
+	if !assert.NoError(t, c.VerifyConfig(schema)) {
+		return
+	}
+	tf := c.NewTransform(schema, logger.Root(), nil)
+	defer tf.(base.LogTransformCloser).Close()
+	{
+		record := schema.NewTestRecord1(base.LogFields{"appServ", "foo.com", "", "", ""})
+		assert.Equal(t, base.PASS, tf.Transform(record))
+		assert.Equal(t, base.LogFields{"appServ", "foo.com", "alpha", "CC-1", "alice"}, record.Fields)
+	}
+	{
+		record := schema.NewTestRecord1(base.LogFields{"appServ", "bar.com", "", "old", ""})
+		assert.Equal(t, base.PASS, tf.Transform(record))
+		assert.Equal(t, base.LogFields{"appServ", "bar.com", "beta", "old", "bob"}, record.Fields)
+	}
+	{
+		record := schema.NewTestRecord1(base.LogFields{"appServfoo.com", "", "", "", ""})
+		assert.Equal(t, base.PASS, tf.Transform(record))
+		assert.Equal(t, base.LogFields{"appServfoo.com", "", "", "", ""}, record.Fields)
+	}
+
+	newPath := path + ".new"
+	assert.NoError(t, os.WriteFile(newPath, []byte("app,vhost,team,cost_center,oncall\nappServ,foo.com,gamma,CC-2,carol\n"), 0o644))
+	assert.NoError(t, os.Rename(newPath, path))
+	assert.Eventually(t, func() bool {
+		record := schema.NewTestRecord1(base.LogFields{"appServ", "foo.com", "", "", ""})
+		tf.Transform(record)
+		return record.Fields[2] == "gamma"
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestLookupTransformYAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vhosts.yml")
+	assert.NoError(t, os.WriteFile(path, []byte(`
+- vhost: foo.com
+  team: alpha
+- vhost: bar.com
+  team: beta
+`), 0o644))
+
+	schema := base.MustNewLogSchema([]string{"vhost", "team"})
+	c := &Config{}
+	assert.NoError(t, util.UnmarshalYamlString(fmt.Sprintf("type: lookup\npath: %s\nkeys: [vhost]\nfields: {team: team}", path), c))
+	if !assert.NoError(t, c.VerifyConfig(schema)) {
+		return
+	}
+	tf := c.NewTransform(schema, logger.Root(), nil)
+	defer tf.(base.LogTransformCloser).Close()
+	record := schema.NewTestRecord1(base.LogFields{"bar.com", ""})
+	assert.Equal(t, base.PASS, tf.Transform(record))
+	assert.Equal(t, "beta", record.Fields[1])
+}
+
+func TestLookupTransformVerify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vhosts.csv")
+	assert.NoError(t, os.WriteFile(path, []byte("vhost,team\nfoo.com,alpha\nfoo.com,beta\n"), 0o644))
+
+	schema := base.MustNewLogSchema([]string{"vhost", "team"})
+	c := &Config{}
+	assert.NoError(t, util.UnmarshalYamlString(fmt.Sprintf("type: lookup\npath: %s\nkeys: [vhost]\nfields: {team: team}", path), c))
+	assert.EqualError(t, c.VerifyConfig(schema), fmt.Sprintf(".path '%s': row 2: duplicated key 'foo.com'", path))
+
+	c.Fields = map[string]string{"team": "owner"}
+	assert.EqualError(t, c.VerifyConfig(schema), fmt.Sprintf(".path '%s': row 1: missing column 'owner'", path))
+}
@@ -0,0 +1,146 @@
+// Package tmask provides 'mask' transform, which masks field values except for the first and last N characters, e.g.
+// "**********7890" for account numbers, or only the part found by a pattern as in 'extractHead' / 'extractTail'.
+//
+// Lengths are counted by UTF-8 characters and each masked character is replaced by one mask character, so that
+// multi-byte characters are never split. Values which are too short to keep anything are masked completely.
+package tmask
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/relex/gotils/logger"
+	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/base/bconfig"
+	"github.com/relex/slog-agent/util"
+	"github.com/relex/slog-agent/util/stringextract"
+)
+
+// Config for maskTransform
+type Config struct {
+	bconfig.Header `yaml:",inline"`
+	Key            string `yaml:"key"`
+	Pattern        string `yaml:"pattern"`   // optional pattern of extractHead/extractTail to find the part to mask
+	Position       string `yaml:"position"`  // head or tail, required with pattern
+	MaxLength      int    `yaml:"maxLen"`    // max search length in bytes, required with pattern
+	KeepFirst      int    `yaml:"keepFirst"` // count of leading characters to keep
+	KeepLast       int    `yaml:"keepLast"`  // count of trailing characters to keep
+	MaskChar       string `yaml:"maskChar"`  // default "*"
+}
+
+type maskTransform struct {
+	keyLocator base.LogFieldLocator
+	extractor  *stringextract.Extractor // nil to mask whole values
+	keepFirst  int
+	keepLast   int
+	maskChar   string
+}
+
+const defaultMaskChar = "*"
+
+var positionMap = map[string]stringextract.Position{
+	"head": stringextract.FromStart,
+	"tail": stringextract.FromEnd,
+}
+
+// NewTransform creates maskTransform
+func (c *Config) NewTransform(schema base.LogSchema, _ logger.Logger, _ base.LogCustomCounterRegistry) base.LogTransform {
+	tf := &maskTransform{
+		keyLocator: schema.MustCreateFieldLocator(c.Key),
+		extractor:  nil,
+		keepFirst:  c.KeepFirst,
+		keepLast:   c.KeepLast,
+		maskChar:   c.MaskChar,
+	}
+	if len(c.Pattern) > 0 {
+		ex, err := stringextract.NewExtractor(positionMap[c.Position], c.Pattern, c.MaxLength)
+		if err != nil {
+			logger.Panic(err)
+		}
+		tf.extractor = &ex
+	}
+	if len(tf.maskChar) == 0 {
+		tf.maskChar = defaultMaskChar
+	}
+	return tf
+}
+
+// VerifyConfig verifies maskTransform config
+func (c *Config) VerifyConfig(schema base.LogSchema) error {
+	if len(c.Key) == 0 {
+		return fmt.Errorf(".key is unspecified")
+	}
+	if _, err := schema.CreateFieldLocator(c.Key); err != nil {
+		return fmt.Errorf(".key '%s' is invalid: %w", c.Key, err)
+	}
+	if len(c.Pattern) > 0 {
+		position, ok := positionMap[c.Position]
+		if !ok {
+			return fmt.Errorf(".position '%s' is invalid, must be head or tail", c.Position)
+		}
+		if c.MaxLength <= 0 {
+			return fmt.Errorf(".maxLen must be larger than zero: %d", c.MaxLength)
+		}
+		if _, err := stringextract.NewExtractor(position, c.Pattern, c.MaxLength); err != nil {
+			return fmt.Errorf(".pattern is invalid: %w", err)
+		}
+	} else if len(c.Position) > 0 || c.MaxLength != 0 {
+		return fmt.Errorf(".position and .maxLen are only supported with .pattern")
+	}
+	if c.KeepFirst < 0 {
+		return fmt.Errorf(".keepFirst must not be negative: %d", c.KeepFirst)
+	}
+	if c.KeepLast < 0 {
+		return fmt.Errorf(".keepLast must not be negative: %d", c.KeepLast)
+	}
+	if len(c.MaskChar) > 0 && utf8.RuneCountInString(c.MaskChar) != 1 {
+		return fmt.Errorf(".maskChar must be a single character: '%s'", c.MaskChar)
+	}
+	return nil
+}
+
+func (tf *maskTransform) Transform(record *base.LogRecord) base.FilterResult {
+	value := tf.keyLocator.Get(record.Fields)
+	if len(value) == 0 {
+		return base.PASS
+	}
+	start, end := 0, len(value)
+	if tf.extractor != nil {
+		start, end = tf.extractor.Locate(value)
+		if start == end { // not found or empty
+			return base.PASS
+		}
+	}
+	tf.keyLocator.Set(record.Fields, tf.mask(value, start, end))
+	return base.PASS
+}
+
+// mask returns a new copy of value with value[start:end] masked except for kept characters
+func (tf *maskTransform) mask(value string, start int, end int) string {
+	target := value[start:end]
+	count := utf8.RuneCountInString(target)
+	keepFirst, keepLast := tf.keepFirst, tf.keepLast
+	if count <= keepFirst+keepLast {
+		keepFirst, keepLast = 0, 0
+	}
+
+	maskStart := start
+	for i := 0; i < keepFirst; i++ {
+		_, size := utf8.DecodeRuneInString(value[maskStart:end])
+		maskStart += size
+	}
+	maskEnd := end
+	for i := 0; i < keepLast; i++ {
+		_, size := utf8.DecodeLastRuneInString(value[maskStart:maskEnd])
+		maskEnd -= size
+	}
+	maskCount := count - keepFirst - keepLast
+
+	buf := make([]byte, 0, maskStart+maskCount*len(tf.maskChar)+len(value)-maskEnd)
+	buf = append(buf, value[:maskStart]...)
+	for i := 0; i < maskCount; i++ {
+		buf = append(buf, tf.maskChar...)
+	}
+	buf = append(buf, value[maskEnd:]...)
+	return util.StringFromBytes(buf)
+}
@@ -0,0 +1,92 @@
+package tmask
+
+import (
+	"testing"
+
+	"github.com/relex/gotils/logger"
+	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaskTransform(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"account", "log"})
+	newTransform := func(yaml string) base.LogTransform {
+		c := &Config{}
+		assert.NoError(t, util.UnmarshalYamlString(yaml, c))
+		assert.NoError(t, c.VerifyConfig(schema))
+		return c.NewTransform(schema, logger.Root(), nil)
+	}
+	run := func(tf base.LogTransform, key string, value string) string {
+		record := schema.NewTestRecord1(base.LogFields{"", ""})
+		loc := schema.MustCreateFieldLocator(key)
+		loc.Set(record.Fields, value)
+		assert.Equal(t, base.PASS, tf.Transform(record))
+		return loc.Get(record.Fields)
+	}
+
+	keepLast := newTransform(`
+type: mask
+key: account
+keepLast: 4
+`)
+	assert.Equal(t, "******7890", run(keepLast, "account", "1234567890"))
+	assert.Equal(t, "****", run(keepLast, "account", "7890"))
+	assert.Equal(t, "", run(keepLast, "account", ""))
+	assert.Equal(t, "***äöüß", run(keepLast, "account", "ÄÖÜäöüß"))
+
+	keepBoth := newTransform(`
+type: mask
+key: account
+keepFirst: 2
+keepLast: 2
+maskChar: •
+`)
+	assert.Equal(t, "+3•••••••67", run(keepBoth, "account", "+3581234567"))
+
+	tail := newTransform(`
+type: mask
+key: log
+pattern: session=[0-9a-f]
+position: tail
+maxLen: 64
+keepLast: 4
+`)
+	assert.Equal(t, "login ok, session=************cdef", run(tail, "log", "login ok, session=0123456789abcdef"))
+	assert.Equal(t, "login ok", run(tail, "log", "login ok"))
+
+	head := newTransform(`
+type: mask
+key: log
+pattern: 'card \[*\] '
+position: head
+maxLen: 30
+keepFirst: 1
+`)
+	assert.Equal(t, "card [4***************] declined", run(head, "log", "card [4111111111111111] declined"))
+}
+
+func TestMaskConfig(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"account"})
+	verify := func(yaml string) error {
+		c := &Config{}
+		assert.NoError(t, util.UnmarshalYamlString(yaml, c))
+		return c.VerifyConfig(schema)
+	}
+	assert.EqualError(t, verify(`
+type: mask
+key: account
+pattern: 'id=*'
+maxLen: 10
+`), ".position '' is invalid, must be head or tail")
+	assert.EqualError(t, verify(`
+type: mask
+key: account
+position: tail
+`), ".position and .maxLen are only supported with .pattern")
+	assert.EqualError(t, verify(`
+type: mask
+key: account
+maskChar: xx
+`), ".maskChar must be a single character: 'xx'")
+}
@@ -0,0 +1,170 @@
+// Package tmetric provides 'metric' transform, which derives Prometheus metrics from log records, e.g. request
+// latency histograms from access logs or counts of 5xx responses by vhost.
+//
+// Metrics are registered to the custom metric registry as "custom_" + name and written in batches like other metrics.
+package tmetric
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+
+	"github.com/relex/gotils/logger"
+	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/base/bconfig"
+)
+
+// Config for metricTransform
+type Config struct {
+	bconfig.Header `yaml:",inline"`
+	Name           string    `yaml:"name"`       // metric name without prefix and suffix, e.g. request_duration_ms
+	Help           string    `yaml:"help"`       // optional description
+	Kind           string    `yaml:"kind"`       // counter, summary or histogram
+	Key            string    `yaml:"key"`        // numeric field to observe, for summary and histogram. Empty values are skipped
+	Scale          float64   `yaml:"scale"`      // multiplier of values before observing, e.g. 1000 for seconds => ms. Default 1
+	Buckets        []float64 `yaml:"buckets"`    // upper bounds of histogram buckets in ascending order
+	Labels         []string  `yaml:"labels"`     // fields to be used as labels of the same names
+	MaxSeries      int       `yaml:"maxSeries"`  // max count of label-value combinations. Default 1000
+	ErrorLabel     string    `yaml:"errorLabel"` // custom counter label for invalid values, for summary and histogram
+}
+
+type metricTransform struct {
+	labelExtractor base.FieldSetExtractor
+	keyLocator     base.LogFieldLocator // MissingFieldLocator for counter
+	scale          float64
+	observe        base.LogCustomMetricObserver
+	countError     func(length int)
+}
+
+const defaultMaxSeries = 1000
+
+var (
+	metricNameRegex = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelNameRegex  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+var kindMap = map[string]base.LogCustomMetricKind{
+	"counter":   base.CustomMetricCounter,
+	"summary":   base.CustomMetricSummary,
+	"histogram": base.CustomMetricHistogram,
+}
+
+// NewTransform creates metricTransform
+func (cfg *Config) NewTransform(schema base.LogSchema, _ logger.Logger, customCounterRegistry base.LogCustomCounterRegistry) base.LogTransform {
+	kind := kindMap[cfg.Kind]
+	tf := &metricTransform{
+		labelExtractor: *base.NewFieldSetExtractor(schema.MustCreateFieldLocators(cfg.Labels)),
+		keyLocator:     base.MissingFieldLocator,
+		scale:          cfg.Scale,
+		observe:        customCounterRegistry.RegisterCustomMetric(cfg.getSpec()),
+		countError:     nil,
+	}
+	if tf.scale == 0 {
+		tf.scale = 1
+	}
+	if kind != base.CustomMetricCounter {
+		tf.keyLocator = schema.MustCreateFieldLocator(cfg.Key)
+		tf.countError = customCounterRegistry.RegisterCustomCounter(cfg.ErrorLabel)
+	}
+	return tf
+}
+
+// VerifyConfig verifies metricTransform config
+func (cfg *Config) VerifyConfig(schema base.LogSchema) error {
+	if len(cfg.Name) == 0 {
+		return fmt.Errorf(".name is unspecified")
+	}
+	if !metricNameRegex.MatchString(cfg.Name) {
+		return fmt.Errorf(".name '%s' is not a valid metric name", cfg.Name)
+	}
+	kind, ok := kindMap[cfg.Kind]
+	if !ok {
+		return fmt.Errorf(".kind '%s' is invalid, must be one of counter, summary or histogram", cfg.Kind)
+	}
+	for _, label := range cfg.Labels {
+		if _, err := schema.CreateFieldLocator(label); err != nil {
+			return fmt.Errorf(".labels[%s] is invalid: %w", label, err)
+		}
+		if !labelNameRegex.MatchString(label) || label == "le" {
+			return fmt.Errorf(".labels[%s] is not a valid label name", label)
+		}
+	}
+	if cfg.Scale < 0 {
+		return fmt.Errorf(".scale must not be negative: %g", cfg.Scale)
+	}
+	if cfg.MaxSeries < 0 {
+		return fmt.Errorf(".maxSeries must not be negative: %d", cfg.MaxSeries)
+	}
+
+	if kind == base.CustomMetricCounter {
+		if len(cfg.Key) > 0 || cfg.Scale != 0 || len(cfg.ErrorLabel) > 0 {
+			return fmt.Errorf(".key, .scale and .errorLabel are not supported by counter")
+		}
+	} else {
+		if len(cfg.Key) == 0 {
+			return fmt.Errorf(".key is unspecified")
+		}
+		if _, err := schema.CreateFieldLocator(cfg.Key); err != nil {
+			return fmt.Errorf(".key '%s' is invalid: %w", cfg.Key, err)
+		}
+		if len(cfg.ErrorLabel) == 0 {
+			return fmt.Errorf(".errorLabel is unspecified")
+		}
+	}
+
+	if kind == base.CustomMetricHistogram {
+		if len(cfg.Buckets) == 0 {
+			return fmt.Errorf(".buckets is empty")
+		}
+		for i := 1; i < len(cfg.Buckets); i++ {
+			if cfg.Buckets[i] <= cfg.Buckets[i-1] {
+				return fmt.Errorf(".buckets must be in strictly ascending order: %v", cfg.Buckets)
+			}
+		}
+	} else if len(cfg.Buckets) > 0 {
+		return fmt.Errorf(".buckets is only supported by histogram")
+	}
+	return schema.VerifyCustomMetric(cfg.getSpec())
+}
+
+func (cfg *Config) getSpec() base.LogCustomMetricSpec {
+	maxSeries := cfg.MaxSeries
+	if maxSeries == 0 {
+		maxSeries = defaultMaxSeries
+	}
+	return base.LogCustomMetricSpec{
+		Name:       cfg.Name,
+		Help:       cfg.getHelp(),
+		Kind:       kindMap[cfg.Kind],
+		LabelNames: cfg.Labels,
+		Buckets:    cfg.Buckets,
+		MaxSeries:  maxSeries,
+	}
+}
+
+func (cfg *Config) getHelp() string {
+	if len(cfg.Help) > 0 {
+		return cfg.Help
+	}
+	return fmt.Sprintf("Custom %s of %s", cfg.Kind, cfg.Name)
+}
+
+func (tf *metricTransform) Transform(record *base.LogRecord) base.FilterResult {
+	labelValues := tf.labelExtractor.Extract(record)
+	if tf.keyLocator == base.MissingFieldLocator {
+		tf.observe(labelValues, 0)
+		return base.PASS
+	}
+	text := tf.keyLocator.Get(record.Fields)
+	if len(text) == 0 {
+		return base.PASS
+	}
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil || math.IsInf(value, 0) || math.IsNaN(value) {
+		tf.countError(record.RawLength)
+		return base.PASS
+	}
+	tf.observe(labelValues, value*tf.scale)
+	return base.PASS
+}
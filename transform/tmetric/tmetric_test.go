@@ -0,0 +1,226 @@
+package tmetric
+
+import (
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/relex/gotils/logger"
+	"github.com/relex/gotils/promexporter/promext"
+	"github.com/relex/gotils/promexporter/promreg"
+	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/util"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricTransform(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"vhost", "status", "duration"})
+	mfactory := promreg.NewMetricFactory("test_", nil, nil)
+	customMetrics := base.NewLogCustomMetricRegistry("test_")
+	pcounter := base.NewLogProcessCounter(mfactory, customMetrics, schema, nil, nil)
+
+	newTransformWith := func(registry base.LogCustomCounterRegistry, yaml string) base.LogTransform {
+		c := &Config{}
+		assert.NoError(t, util.UnmarshalYamlString(yaml, c))
+		assert.NoError(t, c.VerifyConfig(schema))
+		return c.NewTransform(schema, logger.Root(), registry)
+	}
+	newTransform := func(yaml string) base.LogTransform {
+		return newTransformWith(pcounter, yaml)
+	}
+	histogram := newTransform(`
+type: metric
+name: request_duration_ms
+kind: histogram
+key: duration
+scale: 1000
+buckets: [10, 100, 1000]
+labels: [vhost]
+errorLabel: badDuration
+`)
+	summary := newTransform(`
+type: metric
+name: request_duration_seconds
+kind: summary
+key: duration
+labels: [vhost]
+errorLabel: badDuration
+`)
+	counter := newTransform(`
+type: metric
+name: requests
+help: Numbers of requests
+kind: counter
+labels: [vhost, status]
+maxSeries: 2
+`)
+
+	pcounter.SelectMetricKeySet(schema.NewTestRecord1(base.LogFields{"", "", ""}))
+	for _, fields := range []base.LogFields{
+		{"a.com", "200", "0.005"},
+		{"a.com", "500", "0.05"},
+		{"a.com", "200", "2.5"},
+		{"b.com", "200", "0.1"},
+		{"b.com", "404", ""},
+		{"b.com", "200", "-1"},
+		{"b.com", "500", "bad"},
+	} {
+		record := schema.NewTestRecord1(fields)
+		record.RawLength = 10
+		assert.Equal(t, base.PASS, histogram.Transform(record))
+		assert.Equal(t, base.PASS, summary.Transform(record))
+		assert.Equal(t, base.PASS, counter.Transform(record))
+	}
+	pcounter.UpdateMetrics()
+
+	assert.Equal(t, `test_custom_request_duration_ms_bucket{vhost="a.com",le="10"} 1
+test_custom_request_duration_ms_bucket{vhost="a.com",le="100"} 2
+test_custom_request_duration_ms_bucket{vhost="a.com",le="1000"} 2
+test_custom_request_duration_ms_bucket{vhost="a.com",le="+Inf"} 3
+test_custom_request_duration_ms_sum{vhost="a.com"} 2555
+test_custom_request_duration_ms_count{vhost="a.com"} 3
+test_custom_request_duration_ms_bucket{vhost="b.com",le="10"} 1
+test_custom_request_duration_ms_bucket{vhost="b.com",le="100"} 2
+test_custom_request_duration_ms_bucket{vhost="b.com",le="1000"} 2
+test_custom_request_duration_ms_bucket{vhost="b.com",le="+Inf"} 2
+test_custom_request_duration_ms_sum{vhost="b.com"} -900
+test_custom_request_duration_ms_count{vhost="b.com"} 2
+test_custom_request_duration_seconds_sum{vhost="a.com"} 2.555
+test_custom_request_duration_seconds_count{vhost="a.com"} 3
+test_custom_request_duration_seconds_sum{vhost="b.com"} -0.9
+test_custom_request_duration_seconds_count{vhost="b.com"} 2
+test_custom_requests_total{status="200",vhost="a.com"} 2
+test_custom_requests_total{status="500",vhost="a.com"} 1
+test_derived_dropped_record_bytes_total 0
+test_derived_dropped_records_total 0
+test_derived_passed_record_bytes_total 0
+test_derived_passed_records_total 0
+test_dropped_record_bytes_total 0
+test_dropped_records_total 0
+test_labelled_record_bytes_total{label="badDuration"} 20
+test_labelled_records_total{label="badDuration"} 2
+test_passed_record_bytes_total 0
+test_passed_records_total 0
+`, promext.DumpMetrics("", true, false, mfactory, customMetrics))
+
+	families, err := customMetrics.Gather()
+	assert.NoError(t, err)
+	assert.Equal(t, []dto.MetricType{dto.MetricType_HISTOGRAM, dto.MetricType_SUMMARY, dto.MetricType_COUNTER},
+		lo.Map(families, func(mf *dto.MetricFamily, _ int) dto.MetricType { return mf.GetType() }))
+
+	// the same metric from another pipeline is aggregated, while a conflicting one is rejected
+	anotherCounter := base.NewLogProcessCounter(mfactory, customMetrics, schema, nil, nil)
+	anotherSummary := newTransformWith(anotherCounter, `
+type: metric
+name: request_duration_seconds
+kind: summary
+key: duration
+labels: [vhost]
+errorLabel: badDuration
+`)
+	conflictingSummary := newTransformWith(anotherCounter, `
+type: metric
+name: request_duration_seconds
+kind: summary
+key: duration
+labels: [status]
+errorLabel: badDuration
+`)
+	anotherCounter.SelectMetricKeySet(schema.NewTestRecord1(base.LogFields{"", "", ""}))
+	record := schema.NewTestRecord1(base.LogFields{"b.com", "200", "0.4"})
+	anotherSummary.Transform(record)
+	conflictingSummary.Transform(record)
+	anotherCounter.UpdateMetrics()
+	assert.Contains(t, promext.DumpMetrics("", true, false, customMetrics), `test_custom_request_duration_seconds_sum{vhost="b.com"} -0.5
+test_custom_request_duration_seconds_count{vhost="b.com"} 3
+`)
+	assert.NotContains(t, promext.DumpMetrics("", true, false, customMetrics), `status="200"}`)
+
+	// checked without registering, e.g. for config reloading
+	conflictingConfig := &Config{}
+	assert.NoError(t, util.UnmarshalYamlString(`
+type: metric
+name: request_duration_seconds
+kind: counter
+`, conflictingConfig))
+	assert.EqualError(t, customMetrics.CheckSpec(conflictingConfig.getSpec()),
+		"custom metric 'request_duration_seconds' is already registered with a different kind, labels or buckets")
+	conflictingConfig.Name = "request_count"
+	assert.NoError(t, customMetrics.CheckSpec(conflictingConfig.getSpec()))
+}
+
+func TestMetricConfig(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"vhost", "duration"})
+	verify := func(yaml string) error {
+		c := &Config{}
+		assert.NoError(t, util.UnmarshalYamlString(yaml, c))
+		return c.VerifyConfig(schema)
+	}
+	assert.EqualError(t, verify(`
+type: metric
+name: foo-bar
+kind: counter
+`), ".name 'foo-bar' is not a valid metric name")
+	assert.EqualError(t, verify(`
+type: metric
+name: foo
+kind: gauge
+`), ".kind 'gauge' is invalid, must be one of counter, summary or histogram")
+	assert.EqualError(t, verify(`
+type: metric
+name: foo
+kind: counter
+key: duration
+`), ".key, .scale and .errorLabel are not supported by counter")
+	assert.EqualError(t, verify(`
+type: metric
+name: foo
+kind: summary
+key: duration
+`), ".errorLabel is unspecified")
+	assert.EqualError(t, verify(`
+type: metric
+name: foo
+kind: histogram
+key: duration
+errorLabel: bad
+buckets: [1, 10, 5]
+`), ".buckets must be in strictly ascending order: [1 10 5]")
+	assert.EqualError(t, verify(`
+type: metric
+name: foo
+kind: summary
+key: duration
+errorLabel: bad
+buckets: [1]
+`), ".buckets is only supported by histogram")
+
+	// conflicts with other metrics in config
+	var specs []base.LogCustomMetricSpec
+	schema.OnCustomMetric = func(spec base.LogCustomMetricSpec) error {
+		for _, other := range specs {
+			if err := spec.CheckConflict(other); err != nil {
+				return err
+			}
+		}
+		specs = append(specs, spec)
+		return nil
+	}
+	assert.NoError(t, verify(`
+type: metric
+name: foo
+kind: counter
+labels: [vhost]
+`))
+	assert.NoError(t, verify(`
+type: metric
+name: foo
+kind: counter
+labels: [vhost]
+`))
+	assert.EqualError(t, verify(`
+type: metric
+name: foo
+kind: counter
+`), "custom metric 'foo' is already registered with a different kind, labels or buckets")
+}
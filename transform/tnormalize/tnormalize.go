@@ -0,0 +1,86 @@
+// Package tnormalize provides 'lowercase', 'uppercase' and 'trim' transforms, to normalize field values before matching
+// or routing. Field values are only replaced (and allocated) when actually changed.
+package tnormalize
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/relex/gotils/logger"
+	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/base/bconfig"
+)
+
+// Config for normalizeTransform
+type Config struct {
+	bconfig.Header `yaml:",inline"`
+	Keys           []string `yaml:"keys"`
+	Chars          string   `yaml:"chars"` // characters to trim, only for 'trim'. Default to ASCII whitespace
+}
+
+type normalizeTransform struct {
+	locators []base.LogFieldLocator
+	convert  func(string) string
+}
+
+const defaultTrimChars = " \t\r\n\v\f"
+
+// NewTransform creates normalizeTransform
+func (c *Config) NewTransform(schema base.LogSchema, _ logger.Logger, _ base.LogCustomCounterRegistry) base.LogTransform {
+	locators := make([]base.LogFieldLocator, 0, len(c.Keys))
+	for _, key := range c.Keys {
+		locators = append(locators, schema.MustCreateFieldLocator(key))
+	}
+	return &normalizeTransform{
+		locators: locators,
+		convert:  c.getConverter(),
+	}
+}
+
+// VerifyConfig verifies normalizeTransform config
+func (c *Config) VerifyConfig(schema base.LogSchema) error {
+	if len(c.Keys) == 0 {
+		return fmt.Errorf(".keys is empty")
+	}
+	for _, key := range c.Keys {
+		if _, err := schema.CreateFieldLocator(key); err != nil {
+			return fmt.Errorf(".keys[%s] is invalid: %w", key, err)
+		}
+	}
+	if len(c.Chars) > 0 && c.Type != "trim" {
+		return fmt.Errorf(".chars is only supported by 'trim'")
+	}
+	return nil
+}
+
+func (c *Config) getConverter() func(string) string {
+	switch c.Type {
+	case "lowercase":
+		// strings.ToLower and ToUpper return the original string if there is nothing to change
+		return strings.ToLower
+	case "uppercase":
+		return strings.ToUpper
+	case "trim":
+		chars := c.Chars
+		if len(chars) == 0 {
+			chars = defaultTrimChars
+		}
+		return func(s string) string {
+			return strings.Trim(s, chars)
+		}
+	default:
+		panic(fmt.Sprintf("unsupported type '%s'", c.Type))
+	}
+}
+
+func (tf *normalizeTransform) Transform(record *base.LogRecord) base.FilterResult {
+	fields := record.Fields
+	for _, loc := range tf.locators {
+		value := loc.Get(fields)
+		if len(value) == 0 {
+			continue
+		}
+		loc.Set(fields, tf.convert(value))
+	}
+	return base.PASS
+}
@@ -0,0 +1,78 @@
+package tnormalize
+
+import (
+	"testing"
+
+	"github.com/relex/gotils/logger"
+	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeTransforms(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"a", "b", "c"})
+	newTransform := func(yaml string) base.LogTransform {
+		c := &Config{}
+		assert.NoError(t, util.UnmarshalYamlString(yaml, c))
+		assert.NoError(t, c.VerifyConfig(schema))
+		return c.NewTransform(schema, logger.Root(), nil)
+	}
+
+	lower := newTransform(`
+type: lowercase
+keys: [a, b]
+`)
+	record := schema.NewTestRecord1(base.LogFields{"WARN", "Bar Ä", "XXX"})
+	assert.Equal(t, base.PASS, lower.Transform(record))
+	assert.Equal(t, base.LogFields{"warn", "bar ä", "XXX"}, record.Fields)
+
+	upper := newTransform(`
+type: uppercase
+keys: [c]
+`)
+	record = schema.NewTestRecord1(base.LogFields{"foo", "", "xxx"})
+	assert.Equal(t, base.PASS, upper.Transform(record))
+	assert.Equal(t, base.LogFields{"foo", "", "XXX"}, record.Fields)
+
+	trim := newTransform(`
+type: trim
+keys: [a, b]
+`)
+	record = schema.NewTestRecord1(base.LogFields{" foo\n", "\t", "  "})
+	assert.Equal(t, base.PASS, trim.Transform(record))
+	assert.Equal(t, base.LogFields{"foo", "", "  "}, record.Fields)
+
+	trimChars := newTransform(`
+type: trim
+keys: [a]
+chars: '"'
+`)
+	record = schema.NewTestRecord1(base.LogFields{`"quoted"`, "", ""})
+	assert.Equal(t, base.PASS, trimChars.Transform(record))
+	assert.Equal(t, "quoted", record.Fields[0])
+}
+
+func TestNormalizeTransformNoAllocation(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"a", "b"})
+	c := &Config{}
+	assert.NoError(t, util.UnmarshalYamlString(`
+type: lowercase
+keys: [a, b]
+`, c))
+	tf := c.NewTransform(schema, logger.Root(), nil)
+	record := schema.NewTestRecord1(base.LogFields{"already lower", "ünïcode"})
+	assert.Zero(t, testing.AllocsPerRun(100, func() {
+		tf.Transform(record)
+	}))
+}
+
+func TestNormalizeConfig(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"a"})
+	c := &Config{}
+	assert.NoError(t, util.UnmarshalYamlString(`
+type: uppercase
+keys: [a]
+chars: x
+`, c))
+	assert.EqualError(t, c.VerifyConfig(schema), ".chars is only supported by 'trim'")
+}
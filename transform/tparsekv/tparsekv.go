@@ -0,0 +1,155 @@
+// Package tparsekv provides 'parseKV' transform, which parses key=value pairs separated by spaces from a field, e.g.
+// logfmt-style messages: `user=john action="log in" ok=1`.
+//
+// Keys listed in config are set to the fields of the same names. Keys not defined in schema are added to extra fields if
+// enabled by schema's maxExtraFields, until there is no room left; records with keys dropped for that are counted by
+// metricLabel. Other keys are ignored.
+//
+// Values may be double-quoted, in which case the quotes are removed but backslash-escapes are kept as-is. All the
+// values refer to the source field without copying.
+package tparsekv
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/relex/gotils/logger"
+	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/base/bconfig"
+)
+
+// Config for parseKVTransform
+type Config struct {
+	bconfig.Header `yaml:",inline"`
+	Key            string   `yaml:"key"`
+	Keys           []string `yaml:"keys"`        // fields in schema to be set from parsed pairs, optional
+	MetricLabel    string   `yaml:"metricLabel"` // label to count records overflowing extra fields, required if enabled
+}
+
+type parseKVTransform struct {
+	keyLocator    base.LogFieldLocator
+	keyMap        map[string]base.LogFieldLocator // keys to set, MissingFieldLocator for other fields in schema
+	extraFields   bool                            // whether to add undeclared keys to extra fields
+	overflowed    bool                            // whether any key is dropped for lack of room in extra fields
+	countOverflow func(length int)
+}
+
+// NewTransform creates parseKVTransform
+func (c *Config) NewTransform(schema base.LogSchema, _ logger.Logger, customCounterRegistry base.LogCustomCounterRegistry) base.LogTransform {
+	keyMap := make(map[string]base.LogFieldLocator, len(schema.GetFieldNames()))
+	for _, name := range schema.GetFieldNames() {
+		keyMap[name] = base.MissingFieldLocator
+	}
+	for _, name := range c.Keys {
+		keyMap[name] = schema.MustCreateFieldLocator(name)
+	}
+	var countOverflow func(length int)
+	if schema.GetMaxExtraFields() > 0 {
+		countOverflow = customCounterRegistry.RegisterCustomCounter(c.MetricLabel)
+	}
+	return &parseKVTransform{
+		keyLocator:    schema.MustCreateFieldLocator(c.Key),
+		keyMap:        keyMap,
+		extraFields:   schema.GetMaxExtraFields() > 0,
+		overflowed:    false,
+		countOverflow: countOverflow,
+	}
+}
+
+// VerifyConfig verifies parseKVTransform config
+func (c *Config) VerifyConfig(schema base.LogSchema) error {
+	if len(c.Key) == 0 {
+		return fmt.Errorf(".key is unspecified")
+	}
+	if _, err := schema.CreateFieldLocator(c.Key); err != nil {
+		return fmt.Errorf(".key '%s' is invalid: %w", c.Key, err)
+	}
+	for i, name := range c.Keys {
+		if _, err := schema.CreateFieldLocator(name); err != nil {
+			return fmt.Errorf(".keys[%d] '%s' is invalid: %w", i, name, err)
+		}
+		if name == c.Key {
+			return fmt.Errorf(".keys[%d] '%s' is the same as .key", i, name)
+		}
+	}
+	if len(c.Keys) == 0 && schema.GetMaxExtraFields() == 0 {
+		return fmt.Errorf(".keys is empty while extra fields are disabled in schema")
+	}
+	if len(c.MetricLabel) == 0 && schema.GetMaxExtraFields() > 0 {
+		return fmt.Errorf(".metricLabel is unspecified while extra fields are enabled in schema")
+	}
+	return nil
+}
+
+func (tf *parseKVTransform) Transform(record *base.LogRecord) base.FilterResult {
+	value := tf.keyLocator.Get(record.Fields)
+	pos := 0
+	for pos < len(value) {
+		if value[pos] == ' ' {
+			pos++
+			continue
+		}
+		keyStart := pos
+		for pos < len(value) && value[pos] != '=' && value[pos] != ' ' {
+			pos++
+		}
+		if pos == len(value) || value[pos] == ' ' { // no '='
+			continue
+		}
+		if pos == keyStart { // no key, skip the whole word
+			for pos < len(value) && value[pos] != ' ' {
+				pos++
+			}
+			continue
+		}
+		key := value[keyStart:pos]
+		pos++ // skip '='
+
+		var pairValue string
+		if pos < len(value) && value[pos] == '"' {
+			end := findClosingQuote(value, pos+1)
+			pairValue = value[pos+1 : end]
+			pos = end + 1
+		} else {
+			end := strings.IndexByte(value[pos:], ' ')
+			if end == -1 {
+				end = len(value)
+			} else {
+				end += pos
+			}
+			pairValue = value[pos:end]
+			pos = end
+		}
+		tf.setPair(record, key, pairValue)
+	}
+	if tf.overflowed {
+		tf.countOverflow(record.RawLength)
+		tf.overflowed = false
+	}
+	return base.PASS
+}
+
+func (tf *parseKVTransform) setPair(record *base.LogRecord, key string, value string) {
+	locator, declared := tf.keyMap[key]
+	switch {
+	case !declared:
+		if tf.extraFields && !record.Extra.Set(key, value) {
+			tf.overflowed = true
+		}
+	case locator != base.MissingFieldLocator:
+		locator.Set(record.Fields, value)
+	}
+}
+
+// findClosingQuote returns the position of the first unescaped double quote from start, or the end of value
+func findClosingQuote(value string, start int) int {
+	for i := start; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return len(value)
+}
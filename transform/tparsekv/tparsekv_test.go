@@ -0,0 +1,59 @@
+package tparsekv
+
+import (
+	"testing"
+
+	"github.com/relex/gotils/logger"
+	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/base/btest"
+	"github.com/relex/slog-agent/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseKVTransform(t *testing.T) {
+	schema, err := base.NewLogSchema([]string{"log", "user", "host"}, 3, 2)
+	assert.NoError(t, err)
+	c := &Config{}
+	assert.NoError(t, util.UnmarshalYamlString(`
+type: parseKV
+key: log
+keys: [user]
+metricLabel: kv_overflow
+`, c))
+	assert.NoError(t, c.VerifyConfig(schema))
+	reg, lookup := btest.NewStubLogCustomCounterRegistry()
+	tf := c.NewTransform(schema, logger.Root(), reg)
+
+	record := schema.NewTestRecord1(base.LogFields{`user=john host=evil action="log \"in\"" bad = ok=1 x=2 y=3`, "", "localhost"})
+	record.RawLength = 10
+	assert.Equal(t, base.PASS, tf.Transform(record))
+	assert.Equal(t, "john", record.Fields[1])
+	assert.Equal(t, "localhost", record.Fields[2])
+	assert.Equal(t, base.LogExtraFields{
+		{Key: "action", Value: `log \"in\"`},
+		{Key: "ok", Value: "1"},
+	}, record.Extra)
+
+	record = schema.NewTestRecord1(base.LogFields{`msg="unterminated`, "", ""})
+	assert.Equal(t, base.PASS, tf.Transform(record))
+	assert.Equal(t, "unterminated", record.Extra.Get("msg"))
+
+	// x and y are dropped from the first record as extra fields are full
+	count, length := lookup("kv_overflow")
+	assert.Equal(t, int64(1), count)
+	assert.Equal(t, int64(10), length)
+}
+
+func TestParseKVConfig(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"log", "user"})
+	c := &Config{}
+	assert.NoError(t, util.UnmarshalYamlString(`
+type: parseKV
+key: log
+`, c))
+	assert.EqualError(t, c.VerifyConfig(schema), ".keys is empty while extra fields are disabled in schema")
+
+	extraSchema, err := base.NewLogSchema([]string{"log", "user"}, 2, 2)
+	assert.NoError(t, err)
+	assert.EqualError(t, c.VerifyConfig(extraSchema), ".metricLabel is unspecified while extra fields are enabled in schema")
+}
@@ -43,3 +43,19 @@ func atof9(s string) float64 {
 		float64((s[9]-'0'))*0.000000001
 	return v
 }
+
+// atoiN parses non-negative integer of at most maxDigits digits, or returns false if invalid
+func atoiN(s string, maxDigits int) (int64, bool) {
+	if len(s) == 0 || len(s) > maxDigits {
+		return 0, false
+	}
+	var v int64
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		v = v*10 + int64(c-'0')
+	}
+	return v, true
+}
@@ -0,0 +1,24 @@
+package tparsetime
+
+import (
+	"fmt"
+	"time"
+)
+
+// parseCommonLogTimestamp parses timestamp in Common Log Format used by nginx and Apache access logs
+// ex: 15/Aug/2019:15:50:46 +0300
+func parseCommonLogTimestamp(timeStr string, timezoneCache map[string]*time.Location, _ *time.Location) (time.Time, error) {
+	t := timeStr
+	if len(t) != 26 || t[2] != '/' || t[6] != '/' || t[11] != ':' || t[14] != ':' || t[17] != ':' || t[20] != ' ' {
+		return time.Now(), fmt.Errorf("invalid timestamp")
+	}
+	month := parseMonth(t[3:6])
+	if month == 0 {
+		return time.Now(), fmt.Errorf("invalid month '%s'", t[3:6])
+	}
+	location, err := getFixedZone(t[21:], timezoneCache)
+	if err != nil {
+		return time.Now(), err
+	}
+	return time.Date(atoi4(t[7:11]), month, atoi2(t[0:2]), atoi2(t[12:14]), atoi2(t[15:17]), atoi2(t[18:20]), 0, location), nil
+}
@@ -0,0 +1,86 @@
+package tparsetime
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// timestampParser parses timestamp of certain format
+//
+// The defaultLocation is used if the format or the input doesn't specify timezone
+type timestampParser func(s string, timezoneCache map[string]*time.Location, defaultLocation *time.Location) (time.Time, error)
+
+// namedParsers lists hand-written parsers of common formats, all other format names are treated as Go layouts
+var namedParsers = map[string]timestampParser{
+	"rfc3339":   parseRFC3339Timestamp,
+	"rfc3164":   parseRFC3164TimestampNow,
+	"commonLog": parseCommonLogTimestamp,
+	"java":      parseJavaTimestamp,
+	"unix":      parseUnixTimestamp,
+	"unixMs":    parseUnixMsTimestamp,
+}
+
+const defaultFormat = "rfc3339"
+
+// getParser returns the parser of the given format name or Go layout
+func getParser(format string) (timestampParser, error) {
+	if parser, ok := namedParsers[format]; ok {
+		return parser, nil
+	}
+	if err := verifyLayout(format); err != nil {
+		return nil, err
+	}
+	return newLayoutParser(format), nil
+}
+
+// verifyLayout verifies that the given layout contains at least year or minute, to catch misspelled format names
+func verifyLayout(layout string) error {
+	if !strings.Contains(layout, "2006") && !strings.Contains(layout, "04") {
+		return fmt.Errorf("unknown format or invalid layout '%s'", layout)
+	}
+	refTime := time.Date(2022, 11, 30, 23, 58, 59, 0, time.UTC)
+	if _, err := time.Parse(layout, refTime.Format(layout)); err != nil {
+		return fmt.Errorf("invalid layout '%s': %w", layout, err)
+	}
+	return nil
+}
+
+// newLayoutParser creates a slow parser using Go layout, e.g. "2006-01-02 15:04:05"
+func newLayoutParser(layout string) timestampParser {
+	return func(s string, _ map[string]*time.Location, defaultLocation *time.Location) (time.Time, error) {
+		return time.ParseInLocation(layout, s, defaultLocation)
+	}
+}
+
+// parseMonth parses English abbreviated month name, e.g. "Jan", or returns zero if invalid
+func parseMonth(s string) time.Month {
+	switch s {
+	case "Jan":
+		return time.January
+	case "Feb":
+		return time.February
+	case "Mar":
+		return time.March
+	case "Apr":
+		return time.April
+	case "May":
+		return time.May
+	case "Jun":
+		return time.June
+	case "Jul":
+		return time.July
+	case "Aug":
+		return time.August
+	case "Sep":
+		return time.September
+	case "Oct":
+		return time.October
+	case "Nov":
+		return time.November
+	case "Dec":
+		return time.December
+	default:
+		return 0
+	}
+}
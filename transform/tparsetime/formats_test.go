@@ -0,0 +1,95 @@
+package tparsetime
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseOtherFormats(t *testing.T) {
+	helsinki, _ := time.LoadLocation("Europe/Helsinki")
+	timezoneCache := make(map[string]*time.Location)
+	cases := []struct {
+		format    string
+		timestamp string
+		layout    string
+	}{
+		{"commonLog", "15/Aug/2019:15:50:46 +0300", "02/Jan/2006:15:04:05 -0700"},
+		{"commonLog", "07/Feb/2022:10:30:45 -0800", "02/Jan/2006:15:04:05 -0700"},
+		{"java", "2019-08-15 15:50:46,866", "2006-01-02 15:04:05,000"},
+		{"java", "2019-08-15 15:50:46.866915", "2006-01-02 15:04:05.000000"},
+		{"java", "2019-08-15 15:50:46", "2006-01-02 15:04:05"},
+		{"java", "2019-08-15 15:50:46.866+02:00", "2006-01-02 15:04:05.000Z07:00"},
+		{"rfc3339", "2019-08-15T15:50:46.866", "2006-01-02T15:04:05.000"},
+		{"2006/01/02 15:04:05", "2019/08/15 15:50:46", "2006/01/02 15:04:05"},
+	}
+	for _, tc := range cases {
+		parser, err := getParser(tc.format)
+		if !assert.NoError(t, err, tc.format) {
+			continue
+		}
+		ourTime, err := parser(tc.timestamp, timezoneCache, helsinki)
+		assert.NoError(t, err, tc.timestamp+" our parsing")
+		theirTime, err := time.ParseInLocation(tc.layout, tc.timestamp, helsinki)
+		assert.NoError(t, err, tc.timestamp+" go parsing")
+		assert.Equal(t, theirTime.UnixNano(), ourTime.UnixNano(), tc.timestamp+" UNIX nanoseconds")
+		_, ourOffset := ourTime.Zone()
+		_, theirOffset := theirTime.Zone()
+		assert.Equal(t, theirOffset, ourOffset, tc.timestamp+" TZ offset")
+	}
+}
+
+func TestParseUnixTimestamp(t *testing.T) {
+	tm, err := parseUnixTimestamp("1565873446", nil, time.UTC)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1565873446000000000), tm.UnixNano())
+
+	tm, err = parseUnixTimestamp("1565873446.866915", nil, time.UTC)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1565873446866915000), tm.UnixNano())
+
+	tm, err = parseUnixMsTimestamp("1565873446866", nil, time.UTC)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1565873446866000000), tm.UnixNano())
+
+	for _, s := range []string{"", "abc", "1565873446.", "1565873446.86a", "2019-08-15T15:50:46Z", "12345678901234567"} {
+		_, err := parseUnixTimestamp(s, nil, time.UTC)
+		assert.Error(t, err, s)
+		_, err = parseUnixMsTimestamp(s, nil, time.UTC)
+		assert.Error(t, err, s)
+	}
+
+	for _, s := range []string{"1565873446", "156587344686", "15658734468660"} {
+		_, err := parseUnixMsTimestamp(s, nil, time.UTC)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestParseRFC3164Timestamp(t *testing.T) {
+	now := time.Date(2022, 8, 20, 10, 0, 0, 0, time.UTC)
+	tm, err := parseRFC3164Timestamp("Aug 15 15:50:46", time.UTC, now)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2022, 8, 15, 15, 50, 46, 0, time.UTC), tm)
+
+	tm, err = parseRFC3164Timestamp("Aug  5 15:50:46", time.UTC, now)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2022, 8, 5, 15, 50, 46, 0, time.UTC), tm)
+
+	newYear := time.Date(2023, 1, 1, 0, 0, 10, 0, time.UTC)
+	tm, err = parseRFC3164Timestamp("Dec 31 23:59:59", time.UTC, newYear)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2022, 12, 31, 23, 59, 59, 0, time.UTC), tm)
+
+	_, err = parseRFC3164Timestamp("Abc 15 15:50:46", time.UTC, now)
+	assert.EqualError(t, err, "invalid month 'Abc'")
+	_, err = parseRFC3164Timestamp("2019-08-15T15:50:46Z", time.UTC, now)
+	assert.Error(t, err)
+}
+
+func TestGetParser(t *testing.T) {
+	_, err := getParser("rfc1234")
+	assert.EqualError(t, err, "unknown format or invalid layout 'rfc1234'")
+	_, err = getParser("15:04:05")
+	assert.NoError(t, err)
+}
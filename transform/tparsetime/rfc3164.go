@@ -0,0 +1,43 @@
+package tparsetime
+
+import (
+	"fmt"
+	"time"
+)
+
+// parseRFC3164TimestampNow parses timestamp in RFC3164 format, guessing the year from the current time
+func parseRFC3164TimestampNow(timeStr string, _ map[string]*time.Location, defaultLocation *time.Location) (time.Time, error) {
+	return parseRFC3164Timestamp(timeStr, defaultLocation, time.Now())
+}
+
+// parseRFC3164Timestamp parses timestamp in RFC3164 (BSD Syslog) format without year and timezone
+// ex: Aug 15 15:50:46
+// ex: Aug  5 15:50:46
+//
+// The year is of the given current time, or the previous year if the result would be more than a month ahead (around
+// new year)
+func parseRFC3164Timestamp(timeStr string, location *time.Location, now time.Time) (time.Time, error) {
+	t := timeStr
+	if len(t) != 15 || t[3] != ' ' || t[6] != ' ' || t[9] != ':' || t[12] != ':' {
+		return time.Now(), fmt.Errorf("invalid timestamp")
+	}
+	month := parseMonth(t[0:3])
+	if month == 0 {
+		return time.Now(), fmt.Errorf("invalid month '%s'", t[0:3])
+	}
+	var date int
+	if t[4] == ' ' {
+		date = int(t[5] - '0')
+	} else {
+		date = atoi2(t[4:6])
+	}
+	hour := atoi2(t[7:9])
+	min := atoi2(t[10:12])
+	sec := atoi2(t[13:15])
+	year := now.In(location).Year()
+	tm := time.Date(year, month, date, hour, min, sec, 0, location)
+	if tm.After(now.AddDate(0, 1, 0)) {
+		tm = time.Date(year-1, month, date, hour, min, sec, 0, location)
+	}
+	return tm, nil
+}
@@ -5,14 +5,30 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/relex/slog-agent/util"
 )
 
 // parseRFC3339Timestamp parse timestamp in RFC3339 format with fraction part of variable size
 // ex: 2019-08-15T15:50:46.866915+03:00
 // ex: 2019-08-15T15:50:46.866Z
-func parseRFC3339Timestamp(timeStr string, timezoneCache map[string]*time.Location) (time.Time, error) {
+//
+// The defaultLocation is used if timezone is missing
+func parseRFC3339Timestamp(timeStr string, timezoneCache map[string]*time.Location, defaultLocation *time.Location) (time.Time, error) {
+	return parseISO8601Timestamp(timeStr, 'T', timezoneCache, defaultLocation)
+}
+
+// parseJavaTimestamp parses timestamp in the default format of Java logging libraries, with optional timezone
+// ex: 2019-08-15 15:50:46,866
+// ex: 2019-08-15 15:50:46.866+03:00
+func parseJavaTimestamp(timeStr string, timezoneCache map[string]*time.Location, defaultLocation *time.Location) (time.Time, error) {
+	return parseISO8601Timestamp(timeStr, ' ', timezoneCache, defaultLocation)
+}
+
+// parseISO8601Timestamp parses timestamp like RFC3339 with the given date-time separator and optional timezone
+func parseISO8601Timestamp(timeStr string, separator byte, timezoneCache map[string]*time.Location, defaultLocation *time.Location) (time.Time, error) {
 	t := timeStr
-	if t[4] != '-' || t[7] != '-' || t[10] != 'T' || t[13] != ':' || t[16] != ':' {
+	if len(t) < 19 || t[4] != '-' || t[7] != '-' || t[10] != separator || t[13] != ':' || t[16] != ':' {
 		return time.Now(), fmt.Errorf("invalid timestamp")
 	}
 	year := atoi4(t[0:4])
@@ -21,52 +37,27 @@ func parseRFC3339Timestamp(timeStr string, timezoneCache map[string]*time.Locati
 	hour := atoi2(t[11:13])
 	min := atoi2(t[14:16])
 	sec := atoi2(t[17:19])
-	var frac float64
 	fracStr, tzStr := splitFractionAndTimezone(t[19:])
-	switch len(fracStr) - 1 {
-	case -1:
-		frac = 0.0
-	case 3:
-		frac = atof3(fracStr)
-	case 6:
-		frac = atof6(fracStr)
-	case 9:
-		frac = atof9(fracStr)
-	default:
-		f, err := strconv.ParseFloat(fracStr, 64)
-		if err != nil {
-			return time.Now(), fmt.Errorf("invalid fraction '%s': %w", fracStr, err)
-		}
-		frac = f
+	nsec, ferr := parseFraction(fracStr)
+	if ferr != nil {
+		return time.Now(), ferr
 	}
 	var location *time.Location
 	if len(tzStr) > 0 {
-		if loc, ok := timezoneCache[tzStr]; ok {
-			location = loc
-		} else {
-			var layout string
-			if strings.Contains(tzStr, ":") {
-				layout = "Z07:00"
-			} else {
-				layout = "Z0700"
-			}
-			z, err := time.Parse(layout, tzStr)
-			if err != nil {
-				return time.Now(), fmt.Errorf("invalid timezone '%s': %w", tzStr, err)
-			}
-			tzName, tzOffset := z.Zone()
-			location = time.FixedZone(tzName, tzOffset)
-			timezoneCache[tzStr] = location
+		loc, err := getFixedZone(tzStr, timezoneCache)
+		if err != nil {
+			return time.Now(), err
 		}
+		location = loc
 	} else {
-		location = time.Local
+		location = defaultLocation
 	}
-	return time.Date(year, time.Month(month), date, hour, min, sec, int(frac*1000000000.0), location), nil
+	return time.Date(year, time.Month(month), date, hour, min, sec, nsec, location), nil
 }
 
 // splitFractionAndTimezone splits e.g. ".123+07:00" to .123 and +07:00
 func splitFractionAndTimezone(s string) (string, string) {
-	if len(s) > 1 && s[0] == '.' {
+	if len(s) > 1 && (s[0] == '.' || s[0] == ',') {
 		i := 1
 		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
 			i++
@@ -75,3 +66,46 @@ func splitFractionAndTimezone(s string) (string, string) {
 	}
 	return "", s
 }
+
+// parseFraction parses fraction of second including the leading separator, e.g. ".123", into nanoseconds
+func parseFraction(fracStr string) (int, error) {
+	var frac float64
+	switch len(fracStr) - 1 {
+	case -1:
+		frac = 0.0
+	case 3:
+		frac = atof3(fracStr)
+	case 6:
+		frac = atof6(fracStr)
+	case 9:
+		frac = atof9(fracStr)
+	default:
+		f, err := strconv.ParseFloat("."+fracStr[1:], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid fraction '%s': %w", fracStr, err)
+		}
+		frac = f
+	}
+	return int(frac * 1000000000.0), nil
+}
+
+// getFixedZone parses timezone offset such as "Z", "+07:00" or "-0800", with results cached by the original string
+func getFixedZone(tzStr string, timezoneCache map[string]*time.Location) (*time.Location, error) {
+	if loc, ok := timezoneCache[tzStr]; ok {
+		return loc, nil
+	}
+	var layout string
+	if strings.Contains(tzStr, ":") {
+		layout = "Z07:00"
+	} else {
+		layout = "Z0700"
+	}
+	z, err := time.Parse(layout, tzStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone '%s': %w", tzStr, err)
+	}
+	tzName, tzOffset := z.Zone()
+	location := time.FixedZone(tzName, tzOffset)
+	timezoneCache[util.DeepCopyString(tzStr)] = location
+	return location, nil
+}
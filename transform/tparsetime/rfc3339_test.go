@@ -25,7 +25,7 @@ var testCases = []testCase{
 func TestParseRFC3339Timestamp(t *testing.T) {
 	timezoneCache := make(map[string]*time.Location)
 	for _, tc := range testCases {
-		ourTime, err := parseRFC3339Timestamp(tc.timestamp, timezoneCache)
+		ourTime, err := parseRFC3339Timestamp(tc.timestamp, timezoneCache, time.Local)
 		assert.NoError(t, err, tc.timestamp+" our parsing")
 		theirTime, err := time.Parse(tc.layout, tc.timestamp)
 		assert.NoError(t, err, tc.timestamp+" go parsing")
@@ -1,5 +1,7 @@
 // Package tparsetime provides 'parseTime' transform to parses timestamp from a given field.
-// Currently only the RFC 3339 timestamp format used in Syslog RFC 5424 is supported.
+//
+// Common formats are parsed by hand-written parsers, including the RFC 3339 timestamp format used in Syslog RFC 5424.
+// Other formats can be specified as Go layouts, which are much slower.
 package tparsetime
 
 import (
@@ -14,24 +16,42 @@ import (
 // Config for parseTimeTransform
 type Config struct {
 	bconfig.Header `yaml:",inline"`
-	Key            string `yaml:"key"`
-	ErrorLabel     string `yaml:"errorLabel"`
+	Key            string   `yaml:"key"`
+	Formats        []string `yaml:"formats,omitempty"`  // format names or Go layouts to try in order, default rfc3339
+	Timezone       string   `yaml:"timezone,omitempty"` // IANA timezone name for formats without offset, default local
+	ErrorLabel     string   `yaml:"errorLabel"`
 }
 
 type parseTimeTransform struct {
-	keyLocator    base.LogFieldLocator
-	timezoneCache map[string]*time.Location
-	errorLogger   logger.Logger
-	errorCounter  func(length int)
+	keyLocator      base.LogFieldLocator
+	parsers         []timestampParser
+	timezoneCache   map[string]*time.Location
+	defaultLocation *time.Location
+	errorLogger     logger.Logger
+	errorCounter    func(length int)
 }
 
 // NewTransform creates parseTimeTransform
 func (cfg *Config) NewTransform(schema base.LogSchema, parentLogger logger.Logger, customCounterRegistry base.LogCustomCounterRegistry) base.LogTransform {
+	location, lerr := cfg.getDefaultLocation()
+	if lerr != nil {
+		logger.Panic(lerr)
+	}
+	parsers := make([]timestampParser, 0, len(cfg.getFormats()))
+	for _, format := range cfg.getFormats() {
+		parser, err := getParser(format)
+		if err != nil {
+			logger.Panic(err)
+		}
+		parsers = append(parsers, parser)
+	}
 	tf := &parseTimeTransform{
-		keyLocator:    schema.MustCreateFieldLocator(cfg.Key),
-		timezoneCache: make(map[string]*time.Location, 100),
-		errorLogger:   parentLogger,
-		errorCounter:  customCounterRegistry.RegisterCustomCounter(cfg.ErrorLabel),
+		keyLocator:      schema.MustCreateFieldLocator(cfg.Key),
+		parsers:         parsers,
+		timezoneCache:   make(map[string]*time.Location, 100),
+		defaultLocation: location,
+		errorLogger:     parentLogger,
+		errorCounter:    customCounterRegistry.RegisterCustomCounter(cfg.ErrorLabel),
 	}
 	return tf
 }
@@ -44,24 +64,50 @@ func (cfg *Config) VerifyConfig(schema base.LogSchema) error {
 	if _, err := schema.CreateFieldLocator(cfg.Key); err != nil {
 		return fmt.Errorf(".key '%s' is invalid: %w", cfg.Key, err)
 	}
+	for i, format := range cfg.Formats {
+		if _, err := getParser(format); err != nil {
+			return fmt.Errorf(".formats[%d]: %w", i, err)
+		}
+	}
+	if _, err := cfg.getDefaultLocation(); err != nil {
+		return fmt.Errorf(".timezone: %w", err)
+	}
 	if len(cfg.ErrorLabel) == 0 {
 		return fmt.Errorf(".errorLabel is unspecified")
 	}
 	return nil
 }
 
+func (cfg *Config) getFormats() []string {
+	if len(cfg.Formats) == 0 {
+		return []string{defaultFormat}
+	}
+	return cfg.Formats
+}
+
+func (cfg *Config) getDefaultLocation() (*time.Location, error) {
+	if len(cfg.Timezone) == 0 {
+		return time.Local, nil
+	}
+	return time.LoadLocation(cfg.Timezone)
+}
+
 func (tf *parseTimeTransform) Transform(record *base.LogRecord) base.FilterResult {
 	value := tf.keyLocator.Get(record.Fields)
 	if len(value) == 0 {
 		return base.PASS
 	}
-	tm, err := parseRFC3339Timestamp(value, tf.timezoneCache)
-	if err != nil {
-		tf.errorCounter(record.RawLength)
-		// TODO: omit repeated warnings
-		tf.errorLogger.Warnf("failed to parse timestamp: '%s': %s", value, err.Error())
-	} else {
-		record.Timestamp = tm
+	var err error
+	for _, parse := range tf.parsers {
+		var tm time.Time
+		tm, err = parse(value, tf.timezoneCache, tf.defaultLocation)
+		if err == nil {
+			record.Timestamp = tm
+			return base.PASS
+		}
 	}
+	tf.errorCounter(record.RawLength)
+	// TODO: omit repeated warnings
+	tf.errorLogger.Warnf("failed to parse timestamp: '%s': %s", value, err.Error())
 	return base.PASS
 }
@@ -61,3 +61,39 @@ errorLabel: timeError
 		assert.Equal(t, base.PASS, status)
 	}
 }
+
+func TestParseTimeTransformFormats(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"time"})
+	c := &Config{}
+
+	if !assert.NoError(t, util.UnmarshalYamlString(`
+type: parseTime
+key: time
+formats: [unixMs, unix, commonLog, java]
+timezone: Asia/Tokyo
+errorLabel: timeError
+`, c)) {
+		return
+	}
+	if !assert.NoError(t, c.VerifyConfig(schema)) {
+		return
+	}
+
+	reg, _ := btest.NewStubLogCustomCounterRegistry()
+	tf := c.NewTransform(schema, logger.Root(), reg)
+	for _, value := range []string{"1565873446866", "1565873446.866", "15/Aug/2019:15:50:46.866 +0300", "2019-08-15 21:50:46,866"} {
+		record := schema.NewTestRecord2(time.Time{}, base.LogFields{value})
+		assert.Equal(t, base.PASS, tf.Transform(record))
+		if value[2] != '/' {
+			assert.Equal(t, int64(1565873446866), record.Timestamp.UnixMilli(), value)
+		} else {
+			assert.True(t, record.Timestamp.IsZero(), value) // fraction isn't allowed in common log format
+		}
+	}
+
+	c.Timezone = "Mars/Olympus_Mons"
+	assert.ErrorContains(t, c.VerifyConfig(schema), ".timezone: ")
+	c.Timezone = ""
+	c.Formats = []string{"java", "none"}
+	assert.EqualError(t, c.VerifyConfig(schema), ".formats[1]: unknown format or invalid layout 'none'")
+}
@@ -0,0 +1,48 @@
+package tparsetime
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// parseUnixTimestamp parses seconds since UNIX epoch with optional fraction
+// ex: 1565873446
+// ex: 1565873446.866915
+func parseUnixTimestamp(timeStr string, _ map[string]*time.Location, _ *time.Location) (time.Time, error) {
+	secStr, fracStr := timeStr, ""
+	if i := strings.IndexByte(timeStr, '.'); i >= 0 {
+		secStr, fracStr = timeStr[:i], timeStr[i:]
+	}
+	sec, ok := atoiN(secStr, 12)
+	if len(fracStr) > 0 {
+		_, fracOk := atoiN(fracStr[1:], 9)
+		ok = ok && fracOk
+	}
+	if !ok {
+		return time.Now(), fmt.Errorf("invalid timestamp")
+	}
+	nsec, err := parseFraction(fracStr)
+	if err != nil {
+		return time.Now(), err
+	}
+	return time.Unix(sec, int64(nsec)), nil
+}
+
+// unixMsLength is the exact number of digits accepted as milliseconds, from 2001-09-09 to 2286-11-20
+//
+// Shorter numbers are rejected to not mistake timestamps in seconds as milliseconds when unixMs is tried before unix
+const unixMsLength = 13
+
+// parseUnixMsTimestamp parses milliseconds since UNIX epoch, in exactly 13 digits
+// ex: 1565873446866
+func parseUnixMsTimestamp(timeStr string, _ map[string]*time.Location, _ *time.Location) (time.Time, error) {
+	if len(timeStr) != unixMsLength {
+		return time.Now(), fmt.Errorf("invalid timestamp")
+	}
+	msec, ok := atoiN(timeStr, unixMsLength)
+	if !ok {
+		return time.Now(), fmt.Errorf("invalid timestamp")
+	}
+	return time.UnixMilli(msec), nil
+}
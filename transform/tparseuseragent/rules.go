@@ -0,0 +1,150 @@
+package tparseuseragent
+
+import (
+	"strings"
+)
+
+// productRule matches a browser, client or OS by a token in User-Agent
+type productRule struct {
+	token      string            // substring to look for
+	name       string            // product name
+	version    string            // token right before the version, defaults to token itself
+	versionMap map[string]string // optional mapping of versions to display names
+	bot        bool              // whether the product is a crawler
+}
+
+// browserRules are checked in order, so that products imitating others (e.g. Edge having "Chrome/" and "Safari/")
+// have to come before the imitated ones
+var browserRules = []productRule{
+	{token: "Googlebot/", name: "Googlebot", bot: true},
+	{token: "bingbot/", name: "Bingbot", bot: true},
+	{token: "YandexBot/", name: "YandexBot", bot: true},
+	{token: "DuckDuckBot/", name: "DuckDuckBot", bot: true},
+	{token: "Baiduspider/", name: "Baiduspider", bot: true},
+	{token: "Edg/", name: "Edge"},
+	{token: "EdgA/", name: "Edge"},
+	{token: "EdgiOS/", name: "Edge"},
+	{token: "OPR/", name: "Opera"},
+	{token: "SamsungBrowser/", name: "Samsung Internet"},
+	{token: "YaBrowser/", name: "Yandex Browser"},
+	{token: "FxiOS/", name: "Firefox"},
+	{token: "Firefox/", name: "Firefox"},
+	{token: "CriOS/", name: "Chrome"},
+	{token: "Chromium/", name: "Chromium"},
+	{token: "Chrome/", name: "Chrome"},
+	{token: "Safari/", name: "Safari", version: "Version/"},
+	{token: "Trident/", name: "IE", version: "rv:"},
+	{token: "MSIE ", name: "IE"},
+	{token: "curl/", name: "curl"},
+	{token: "Wget/", name: "Wget"},
+	{token: "python-requests/", name: "python-requests"},
+	{token: "Go-http-client/", name: "Go-http-client"},
+	{token: "okhttp/", name: "okhttp"},
+}
+
+// osRules are checked in order, e.g. iOS has "like Mac OS X" and Android has "Linux"
+var osRules = []productRule{
+	{token: "Windows Phone", name: "Windows Phone", version: "Windows Phone "},
+	{token: "Windows NT ", name: "Windows", versionMap: map[string]string{
+		"10.0": "10",
+		"6.3":  "8.1",
+		"6.2":  "8",
+		"6.1":  "7",
+		"6.0":  "Vista",
+		"5.1":  "XP",
+	}},
+	{token: "iPhone OS ", name: "iOS"},
+	{token: "CPU OS ", name: "iOS"},
+	{token: "Android", name: "Android", version: "Android "},
+	{token: "CrOS ", name: "Chrome OS"},
+	{token: "Mac OS X", name: "macOS", version: "Mac OS X "},
+	{token: "Linux", name: "Linux"},
+}
+
+// botTokens mark generic crawlers in addition to bots in browserRules
+var botTokens = []string{"bot/", "Bot/", "spider", "crawler", "+http"}
+
+// userAgentInfo is the result of parsing an User-Agent
+type userAgentInfo struct {
+	browser        string
+	browserVersion string
+	os             string
+	osVersion      string
+	device         string // "bot", "mobile", "tablet", "desktop" or "other"
+}
+
+// parseUserAgent parses the given User-Agent by built-in rules. Unrecognized attributes are left empty.
+//
+// Returned strings may refer to the input
+func parseUserAgent(ua string) userAgentInfo {
+	info := userAgentInfo{}
+	var browserRuleIndex int
+	info.browser, info.browserVersion, browserRuleIndex = matchProduct(browserRules, ua)
+	info.os, info.osVersion, _ = matchProduct(osRules, ua)
+
+	switch {
+	case browserRuleIndex != -1 && browserRules[browserRuleIndex].bot, containsAny(ua, botTokens):
+		info.device = "bot"
+	case strings.Contains(ua, "iPad") || strings.Contains(ua, "Tablet"),
+		info.os == "Android" && !strings.Contains(ua, "Mobile"):
+		info.device = "tablet"
+	case strings.Contains(ua, "Mobi") || strings.Contains(ua, "iPhone") || info.os == "Windows Phone":
+		info.device = "mobile"
+	case info.os == "Windows" || info.os == "macOS" || info.os == "Linux" || info.os == "Chrome OS":
+		info.device = "desktop"
+	default:
+		info.device = "other"
+	}
+	return info
+}
+
+// matchProduct returns the name and version of the first rule matched and its index, or -1 if none
+func matchProduct(rules []productRule, ua string) (string, string, int) {
+	for i, rule := range rules {
+		pos := strings.Index(ua, rule.token)
+		if pos == -1 {
+			continue
+		}
+		var version string
+		if len(rule.version) == 0 {
+			version = extractVersion(ua[pos+len(rule.token):])
+		} else if vpos := strings.Index(ua, rule.version); vpos != -1 {
+			version = extractVersion(ua[vpos+len(rule.version):])
+		}
+		if rule.versionMap != nil {
+			version = rule.versionMap[version]
+		}
+		return rule.name, version, i
+	}
+	return "", "", -1
+}
+
+// extractVersion returns the leading version number in s, with underscore separators (as in iOS and macOS) replaced by
+// dots
+func extractVersion(s string) string {
+	end := 0
+	hasUnderscore := false
+	for end < len(s) {
+		c := s[end]
+		if c == '_' {
+			hasUnderscore = true
+		} else if (c < '0' || c > '9') && c != '.' {
+			break
+		}
+		end++
+	}
+	version := strings.TrimRight(s[:end], "._")
+	if hasUnderscore {
+		version = strings.ReplaceAll(version, "_", ".")
+	}
+	return version
+}
+
+func containsAny(s string, tokens []string) bool {
+	for _, token := range tokens {
+		if strings.Contains(s, token) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+// Package tparseuseragent provides 'parseUserAgent' transform, which extracts browser, OS and device class from an
+// User-Agent field into other fields, by a compact set of built-in rules for common browsers, clients and crawlers.
+//
+// Results are cached per worker since User-Agent strings repeat heavily in access logs.
+package tparseuseragent
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/relex/gotils/logger"
+	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/base/bconfig"
+	"github.com/relex/slog-agent/util"
+	"github.com/relex/slog-agent/util/lrucache"
+	"github.com/samber/lo"
+)
+
+// Config for parseUserAgentTransform
+type Config struct {
+	bconfig.Header `yaml:",inline"`
+	Key            string            `yaml:"key"`
+	Fields         map[string]string `yaml:"fields"`    // destination field to attribute, see attributeGetters
+	CacheSize      int               `yaml:"cacheSize"` // default 1000
+}
+
+type parseUserAgentTransform struct {
+	keyLocator   base.LogFieldLocator
+	destLocators []base.LogFieldLocator
+	getters      []attributeGetter
+	cache        *lrucache.LRUCache[string, []string]
+}
+
+type attributeGetter func(info *userAgentInfo) string
+
+var attributeGetters = map[string]attributeGetter{
+	"browser":        func(info *userAgentInfo) string { return info.browser },
+	"browserVersion": func(info *userAgentInfo) string { return info.browserVersion },
+	"os":             func(info *userAgentInfo) string { return info.os },
+	"osVersion":      func(info *userAgentInfo) string { return info.osVersion },
+	"device":         func(info *userAgentInfo) string { return info.device },
+}
+
+const defaultCacheSize = 1000
+
+// NewTransform creates parseUserAgentTransform
+func (c *Config) NewTransform(schema base.LogSchema, _ logger.Logger, _ base.LogCustomCounterRegistry) base.LogTransform {
+	destFields, attributes := c.getSortedFieldsAndAttributes()
+	cacheSize := c.CacheSize
+	if cacheSize == 0 {
+		cacheSize = defaultCacheSize
+	}
+	return &parseUserAgentTransform{
+		keyLocator:   schema.MustCreateFieldLocator(c.Key),
+		destLocators: schema.MustCreateFieldLocators(destFields),
+		getters: lo.Map(attributes, func(attr string, _ int) attributeGetter {
+			return attributeGetters[attr]
+		}),
+		cache: lrucache.NewLRUCache[string, []string](cacheSize),
+	}
+}
+
+// VerifyConfig verifies parseUserAgentTransform config
+func (c *Config) VerifyConfig(schema base.LogSchema) error {
+	if len(c.Key) == 0 {
+		return fmt.Errorf(".key is unspecified")
+	}
+	if _, err := schema.CreateFieldLocator(c.Key); err != nil {
+		return fmt.Errorf(".key: %w", err)
+	}
+	if len(c.Fields) == 0 {
+		return fmt.Errorf(".fields is empty")
+	}
+	for dstKey, attr := range c.Fields {
+		if _, err := schema.CreateFieldLocator(dstKey); err != nil {
+			return fmt.Errorf(".fields[%s] is invalid: %w", dstKey, err)
+		}
+		if dstKey == c.Key {
+			return fmt.Errorf(".fields[%s] is the same as .key", dstKey)
+		}
+		if _, ok := attributeGetters[attr]; !ok {
+			return fmt.Errorf(".fields[%s] has unknown attribute '%s'", dstKey, attr)
+		}
+	}
+	if c.CacheSize < 0 {
+		return fmt.Errorf(".cacheSize cannot be negative")
+	}
+	return nil
+}
+
+// getSortedFieldsAndAttributes returns destination fields and their attributes in a stable order
+func (c *Config) getSortedFieldsAndAttributes() ([]string, []string) {
+	destFields := lo.Keys(c.Fields)
+	sort.Strings(destFields)
+	attributes := lo.Map(destFields, func(field string, _ int) string {
+		return c.Fields[field]
+	})
+	return destFields, attributes
+}
+
+func (tf *parseUserAgentTransform) Transform(record *base.LogRecord) base.FilterResult {
+	fields := record.Fields
+	ua := tf.keyLocator.Get(fields)
+	if len(ua) == 0 {
+		return base.PASS
+	}
+
+	var values []string
+	if cached := tf.cache.Get(ua); cached != nil {
+		values = *cached
+	} else {
+		values = tf.parse(ua)
+		tf.cache.Add(util.DeepCopyString(ua), values, nil)
+	}
+	for i, loc := range tf.destLocators {
+		if value := values[i]; len(value) > 0 {
+			loc.Set(fields, value)
+		}
+	}
+	return base.PASS
+}
+
+// parse returns the values of destination fields for the given User-Agent, copied to be cached
+func (tf *parseUserAgentTransform) parse(ua string) []string {
+	info := parseUserAgent(ua)
+	values := make([]string, len(tf.getters))
+	for i, getter := range tf.getters {
+		values[i] = util.DeepCopyString(getter(&info))
+	}
+	return values
+}
@@ -0,0 +1,116 @@
+package tparseuseragent
+
+import (
+	"testing"
+
+	"github.com/relex/gotils/logger"
+	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseUserAgentTransform(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"ua", "browser", "browserVersion", "os", "osVersion", "device"})
+	c := &Config{}
+	if !assert.NoError(t, util.UnmarshalYamlString(`
+type: parseUserAgent
+key: ua
+fields:
+  browser: browser
+  browserVersion: browserVersion
+  os: os
+  osVersion: osVersion
+  device: device
+cacheSize: 2
+`, c)) {
+		return
+	}
+	if !assert.NoError(t, c.VerifyConfig(schema)) {
+		return
+	}
+	tf := c.NewTransform(schema, logger.Root(), nil)
+
+	cases := []struct {
+		ua       string
+		expected base.LogFields
+	}{
+		{
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			base.LogFields{"Chrome", "120.0.0.0", "Windows", "10", "desktop"},
+		},
+		{
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.2210.91",
+			base.LogFields{"Edge", "120.0.2210.91", "Windows", "10", "desktop"},
+		},
+		{
+			"Mozilla/5.0 (iPhone; CPU iPhone OS 17_1_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1.2 Mobile/15E148 Safari/604.1",
+			base.LogFields{"Safari", "17.1.2", "iOS", "17.1.2", "mobile"},
+		},
+		{
+			"Mozilla/5.0 (iPad; CPU OS 16_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/119.0.6045.169 Mobile/15E148 Safari/604.1",
+			base.LogFields{"Chrome", "119.0.6045.169", "iOS", "16.6", "tablet"},
+		},
+		{
+			"Mozilla/5.0 (Linux; Android 13; SM-S908B) AppleWebKit/537.36 (KHTML, like Gecko) SamsungBrowser/23.0 Chrome/115.0.0.0 Mobile Safari/537.36",
+			base.LogFields{"Samsung Internet", "23.0", "Android", "13", "mobile"},
+		},
+		{
+			"Mozilla/5.0 (Linux; Android 12; SM-X700) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Safari/537.36",
+			base.LogFields{"Chrome", "118.0.0.0", "Android", "12", "tablet"},
+		},
+		{
+			"Mozilla/5.0 (Macintosh; Intel Mac OS X 10.15; rv:121.0) Gecko/20100101 Firefox/121.0",
+			base.LogFields{"Firefox", "121.0", "macOS", "10.15", "desktop"},
+		},
+		{
+			"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 OPR/106.0.0.0",
+			base.LogFields{"Opera", "106.0.0.0", "Linux", "", "desktop"},
+		},
+		{
+			"Mozilla/5.0 (Windows NT 6.1; WOW64; Trident/7.0; rv:11.0) like Gecko",
+			base.LogFields{"IE", "11.0", "Windows", "7", "desktop"},
+		},
+		{
+			"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			base.LogFields{"Googlebot", "2.1", "", "", "bot"},
+		},
+		{
+			"Mozilla/5.0 (compatible; SemrushBot/7~bl; +http://www.semrush.com/bot.html)",
+			base.LogFields{"", "", "", "", "bot"},
+		},
+		{
+			"curl/8.4.0",
+			base.LogFields{"curl", "8.4.0", "", "", "other"},
+		},
+	}
+	for round := 0; round < 2; round++ { // the last two are cached in second round
+		for _, cs := range cases {
+			record := schema.NewTestRecord1(base.LogFields{cs.ua, "", "", "", "", ""})
+			assert.Equal(t, base.PASS, tf.Transform(record))
+			assert.Equal(t, cs.expected, record.Fields[1:], cs.ua)
+		}
+	}
+
+	record := schema.NewTestRecord1(base.LogFields{"", "", "", "", "", "old"})
+	assert.Equal(t, base.PASS, tf.Transform(record))
+	assert.Equal(t, base.LogFields{"", "", "", "", "", "old"}, record.Fields)
+}
+
+func TestParseUserAgentConfig(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"ua", "browser"})
+	c := &Config{}
+	assert.NoError(t, util.UnmarshalYamlString(`
+type: parseUserAgent
+key: ua
+fields:
+  browser: engine
+`, c))
+	assert.EqualError(t, c.VerifyConfig(schema), ".fields[browser] has unknown attribute 'engine'")
+}
+
+func BenchmarkParseUserAgent(b *testing.B) {
+	ua := "Mozilla/5.0 (Linux; Android 13; SM-S908B) AppleWebKit/537.36 (KHTML, like Gecko) SamsungBrowser/23.0 Chrome/115.0.0.0 Mobile Safari/537.36"
+	for i := 0; i < b.N; i++ {
+		parseUserAgent(ua)
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"github.com/relex/gotils/logger"
 	"github.com/relex/slog-agent/base"
 	"github.com/relex/slog-agent/base/bconfig"
+	"github.com/relex/slog-agent/util"
+	"github.com/relex/slog-agent/util/emailredact"
 )
 
 // Config for redactEmailTransform
@@ -49,13 +51,13 @@ func (tf *redactEmailTransform) Transform(record *base.LogRecord) base.FilterRes
 	if len(value) == 0 {
 		return base.PASS
 	}
-	first := redactEmailFindFirst(value)
+	first := emailredact.FindFirst(value)
 	if first == -1 {
 		return base.PASS
 	}
-	newValue, numRedacted := redactEmail1(value, first)
+	newValue, numRedacted := emailredact.AppendRedacted(make([]byte, 0, len(value)), value, first)
 	if numRedacted > 0 {
-		tf.keyLocator.Set(record.Fields, newValue)
+		tf.keyLocator.Set(record.Fields, util.StringFromBytes(newValue))
 		tf.counter(record.RawLength)
 	}
 	return base.PASS
@@ -0,0 +1,175 @@
+// Package tredactquery provides 'redactQuery' transform to redact values of query parameters in URLs, e.g. tokens in
+// "GET /login?token=...&lang=en HTTP/1.1", by a single scan without regex.
+//
+// Only the first request-target in a field is handled: the query string after a '?' in a word starting with '/' or
+// containing "://", and ending before a space, a double quote or a '#'.
+//
+// Parameter names are compared after decoding percent-escapes and '+' as servers would, e.g. "%74oken" as "token".
+package tredactquery
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/relex/gotils/logger"
+	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/base/bconfig"
+	"github.com/relex/slog-agent/util"
+)
+
+// Config for redactQueryTransform
+type Config struct {
+	bconfig.Header `yaml:",inline"`
+	Key            string   `yaml:"key"`
+	Params         []string `yaml:"params"`      // names of parameters to redact
+	AllowParams    []string `yaml:"allowParams"` // names of parameters to keep, to redact all others. Exclusive with params
+	Replacement    string   `yaml:"replacement"` // default "REDACTED"
+	MetricLabel    string   `yaml:"metricLabel"`
+}
+
+type redactQueryTransform struct {
+	keyLocator  base.LogFieldLocator
+	names       map[string]bool // names to redact, or to keep if allowMode
+	allowMode   bool
+	replacement string
+	counter     func(length int)
+}
+
+const defaultReplacement = "REDACTED"
+
+// NewTransform creates redactQueryTransform
+func (cfg *Config) NewTransform(schema base.LogSchema, _ logger.Logger, customCounterRegistry base.LogCustomCounterRegistry) base.LogTransform {
+	tf := &redactQueryTransform{
+		keyLocator:  schema.MustCreateFieldLocator(cfg.Key),
+		names:       make(map[string]bool, len(cfg.Params)+len(cfg.AllowParams)),
+		allowMode:   len(cfg.AllowParams) > 0,
+		replacement: cfg.Replacement,
+		counter:     customCounterRegistry.RegisterCustomCounter(cfg.MetricLabel),
+	}
+	for _, name := range cfg.Params {
+		tf.names[name] = true
+	}
+	for _, name := range cfg.AllowParams {
+		tf.names[name] = true
+	}
+	if len(tf.replacement) == 0 {
+		tf.replacement = defaultReplacement
+	}
+	return tf
+}
+
+// VerifyConfig verifies redactQueryTransform config
+func (cfg *Config) VerifyConfig(schema base.LogSchema) error {
+	if len(cfg.Key) == 0 {
+		return fmt.Errorf(".key is unspecified")
+	}
+	if _, err := schema.CreateFieldLocator(cfg.Key); err != nil {
+		return fmt.Errorf(".key '%s' is invalid: %w", cfg.Key, err)
+	}
+	if len(cfg.Params) == 0 && len(cfg.AllowParams) == 0 {
+		return fmt.Errorf("either .params or .allowParams must be specified")
+	}
+	if len(cfg.Params) > 0 && len(cfg.AllowParams) > 0 {
+		return fmt.Errorf(".params and .allowParams cannot be both specified")
+	}
+	for i, name := range cfg.Params {
+		if len(name) == 0 {
+			return fmt.Errorf(".params[%d] is empty", i)
+		}
+	}
+	for i, name := range cfg.AllowParams {
+		if len(name) == 0 {
+			return fmt.Errorf(".allowParams[%d] is empty", i)
+		}
+	}
+	if len(cfg.MetricLabel) == 0 {
+		return fmt.Errorf(".metricLabel is unspecified")
+	}
+	return nil
+}
+
+func (tf *redactQueryTransform) Transform(record *base.LogRecord) base.FilterResult {
+	value := tf.keyLocator.Get(record.Fields)
+	if len(value) == 0 {
+		return base.PASS
+	}
+	queryStart, queryEnd := findQuery(value)
+	if queryStart == -1 {
+		return base.PASS
+	}
+	if newValue, redacted := tf.redact(value, queryStart, queryEnd); redacted {
+		tf.keyLocator.Set(record.Fields, newValue)
+		tf.counter(record.RawLength)
+	}
+	return base.PASS
+}
+
+// redact returns a new copy of value with parameter values in value[start:end] redacted, or the original if nothing
+// is redacted
+func (tf *redactQueryTransform) redact(value string, start int, end int) (string, bool) {
+	var dst []byte
+	copied := 0
+	for pos := start; pos < end; {
+		paramEnd := strings.IndexByte(value[pos:end], '&')
+		if paramEnd == -1 {
+			paramEnd = end
+		} else {
+			paramEnd += pos
+		}
+		if eq := strings.IndexByte(value[pos:paramEnd], '='); eq != -1 {
+			valueStart := pos + eq + 1
+			if valueStart < paramEnd && tf.names[decodeParamName(value[pos:valueStart-1])] != tf.allowMode {
+				if dst == nil {
+					dst = make([]byte, 0, len(value))
+				}
+				dst = append(dst, value[copied:valueStart]...)
+				dst = append(dst, tf.replacement...)
+				copied = paramEnd
+			}
+		}
+		pos = paramEnd + 1
+	}
+	if dst == nil {
+		return value, false
+	}
+	dst = append(dst, value[copied:]...)
+	return util.StringFromBytes(dst), true
+}
+
+// decodeParamName returns the name of parameter decoded from the query string, or the raw name if it's not encoded or
+// not validly encoded
+func decodeParamName(raw string) string {
+	if !strings.ContainsAny(raw, "%+") {
+		return raw
+	}
+	name, err := url.QueryUnescape(raw)
+	if err != nil {
+		return raw
+	}
+	return name
+}
+
+// findQuery locates the query string of the first request-target in value, without the leading '?'
+//
+// Returns (-1, -1) if not found
+func findQuery(value string) (int, int) {
+	for offset := 0; offset < len(value); {
+		q := strings.IndexByte(value[offset:], '?')
+		if q == -1 {
+			break
+		}
+		q += offset
+		targetStart := strings.LastIndexAny(value[:q], " \"") + 1
+		target := value[targetStart:q]
+		if strings.HasPrefix(target, "/") || strings.Contains(target, "://") {
+			queryEnd := strings.IndexAny(value[q+1:], " \"#")
+			if queryEnd == -1 {
+				return q + 1, len(value)
+			}
+			return q + 1, q + 1 + queryEnd
+		}
+		offset = q + 1
+	}
+	return -1, -1
+}
@@ -0,0 +1,72 @@
+package tredactquery
+
+import (
+	"testing"
+
+	"github.com/relex/gotils/logger"
+	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/base/btest"
+	"github.com/relex/slog-agent/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactQueryTransform(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"log"})
+	c := &Config{}
+	assert.NoError(t, util.UnmarshalYamlString(`
+type: redactQuery
+key: log
+params: [token, email, api key]
+metricLabel: query
+`, c))
+	assert.NoError(t, c.VerifyConfig(schema))
+	reg, _ := btest.NewStubLogCustomCounterRegistry()
+	tf := c.NewTransform(schema, logger.Root(), reg)
+	run := func(value string) string {
+		record := schema.NewTestRecord1(base.LogFields{value})
+		assert.Equal(t, base.PASS, tf.Transform(record))
+		return record.Fields[0]
+	}
+
+	assert.Equal(t, `1.2.3.4 "GET /login?token=REDACTED&lang=en&email=REDACTED HTTP/1.1" 200`,
+		run(`1.2.3.4 "GET /login?token=abc123&lang=en&email=foo@bar.com HTTP/1.1" 200`))
+	assert.Equal(t, `GET https://example.com/a?email=REDACTED#top`, run(`GET https://example.com/a?email=x%40y.com#top`))
+	assert.Equal(t, `GET /a?token=&tokens=1&token`, run(`GET /a?token=&tokens=1&token`))
+	assert.Equal(t, `why? GET /a?x=1&token=REDACTED`, run(`why? GET /a?x=1&token=secret`))
+	assert.Equal(t, `GET /a HTTP/1.1`, run(`GET /a HTTP/1.1`))
+
+	// encoded names are decoded before lookup
+	assert.Equal(t, `GET /a?%74oken=REDACTED&api+key=REDACTED&api%20key=REDACTED&%zz=1`,
+		run(`GET /a?%74oken=abc&api+key=def&api%20key=ghi&%zz=1`))
+}
+
+func TestRedactQueryTransformAllowParams(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"log"})
+	c := &Config{}
+	assert.NoError(t, util.UnmarshalYamlString(`
+type: redactQuery
+key: log
+allowParams: [page, lang]
+replacement: '***'
+metricLabel: query
+`, c))
+	assert.NoError(t, c.VerifyConfig(schema))
+	reg, _ := btest.NewStubLogCustomCounterRegistry()
+	tf := c.NewTransform(schema, logger.Root(), reg)
+	record := schema.NewTestRecord1(base.LogFields{`GET /search?q=my+secret&page=2&lang=en&sid=42 HTTP/1.1`})
+	tf.Transform(record)
+	assert.Equal(t, `GET /search?q=***&page=2&lang=en&sid=*** HTTP/1.1`, record.Fields[0])
+}
+
+func TestRedactQueryTransformVerify(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"log"})
+	verify := func(yaml string) error {
+		c := &Config{}
+		assert.NoError(t, util.UnmarshalYamlString(yaml, c))
+		return c.VerifyConfig(schema)
+	}
+	assert.EqualError(t, verify("type: redactQuery\nkey: log\nmetricLabel: q"), "either .params or .allowParams must be specified")
+	assert.EqualError(t, verify("type: redactQuery\nkey: log\nparams: [a]\nallowParams: [b]\nmetricLabel: q"),
+		".params and .allowParams cannot be both specified")
+	assert.EqualError(t, verify("type: redactQuery\nkey: log\nparams: [a]"), ".metricLabel is unspecified")
+}
@@ -0,0 +1,98 @@
+// Package tsanitize provides 'sanitize' transform to replace invalid UTF-8 sequences and C0 control characters anywhere in
+// fields, which could break consumers of JSON or other text formats, and optionally to limit the length of fields.
+//
+// Fields are only copied when changed, which should be rare.
+package tsanitize
+
+import (
+	"fmt"
+
+	"github.com/relex/gotils/logger"
+	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/base/bconfig"
+	"github.com/relex/slog-agent/util"
+)
+
+// Config for sanitizeTransform
+type Config struct {
+	bconfig.Header `yaml:",inline"`
+	Keys           []string `yaml:"keys"`
+	KeepChars      string   `yaml:"keepChars"`   // control characters to keep, e.g. "\t"
+	Replacement    *string  `yaml:"replacement"` // default U+FFFD, may be empty to delete
+	MaxLength      int      `yaml:"maxLength"`   // optional max length of fields in bytes
+	MetricLabel    string   `yaml:"metricLabel"` // label for repaired logs
+}
+
+type sanitizeTransform struct {
+	keyLocators []base.LogFieldLocator
+	keep        util.ControlCharSet
+	replacement string
+	maxLength   int
+	counter     func(length int)
+}
+
+const defaultReplacement = "\uFFFD"
+
+// NewTransform creates sanitizeTransform
+func (cfg *Config) NewTransform(schema base.LogSchema, _ logger.Logger, customCounterRegistry base.LogCustomCounterRegistry) base.LogTransform {
+	keep, _ := util.NewControlCharSet(cfg.KeepChars)
+	replacement := defaultReplacement
+	if cfg.Replacement != nil {
+		replacement = *cfg.Replacement
+	}
+	return &sanitizeTransform{
+		keyLocators: schema.MustCreateFieldLocators(cfg.Keys),
+		keep:        keep,
+		replacement: replacement,
+		maxLength:   cfg.MaxLength,
+		counter:     customCounterRegistry.RegisterCustomCounter(cfg.MetricLabel),
+	}
+}
+
+// VerifyConfig verifies sanitizeTransform config
+func (cfg *Config) VerifyConfig(schema base.LogSchema) error {
+	if len(cfg.Keys) == 0 {
+		return fmt.Errorf(".keys is unspecified")
+	}
+	if _, err := schema.CreateFieldLocators(cfg.Keys); err != nil {
+		return fmt.Errorf(".keys%w", err)
+	}
+	if _, ok := util.NewControlCharSet(cfg.KeepChars); !ok {
+		return fmt.Errorf(".keepChars %q contains non-control characters", cfg.KeepChars)
+	}
+	if cfg.Replacement != nil && util.FindUnsafeUTF8(*cfg.Replacement, 0) != -1 {
+		return fmt.Errorf(".replacement %q contains invalid or control characters", *cfg.Replacement)
+	}
+	if cfg.MaxLength < 0 {
+		return fmt.Errorf(".maxLength cannot be negative")
+	}
+	if len(cfg.MetricLabel) == 0 {
+		return fmt.Errorf(".metricLabel is unspecified")
+	}
+	return nil
+}
+
+func (tf *sanitizeTransform) Transform(record *base.LogRecord) base.FilterResult {
+	repaired := false
+	for _, loc := range tf.keyLocators {
+		value := loc.Get(record.Fields)
+		changed := false
+		if first := util.FindUnsafeUTF8(value, tf.keep); first != -1 {
+			value = util.StringFromBytes(util.SanitizeUTF8(make([]byte, 0, len(value)+len(tf.replacement)),
+				value, first, tf.replacement, tf.keep))
+			changed = true
+		}
+		if tf.maxLength > 0 && len(value) > tf.maxLength {
+			value = util.TruncateUTF8(value, tf.maxLength)
+			changed = true
+		}
+		if changed {
+			loc.Set(record.Fields, value)
+			repaired = true
+		}
+	}
+	if repaired {
+		tf.counter(record.RawLength)
+	}
+	return base.PASS
+}
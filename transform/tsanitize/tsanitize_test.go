@@ -0,0 +1,69 @@
+package tsanitize
+
+import (
+	"testing"
+
+	"github.com/relex/gotils/logger"
+	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/base/btest"
+	"github.com/relex/slog-agent/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitizeTransform(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"app", "log"})
+	c := &Config{}
+	assert.NoError(t, util.UnmarshalYamlString(`
+type: sanitize
+keys: [app, log]
+keepChars: "\t"
+maxLength: 12
+metricLabel: sanitized
+`, c))
+	assert.NoError(t, c.VerifyConfig(schema))
+	reg, lookupCounter := btest.NewStubLogCustomCounterRegistry()
+	tf := c.NewTransform(schema, logger.Root(), reg)
+	run := func(app string, log string) base.LogFields {
+		record := schema.NewTestRecord1(base.LogFields{app, log})
+		record.RawLength = len(app) + len(log)
+		assert.Equal(t, base.PASS, tf.Transform(record))
+		return record.Fields
+	}
+
+	assert.Equal(t, base.LogFields{"web", "a\tb"}, run("web", "a\tb"))
+	assert.Equal(t, base.LogFields{"web�", "a�b�"}, run("web\x1b", "a\xff\xfeb\x00"))
+	assert.Equal(t, base.LogFields{"web", "Hello, 世"}, run("web", "Hello, 世界"))
+	count, length := lookupCounter("sanitized")
+	assert.EqualValues(t, 2, count)
+	assert.EqualValues(t, 25, length)
+}
+
+func TestSanitizeTransformReplacement(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"log"})
+	c := &Config{}
+	assert.NoError(t, util.UnmarshalYamlString(`
+type: sanitize
+keys: [log]
+replacement: ""
+metricLabel: sanitized
+`, c))
+	assert.NoError(t, c.VerifyConfig(schema))
+	reg, _ := btest.NewStubLogCustomCounterRegistry()
+	tf := c.NewTransform(schema, logger.Root(), reg)
+
+	record := schema.NewTestRecord1(base.LogFields{"a\tb\r\n"})
+	assert.Equal(t, base.PASS, tf.Transform(record))
+	assert.Equal(t, "ab", record.Fields[0])
+}
+
+func TestSanitizeConfig(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"log"})
+	c := &Config{}
+	assert.NoError(t, util.UnmarshalYamlString(`
+type: sanitize
+keys: [log]
+keepChars: "\t "
+metricLabel: sanitized
+`, c))
+	assert.EqualError(t, c.VerifyConfig(schema), `.keepChars "\t " contains non-control characters`)
+}
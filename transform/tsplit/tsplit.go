@@ -0,0 +1,189 @@
+// Package tsplit provides 'split' transform, which splits a field containing multiple events into separate records,
+// either by a delimiter or as a JSON array. Each of the new records inherits all fields of the original one, except the
+// split field which is set to one of the parts.
+//
+// Split is only supported in top-level transformations of pipelines, and rejected elsewhere, e.g. inside "if".
+package tsplit
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/relex/gotils/logger"
+	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/base/bconfig"
+)
+
+// Config for splitTransform
+type Config struct {
+	bconfig.Header `yaml:",inline"`
+	Key            string `yaml:"key"`
+	Delimiter      string `yaml:"delimiter"`  // delimiter between parts, exclusive with JSONArray
+	JSONArray      bool   `yaml:"jsonArray"`  // whether to split elements of JSON array, exclusive with Delimiter
+	MaxRecords     int    `yaml:"maxRecords"` // max count of records to split into, the last one gets all the rest. Default 100
+}
+
+type splitTransform struct {
+	keyLocator base.LogFieldLocator
+	delimiter  string
+	jsonArray  bool
+	maxRecords int
+	parts      []string // reused buffer of parts
+}
+
+const defaultMaxRecords = 100
+
+// NewTransform creates splitTransform
+func (c *Config) NewTransform(schema base.LogSchema, _ logger.Logger, _ base.LogCustomCounterRegistry) base.LogTransform {
+	maxRecords := c.MaxRecords
+	if maxRecords == 0 {
+		maxRecords = defaultMaxRecords
+	}
+	return &splitTransform{
+		keyLocator: schema.MustCreateFieldLocator(c.Key),
+		delimiter:  c.Delimiter,
+		jsonArray:  c.JSONArray,
+		maxRecords: maxRecords,
+		parts:      make([]string, 0, maxRecords),
+	}
+}
+
+// TopLevelOnly marks splitTransform as only supported in top-level transformations
+func (c *Config) TopLevelOnly() {
+}
+
+// VerifyConfig verifies splitTransform config
+func (c *Config) VerifyConfig(schema base.LogSchema) error {
+	if len(c.Key) == 0 {
+		return fmt.Errorf(".key is unspecified")
+	}
+	if _, err := schema.CreateFieldLocator(c.Key); err != nil {
+		return fmt.Errorf(".key '%s' is invalid: %w", c.Key, err)
+	}
+	if len(c.Delimiter) == 0 && !c.JSONArray {
+		return fmt.Errorf(".delimiter is unspecified while .jsonArray is false")
+	}
+	if len(c.Delimiter) > 0 && c.JSONArray {
+		return fmt.Errorf(".delimiter and .jsonArray cannot be used together")
+	}
+	if c.MaxRecords < 0 || c.MaxRecords == 1 {
+		return fmt.Errorf(".maxRecords must be larger than one")
+	}
+	return nil
+}
+
+// Transform passes records without splitting, which is never called as split is only allowed at top level
+func (tf *splitTransform) Transform(_ *base.LogRecord) base.FilterResult {
+	return base.PASS
+}
+
+// ExpandRecord replaces the input with one record per part, or keeps it if there is no more than one part
+func (tf *splitTransform) ExpandRecord(input *base.LogRecord, allocator *base.LogAllocator, outputs []*base.LogRecord) []*base.LogRecord {
+	value := tf.keyLocator.Get(input.Fields)
+	var parts []string
+	if tf.jsonArray {
+		parts = splitJSONArray(value, tf.maxRecords, tf.parts[:0])
+	} else {
+		parts = splitByDelimiter(value, tf.delimiter, tf.maxRecords, tf.parts[:0])
+	}
+	defer clear(parts) // don't keep references to the input
+
+	switch len(parts) {
+	case 0:
+		return append(outputs, input)
+	case 1:
+		tf.keyLocator.Set(input.Fields, parts[0])
+		return append(outputs, input)
+	}
+	for _, part := range parts {
+		tf.keyLocator.Set(input.Fields, part)
+		derived := allocator.DeriveRecord(input)
+		derived.RawLength = input.RawLength * len(part) / len(value) // approximate to keep the total in statistics
+		outputs = append(outputs, derived)
+	}
+	return outputs
+}
+
+// splitByDelimiter appends non-empty parts of value to parts, with the rest in the last part after maxParts - 1
+func splitByDelimiter(value string, delimiter string, maxParts int, parts []string) []string {
+	for len(value) > 0 {
+		end := strings.Index(value, delimiter)
+		if end == -1 || len(parts) == maxParts-1 {
+			return append(parts, value)
+		}
+		if end > 0 {
+			parts = append(parts, value[:end])
+		}
+		value = value[end+len(delimiter):]
+	}
+	return parts
+}
+
+// splitJSONArray appends raw elements of the JSON array in value to parts, with the rest in the last part after
+// maxParts - 1. Elements of strings have their quotes removed but escapes kept.
+//
+// Nothing is appended if value isn't an array or contains unbalanced brackets or quotes
+func splitJSONArray(value string, maxParts int, parts []string) []string {
+	value = strings.TrimSpace(value)
+	if len(value) < 2 || value[0] != '[' || value[len(value)-1] != ']' {
+		return parts
+	}
+	start := len(parts)
+	body := value[1 : len(value)-1]
+	pos := 0
+	for pos < len(body) {
+		if len(parts) == start+maxParts-1 {
+			parts = append(parts, strings.TrimSpace(body[pos:]))
+			break
+		}
+		end := findElementEnd(body, pos)
+		if end == -1 {
+			return parts[:start]
+		}
+		if element := strings.TrimSpace(body[pos:end]); len(element) > 0 {
+			parts = append(parts, unquoteElement(element))
+		}
+		pos = end + 1
+	}
+	return parts
+}
+
+// findElementEnd returns the position of the comma or the end of body after the element from start, or -1 if invalid
+func findElementEnd(body string, start int) int {
+	depth := 0
+	inString := false
+	for i := start; i < len(body); i++ {
+		c := body[i]
+		switch {
+		case inString:
+			switch c {
+			case '\\':
+				i++
+			case '"':
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+			if depth < 0 {
+				return -1
+			}
+		case c == ',' && depth == 0:
+			return i
+		}
+	}
+	if inString || depth != 0 {
+		return -1
+	}
+	return len(body)
+}
+
+func unquoteElement(element string) string {
+	if len(element) >= 2 && element[0] == '"' && element[len(element)-1] == '"' {
+		return element[1 : len(element)-1]
+	}
+	return element
+}
@@ -0,0 +1,102 @@
+package tsplit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/relex/gotils/logger"
+	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/base/bconfig"
+	"github.com/relex/slog-agent/base/bsupport"
+	"github.com/relex/slog-agent/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitTransformByDelimiter(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"app", "log"})
+	allocator := base.NewLogAllocator(schema, 1)
+	c := &Config{}
+	assert.NoError(t, util.UnmarshalYamlString(`
+type: split
+key: log
+delimiter: "\n"
+maxRecords: 3
+`, c))
+	assert.NoError(t, c.VerifyConfig(schema))
+	tf := c.NewTransform(schema, logger.Root(), nil).(base.LogExpandTransform)
+
+	tm := time.Unix(1700000000, 0)
+	record := schema.NewTestRecord2(tm, base.LogFields{"web", "first\n\nsecond\nthird\nfourth\n"})
+	record.RawLength = 27
+	outputs := tf.ExpandRecord(record, allocator, nil)
+	if assert.Len(t, outputs, 3) {
+		assert.Equal(t, base.LogFields{"web", "first"}, outputs[0].Fields)
+		assert.Equal(t, base.LogFields{"web", "second"}, outputs[1].Fields)
+		assert.Equal(t, base.LogFields{"web", "third\nfourth\n"}, outputs[2].Fields)
+		assert.Equal(t, tm, outputs[2].Timestamp)
+		assert.Equal(t, 5, outputs[0].RawLength)
+	}
+
+	record = schema.NewTestRecord1(base.LogFields{"web", "single\n"})
+	outputs = tf.ExpandRecord(record, allocator, nil)
+	assert.Equal(t, []*base.LogRecord{record}, outputs)
+	assert.Equal(t, "single", record.Fields[1])
+
+	record = schema.NewTestRecord1(base.LogFields{"web", "a\nb"})
+	assert.Equal(t, base.PASS, tf.Transform(record))
+	assert.Equal(t, "a\nb", record.Fields[1])
+}
+
+func TestSplitTransformByJSONArray(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"log"})
+	allocator := base.NewLogAllocator(schema, 1)
+	c := &Config{}
+	assert.NoError(t, util.UnmarshalYamlString(`
+type: split
+key: log
+jsonArray: true
+`, c))
+	assert.NoError(t, c.VerifyConfig(schema))
+	tf := c.NewTransform(schema, logger.Root(), nil).(base.LogExpandTransform)
+
+	record := schema.NewTestRecord1(base.LogFields{` [{"msg": "a,b", "tags": [1, 2]}, "say \"hi\"", 3 ] `})
+	outputs := tf.ExpandRecord(record, allocator, nil)
+	if assert.Len(t, outputs, 3) {
+		assert.Equal(t, base.LogFields{`{"msg": "a,b", "tags": [1, 2]}`}, outputs[0].Fields)
+		assert.Equal(t, base.LogFields{`say \"hi\"`}, outputs[1].Fields)
+		assert.Equal(t, base.LogFields{`3`}, outputs[2].Fields)
+	}
+
+	for _, invalid := range []string{`{"msg": "a"}`, `[{"msg": "a"]`, `["a", "b]`, `[]`} {
+		record = schema.NewTestRecord1(base.LogFields{invalid})
+		outputs = tf.ExpandRecord(record, allocator, nil)
+		assert.Equal(t, []*base.LogRecord{record}, outputs, invalid)
+		assert.Equal(t, invalid, record.Fields[0])
+	}
+}
+
+func TestSplitConfig(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"log"})
+	c := &Config{}
+	assert.NoError(t, util.UnmarshalYamlString(`
+type: split
+key: log
+delimiter: ","
+jsonArray: true
+`, c))
+	assert.EqualError(t, c.VerifyConfig(schema), ".delimiter and .jsonArray cannot be used together")
+}
+
+func TestSplitConfigTopLevelOnly(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"log"})
+	c := &Config{}
+	assert.NoError(t, util.UnmarshalYamlString(`
+type: split
+key: log
+delimiter: ","
+`, c))
+	configs := []bconfig.LogTransformConfigHolder{{Location: "line 1", Value: c}}
+	assert.NoError(t, bsupport.VerifyTopLevelTransformConfigs(configs, schema, "transforms"))
+	assert.EqualError(t, bsupport.VerifyTransformConfigs(configs, schema, ".then"),
+		".then[0] line 1: .type 'split' is only supported in top-level transformations")
+}
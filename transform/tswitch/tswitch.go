@@ -31,8 +31,9 @@ type switchTransform struct {
 
 // switchCase acts like C switch "case", with nested cases and optional then steps if matched
 type switchCase struct {
-	matcher bmatch.LogMatcher
-	then    []base.LogTransformFunc
+	matcher   bmatch.LogMatcher
+	then      []base.LogTransformFunc
+	closeThen func()
 }
 
 type switchCaseResult bool
@@ -61,14 +62,16 @@ func (c *Config) VerifyConfig(schema base.LogSchema) error {
 }
 
 func (c *CaseConfig) newCase(schema base.LogSchema, parentLogger logger.Logger, customCounterRegistry base.LogCustomCounterRegistry) switchCase {
+	then, closeThen := bsupport.NewTransformsFromConfig(c.Then, schema, parentLogger, customCounterRegistry)
 	return switchCase{
-		matcher: c.Match.NewMatcher(schema),
-		then:    bsupport.NewTransformsFromConfig(c.Then, schema, parentLogger, customCounterRegistry),
+		matcher:   c.Match.NewMatcher(schema),
+		then:      then,
+		closeThen: closeThen,
 	}
 }
 
 func (c *CaseConfig) verify(schema base.LogSchema) error {
-	if len(c.Match) == 0 {
+	if c.Match.IsEmpty() {
 		return fmt.Errorf(".match is empty")
 	}
 	if err := c.Match.VerifyConfig(schema); err != nil {
@@ -91,6 +94,12 @@ func (tf *switchTransform) Transform(record *base.LogRecord) base.FilterResult {
 	return base.PASS
 }
 
+func (tf *switchTransform) Close() {
+	for _, c := range tf.cases {
+		c.closeThen()
+	}
+}
+
 func (sc *switchCase) apply(record *base.LogRecord) (switchCaseResult, base.FilterResult) {
 	if !sc.matcher.Match(record) {
 		return false, base.PASS
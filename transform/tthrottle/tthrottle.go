@@ -0,0 +1,189 @@
+// Package tthrottle provides 'throttle' transform, which limits the rates of log records by key fields using token buckets,
+// e.g. to stop a single class from flooding the pipeline with identical warnings during incidents.
+package tthrottle
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/relex/gotils/logger"
+	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/base/bconfig"
+	"github.com/relex/slog-agent/util"
+	"github.com/relex/slog-agent/util/lrucache"
+)
+
+// Config for throttleTransform
+type Config struct {
+	bconfig.Header `yaml:",inline"`
+	Keys           []string      `yaml:"keys"`
+	MaxRecords     int           `yaml:"maxRecords"`
+	Interval       time.Duration `yaml:"interval"`
+	MaxKeys        int           `yaml:"maxKeys"`
+	MetricLabel    string        `yaml:"metricLabel"`
+}
+
+type throttleTransform struct {
+	logger         logger.Logger
+	keyExtractor   *base.FieldSetExtractor
+	keyBuffer      []byte
+	buckets        *lrucache.LRUCache[string, throttleBucket]
+	maxRecords     float64
+	refillRate     float64 // tokens per second
+	interval       time.Duration
+	intervalEnd    time.Time
+	generation     int // generation of the current summary interval, to reset per-bucket stats lazily
+	droppedCount   int // numbers of dropped records in the current interval
+	evictedDropped int // numbers of dropped records from buckets evicted in the current interval
+	countDropped   func(length int)
+	now            func() time.Time
+}
+
+// throttleBucket is a token bucket of one key-set, refilled continuously at the rate of maxRecords per interval
+type throttleBucket struct {
+	tokens        float64
+	lastRefill    time.Time
+	generation    int
+	droppedCount  int
+	droppedLength int
+}
+
+// NewTransform creates throttleTransform
+func (cfg *Config) NewTransform(schema base.LogSchema, parentLogger logger.Logger, customCounterRegistry base.LogCustomCounterRegistry) base.LogTransform {
+	return newThrottleTransform(cfg, schema, parentLogger, customCounterRegistry, time.Now)
+}
+
+// VerifyConfig verifies throttleTransform config
+func (cfg *Config) VerifyConfig(schema base.LogSchema) error {
+	if len(cfg.Keys) == 0 {
+		return fmt.Errorf(".keys is empty")
+	}
+	if _, err := schema.CreateFieldLocators(cfg.Keys); err != nil {
+		return fmt.Errorf(".keys: %w", err)
+	}
+	if cfg.MaxRecords <= 0 {
+		return fmt.Errorf(".maxRecords must be larger than zero: %d", cfg.MaxRecords)
+	}
+	if cfg.Interval <= 0 {
+		return fmt.Errorf(".interval must be larger than zero: %s", cfg.Interval)
+	}
+	if cfg.MaxKeys <= 0 {
+		return fmt.Errorf(".maxKeys must be larger than zero: %d", cfg.MaxKeys)
+	}
+	if len(cfg.MetricLabel) == 0 {
+		return fmt.Errorf(".metricLabel is unspecified")
+	}
+	return nil
+}
+
+func newThrottleTransform(cfg *Config, schema base.LogSchema, parentLogger logger.Logger,
+	customCounterRegistry base.LogCustomCounterRegistry, now func() time.Time,
+) *throttleTransform {
+	return &throttleTransform{
+		logger:         parentLogger,
+		keyExtractor:   base.NewFieldSetExtractor(schema.MustCreateFieldLocators(cfg.Keys)),
+		keyBuffer:      make([]byte, 0, 200),
+		buckets:        lrucache.NewLRUCache[string, throttleBucket](cfg.MaxKeys),
+		maxRecords:     float64(cfg.MaxRecords),
+		refillRate:     float64(cfg.MaxRecords) / cfg.Interval.Seconds(),
+		interval:       cfg.Interval,
+		intervalEnd:    now().Add(cfg.Interval),
+		generation:     0,
+		droppedCount:   0,
+		evictedDropped: 0,
+		countDropped:   customCounterRegistry.RegisterCustomCounter(cfg.MetricLabel),
+		now:            now,
+	}
+}
+
+// Transform passes the record if its bucket has a token left, or drops it otherwise
+//
+// Dropped records are counted as they're dropped, under their own metric key-sets, and summarized in logs at the end
+// of each interval, when the first record after the interval arrives or the transform is closed.
+func (tf *throttleTransform) Transform(record *base.LogRecord) base.FilterResult {
+	now := tf.now()
+	if !now.Before(tf.intervalEnd) {
+		tf.endInterval(now)
+	}
+
+	tempKeys := tf.keyExtractor.Extract(record)
+	tempMergedKey := tf.keyBuffer
+	for _, tkey := range tempKeys {
+		tempMergedKey = append(tempMergedKey, tkey...)
+		tempMergedKey = append(tempMergedKey, 0)
+	}
+	tf.keyBuffer = tempMergedKey[:0]
+
+	bucket := tf.buckets.Get(util.StringFromBytes(tempMergedKey))
+	if bucket == nil {
+		bucket = tf.buckets.Add(util.DeepCopyStringFromBytes(tempMergedKey), tf.newBucket(now), tf.onEvict)
+	} else {
+		tf.refill(bucket, now)
+	}
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return base.PASS
+	}
+	bucket.droppedCount++
+	tf.droppedCount++
+	tf.countDropped(record.RawLength)
+	return base.DROP
+}
+
+// Close logs the summary of the current interval
+func (tf *throttleTransform) Close() {
+	tf.endInterval(tf.now())
+}
+
+// endInterval logs the summary of throttled records and starts a new interval
+func (tf *throttleTransform) endInterval(now time.Time) {
+	if tf.droppedCount > 0 {
+		tf.buckets.Walk(func(key string, bucket *throttleBucket) {
+			if bucket.generation != tf.generation || bucket.droppedCount == 0 {
+				return
+			}
+			tf.logger.Warnf("throttled %d records (%d bytes) in %s for keys [%s]",
+				bucket.droppedCount, bucket.droppedLength, tf.interval, strings.TrimSuffix(strings.ReplaceAll(key, "\x00", ","), ","))
+		})
+		if tf.evictedDropped > 0 {
+			tf.logger.Warnf("throttled %d records in %s for evicted keys", tf.evictedDropped, tf.interval)
+		}
+	}
+	tf.droppedCount = 0
+	tf.evictedDropped = 0
+	tf.generation++
+	tf.intervalEnd = now.Add(tf.interval)
+}
+
+func (tf *throttleTransform) newBucket(now time.Time) throttleBucket {
+	return throttleBucket{
+		tokens:        tf.maxRecords,
+		lastRefill:    now,
+		generation:    tf.generation,
+		droppedCount:  0,
+		droppedLength: 0,
+	}
+}
+
+func (tf *throttleTransform) refill(bucket *throttleBucket, now time.Time) {
+	if elapsed := now.Sub(bucket.lastRefill); elapsed > 0 {
+		bucket.tokens += elapsed.Seconds() * tf.refillRate
+		if bucket.tokens > tf.maxRecords {
+			bucket.tokens = tf.maxRecords
+		}
+		bucket.lastRefill = now
+	}
+	if bucket.generation != tf.generation {
+		bucket.generation = tf.generation
+		bucket.droppedCount = 0
+		bucket.droppedLength = 0
+	}
+}
+
+func (tf *throttleTransform) onEvict(_ string, bucket *throttleBucket) {
+	if bucket.generation == tf.generation {
+		tf.evictedDropped += bucket.droppedCount
+	}
+}
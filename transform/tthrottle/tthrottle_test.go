@@ -0,0 +1,87 @@
+package tthrottle
+
+import (
+	"testing"
+	"time"
+
+	"github.com/relex/gotils/logger"
+	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/base/btest"
+	"github.com/relex/slog-agent/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThrottleTransform(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"app", "class", "log"})
+	c := &Config{}
+	if !assert.NoError(t, util.UnmarshalYamlString(`
+type: throttle
+keys: [app, class]
+maxRecords: 2
+interval: 1m
+maxKeys: 2
+metricLabel: throttled
+`, c)) {
+		return
+	}
+	if !assert.NoError(t, c.VerifyConfig(schema)) {
+		return
+	}
+	reg, lookup := btest.NewStubLogCustomCounterRegistry()
+	now := time.Date(2022, 8, 15, 3, 48, 20, 0, time.UTC)
+	tf := newThrottleTransform(c, schema, logger.Root(), reg, func() time.Time { return now })
+
+	newRecord := func(app string, class string) *base.LogRecord {
+		r := schema.NewTestRecord1(base.LogFields{app, class, "hello"})
+		r.RawLength = 10
+		return r
+	}
+
+	assert.Equal(t, base.PASS, tf.Transform(newRecord("foo", "A")))
+	assert.Equal(t, base.PASS, tf.Transform(newRecord("foo", "A")))
+	assert.Equal(t, base.DROP, tf.Transform(newRecord("foo", "A")))
+	assert.Equal(t, base.PASS, tf.Transform(newRecord("foo", "B")))
+	assert.Equal(t, base.PASS, tf.Transform(newRecord("fooA", "")), "merged key must not collide with foo+A")
+	assert.Equal(t, base.PASS, tf.Transform(newRecord("fooA", "")))
+	assert.Equal(t, base.DROP, tf.Transform(newRecord("fooA", "")))
+
+	// "foo,A" has been evicted by "fooA" and starts with a new bucket
+	assert.Equal(t, base.PASS, tf.Transform(newRecord("foo", "A")))
+	assert.Equal(t, 1, tf.evictedDropped)
+
+	// dropped records are counted immediately
+	count, length := lookup("throttled")
+	assert.Equal(t, int64(2), count)
+	assert.Equal(t, int64(20), length)
+
+	// new interval: buckets are refilled fully after one interval
+	now = now.Add(time.Minute)
+	assert.Equal(t, base.PASS, tf.Transform(newRecord("fooA", "")))
+	assert.Equal(t, 0, tf.evictedDropped)
+	assert.Equal(t, 0, tf.droppedCount)
+	assert.Equal(t, base.PASS, tf.Transform(newRecord("fooA", "")))
+	assert.Equal(t, base.DROP, tf.Transform(newRecord("fooA", "")))
+
+	// partial refill: one token in half interval
+	now = now.Add(30 * time.Second)
+	assert.Equal(t, base.PASS, tf.Transform(newRecord("fooA", "")))
+	assert.Equal(t, base.DROP, tf.Transform(newRecord("fooA", "")))
+
+	count, length = lookup("throttled")
+	assert.Equal(t, int64(4), count)
+	assert.Equal(t, int64(40), length)
+
+	// summary of the last interval on close
+	assert.Equal(t, 2, tf.droppedCount)
+	tf.Close()
+	assert.Equal(t, 0, tf.droppedCount)
+}
+
+func TestThrottleTransformVerify(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"app", "class", "log"})
+	c := &Config{}
+	assert.NoError(t, util.UnmarshalYamlString("type: throttle\nkeys: [app]\nmaxRecords: 10\ninterval: 1s\nmetricLabel: x", c))
+	assert.EqualError(t, c.VerifyConfig(schema), ".maxKeys must be larger than zero: 0")
+	c.Keys = []string{"host"}
+	assert.EqualError(t, c.VerifyConfig(schema), ".keys: [0]: field 'host' is not defined in schema")
+}
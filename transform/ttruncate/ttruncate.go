@@ -52,13 +52,10 @@ func (c *Config) VerifyConfig(schema base.LogSchema) error {
 
 func (tf *truncateTransform) Transform(record *base.LogRecord) base.FilterResult {
 	value := tf.keyLocator.Get(record.Fields)
-	if len(value) > tf.maxLength+len(tf.suffix) {
-		valueB := util.BytesFromString(value)
-
-		// truncate and clean up before the maxLength in case of UTF-8 sequences cut in the middle
-		valueTrimmed := util.CleanUTF8(valueB[:tf.maxLength])
+	// truncate before the maxLength in case of UTF-8 sequences cut in the middle
+	if end := util.FindTruncationEnd(value, tf.maxLength, len(tf.suffix)); end != -1 {
 		// paste suffix at the truncated end - NOT the maxLength as the actual length could be smaller due to UTF-8 cleanup
-		valueOverwritten := util.OverwriteNTruncate(valueB, len(valueTrimmed), tf.suffix)
+		valueOverwritten := util.OverwriteNTruncate(util.BytesFromString(value), end, tf.suffix)
 
 		tf.keyLocator.Set(record.Fields, util.StringFromBytes(valueOverwritten))
 	}
@@ -0,0 +1,171 @@
+;; Test module for the wasm transform: drops records with an empty first field, and otherwise converts the first field
+;; to ASCII uppercase. Other fields are skipped.
+;;
+;; Build by "wat2wasm upper.wat -o upper.wasm" from WABT.
+(module
+  (memory (export "memory") 1)
+
+  ;; slog_alloc reserves three times the input size at 1024, for the input and the output after it
+  (func (export "slog_alloc") (param $size i32) (result i32)
+    (local $pages i32)
+    local.get $size
+    i32.const 3
+    i32.mul
+    i32.const 66559
+    i32.add
+    i32.const 16
+    i32.shr_u
+    local.set $pages
+    local.get $pages
+    memory.size
+    i32.gt_u
+    if
+      local.get $pages
+      memory.size
+      i32.sub
+      memory.grow
+      drop
+    end
+    i32.const 1024
+  )
+
+  ;; slog_transform writes the output right after the input and returns (output pointer << 32 | output length)
+  (func (export "slog_transform") (param $ptr i32) (param $len i32) (result i64)
+    (local $n i32) (local $nf i32) (local $in i32) (local $out i32) (local $flen i32) (local $end i32) (local $c i32)
+    (local $f i32)
+    local.get $ptr
+    i32.load
+    local.set $n
+    local.get $ptr
+    i32.load offset=4
+    local.set $nf
+    local.get $ptr
+    i32.const 8
+    i32.add
+    local.set $in
+    local.get $ptr
+    local.get $len
+    i32.add
+    local.set $out
+    block $done
+      loop $record
+        local.get $n
+        i32.eqz
+        br_if $done
+        local.get $in
+        i32.load
+        local.set $flen
+        local.get $in
+        i32.const 4
+        i32.add
+        local.set $in
+        local.get $flen
+        i32.eqz
+        if
+          ;; DROP without updates
+          local.get $out
+          i32.const 1
+          i32.store16
+          local.get $out
+          i32.const 2
+          i32.add
+          local.set $out
+        else
+          ;; PASS with one update of the first field
+          local.get $out
+          i32.const 256
+          i32.store16
+          local.get $out
+          i32.const 0
+          i32.store8 offset=2
+          local.get $out
+          local.get $flen
+          i32.store offset=3
+          local.get $out
+          i32.const 7
+          i32.add
+          local.set $out
+          local.get $in
+          local.get $flen
+          i32.add
+          local.set $end
+          block $copied
+            loop $copy
+              local.get $in
+              local.get $end
+              i32.ge_u
+              br_if $copied
+              local.get $in
+              i32.load8_u
+              local.set $c
+              local.get $c
+              i32.const 97
+              i32.sub
+              i32.const 26
+              i32.lt_u
+              if
+                local.get $c
+                i32.const 32
+                i32.sub
+                local.set $c
+              end
+              local.get $out
+              local.get $c
+              i32.store8
+              local.get $in
+              i32.const 1
+              i32.add
+              local.set $in
+              local.get $out
+              i32.const 1
+              i32.add
+              local.set $out
+              br $copy
+            end
+          end
+        end
+        ;; skip other fields
+        i32.const 1
+        local.set $f
+        block $skipped
+          loop $skip
+            local.get $f
+            local.get $nf
+            i32.ge_u
+            br_if $skipped
+            local.get $in
+            local.get $in
+            i32.load
+            i32.add
+            i32.const 4
+            i32.add
+            local.set $in
+            local.get $f
+            i32.const 1
+            i32.add
+            local.set $f
+            br $skip
+          end
+        end
+        local.get $n
+        i32.const 1
+        i32.sub
+        local.set $n
+        br $record
+      end
+    end
+    local.get $ptr
+    local.get $len
+    i32.add
+    i64.extend_i32_u
+    i64.const 32
+    i64.shl
+    local.get $out
+    local.get $ptr
+    i32.sub
+    local.get $len
+    i32.sub
+    i64.extend_i32_u
+    i64.or
+  )
+)
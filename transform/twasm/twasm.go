@@ -0,0 +1,389 @@
+// Package twasm provides 'wasm' transform, which runs a WebAssembly module as a sandboxed plugin, for custom logic
+// which isn't possible by combining other transforms and doesn't belong to this repository.
+//
+// Modules are run by wazero, a pure-Go runtime. They may import WASI (wasi_snapshot_preview1), without access to
+// files, environment variables or networking. Each pipeline has its own module instance, so that modules don't need
+// to be thread-safe. Compiled modules are shared by pipelines and released after the last of them is closed.
+//
+// # ABI
+//
+// The module must export "memory" and the following functions:
+//
+//	slog_alloc(size i32) -> i32
+//	    returns a pointer to a buffer of at least the given size, called when the previous buffer is too small
+//	slog_transform(ptr i32, len i32) -> i64
+//	    transforms a batch of records in the buffer and returns the pointer and length of results (ptr << 32 | len)
+//
+// Records are passed in batches, normally a whole buffer of records from inputs per call. All integers are in little
+// endian. The input:
+//
+//	u32 number of records
+//	u32 number of fields per record, as in .keys
+//	for each record, for each field: u32 length, value bytes
+//
+// The results, for every record in order:
+//
+//	u8  0 for PASS or 1 for DROP
+//	u8  number of field updates
+//	for each update: u8 field index in .keys, u32 length, value bytes
+//
+// Invalid results fail the whole batch, in which case all records pass unchanged and are counted by metricLabel. The
+// error is logged at most once per minute, with the number of failed batches not logged in between.
+package twasm
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/c2h5oh/datasize"
+	"github.com/relex/gotils/logger"
+	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/base/bconfig"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+// Config for wasmTransform
+type Config struct {
+	bconfig.Header `yaml:",inline"`
+	Path           string            `yaml:"path"`        // path to .wasm module, loaded only at startup
+	Keys           []string          `yaml:"keys"`        // fields to pass to the module, by the same indexes in ABI
+	MaxMemory      datasize.ByteSize `yaml:"maxMemory"`   // max memory of each module instance, default 64MB
+	MetricLabel    string            `yaml:"metricLabel"` // label to count records passed unchanged by failed calls
+}
+
+type wasmTransform struct {
+	keyLocators   []base.LogFieldLocator
+	plugin        *wasmPlugin
+	module        api.Module
+	allocFunc     api.Function
+	transformFunc api.Function
+	stack         []uint64 // reused stack for calls
+	bufferPtr     uint32   // input buffer in module memory
+	bufferSize    uint32
+	singleInput   [1]*base.LogRecord
+	singleResult  [1]base.FilterResult
+	updates       []wasmFieldUpdate // reused buffer of parsed field updates
+	logger        logger.Logger
+	countFailed   func(length int)
+	lastErrorLog  time.Time // when the last error was logged
+	numUnlogged   int       // number of failed batches not logged since the last logged error
+	now           func() time.Time
+}
+
+// wasmPlugin is a compiled module shared by transforms of the same config, in its own runtime
+type wasmPlugin struct {
+	mapKey   string
+	runtime  wazero.Runtime
+	compiled wazero.CompiledModule
+	refCount int // protected by pluginMapMutex
+}
+
+// wasmFieldUpdate is a field update parsed from results, with the value still in module memory
+type wasmFieldUpdate struct {
+	recordIndex int
+	fieldIndex  int
+	value       []byte
+}
+
+const (
+	defaultMaxMemory = 64 * datasize.MB
+	wasmPageSize     = 65536
+	maxFields        = 256 // limited by u8 field index in results
+	resultPass       = 0
+	resultDrop       = 1
+	errorLogInterval = time.Minute
+)
+
+var (
+	pluginMap      = make(map[string]*wasmPlugin) // map of path and max memory => plugin in use
+	pluginMapMutex = &sync.Mutex{}
+)
+
+// NewTransform creates wasmTransform
+func (c *Config) NewTransform(schema base.LogSchema, parentLogger logger.Logger, customCounterRegistry base.LogCustomCounterRegistry) base.LogTransform {
+	plugin, err := c.acquirePlugin()
+	if err != nil {
+		logger.Panicf("failed to load wasm module '%s': %s", c.Path, err.Error())
+	}
+	ctx := context.Background()
+	module, ierr := plugin.runtime.InstantiateModule(ctx, plugin.compiled, wazero.NewModuleConfig().
+		WithName("").
+		WithStartFunctions("_initialize"))
+	if ierr != nil {
+		logger.Panicf("failed to instantiate wasm module '%s': %s", c.Path, ierr.Error())
+	}
+	return &wasmTransform{
+		keyLocators:   schema.MustCreateFieldLocators(c.Keys),
+		plugin:        plugin,
+		module:        module,
+		allocFunc:     module.ExportedFunction("slog_alloc"),
+		transformFunc: module.ExportedFunction("slog_transform"),
+		stack:         make([]uint64, 2),
+		bufferPtr:     0,
+		bufferSize:    0,
+		singleInput:   [1]*base.LogRecord{nil},
+		singleResult:  [1]base.FilterResult{base.PASS},
+		updates:       nil,
+		logger:        parentLogger.WithField("path", c.Path),
+		countFailed:   customCounterRegistry.RegisterCustomCounter(c.MetricLabel),
+		lastErrorLog:  time.Time{},
+		numUnlogged:   0,
+		now:           time.Now,
+	}
+}
+
+// VerifyConfig verifies wasmTransform config
+func (c *Config) VerifyConfig(schema base.LogSchema) error {
+	if len(c.Path) == 0 {
+		return fmt.Errorf(".path is unspecified")
+	}
+	if len(c.Keys) == 0 {
+		return fmt.Errorf(".keys is empty")
+	}
+	if len(c.Keys) > maxFields {
+		return fmt.Errorf(".keys cannot have more than %d fields", maxFields)
+	}
+	for i, key := range c.Keys {
+		if _, err := schema.CreateFieldLocator(key); err != nil {
+			return fmt.Errorf(".keys[%d] '%s' is invalid: %w", i, key, err)
+		}
+	}
+	if c.MaxMemory != 0 && c.MaxMemory < wasmPageSize {
+		return fmt.Errorf(".maxMemory must be at least %d bytes: %s", wasmPageSize, c.MaxMemory.HR())
+	}
+	if len(c.MetricLabel) == 0 {
+		return fmt.Errorf(".metricLabel is unspecified")
+	}
+	// the module is compiled here only to be verified, and compiled again later unless it's still used by others
+	plugin, err := c.acquirePlugin()
+	if err != nil {
+		return fmt.Errorf(".path '%s': %w", c.Path, err)
+	}
+	plugin.release()
+	return nil
+}
+
+// acquirePlugin gets the compiled module of this config in use, or compiles it in a new runtime
+//
+// The returned plugin must be released after use.
+func (c *Config) acquirePlugin() (*wasmPlugin, error) {
+	maxMemory := c.MaxMemory
+	if maxMemory == 0 {
+		maxMemory = defaultMaxMemory
+	}
+	mapKey := fmt.Sprintf("%s:%d", c.Path, maxMemory)
+
+	pluginMapMutex.Lock()
+	defer pluginMapMutex.Unlock()
+	plugin, ok := pluginMap[mapKey]
+	if !ok {
+		code, err := os.ReadFile(c.Path)
+		if err != nil {
+			return nil, err
+		}
+		var perr error
+		plugin, perr = compilePlugin(mapKey, code, uint32(maxMemory/wasmPageSize))
+		if perr != nil {
+			return nil, perr
+		}
+		pluginMap[mapKey] = plugin
+	}
+	plugin.refCount++
+	return plugin, nil
+}
+
+// release decreases the reference count of the plugin, and closes its runtime if it's no longer used
+func (plugin *wasmPlugin) release() {
+	pluginMapMutex.Lock()
+	defer pluginMapMutex.Unlock()
+	plugin.refCount--
+	switch {
+	case plugin.refCount < 0:
+		logger.Panicf("wasm module '%s' is released more than acquired", plugin.mapKey)
+	case plugin.refCount == 0:
+		delete(pluginMap, plugin.mapKey)
+		_ = plugin.runtime.Close(context.Background())
+	}
+}
+
+func compilePlugin(mapKey string, code []byte, maxPages uint32) (*wasmPlugin, error) {
+	ctx := context.Background()
+	rt := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().WithMemoryLimitPages(maxPages))
+	compiled, err := rt.CompileModule(ctx, code)
+	if err == nil {
+		err = verifyExports(compiled)
+	}
+	if err == nil {
+		_, err = wasi_snapshot_preview1.Instantiate(ctx, rt)
+	}
+	if err != nil {
+		_ = rt.Close(ctx)
+		return nil, err
+	}
+	return &wasmPlugin{mapKey: mapKey, runtime: rt, compiled: compiled, refCount: 0}, nil
+}
+
+func verifyExports(compiled wazero.CompiledModule) error {
+	if _, ok := compiled.ExportedMemories()["memory"]; !ok {
+		return fmt.Errorf("missing exported memory 'memory'")
+	}
+	functions := compiled.ExportedFunctions()
+	for name, signature := range map[string][2][]api.ValueType{
+		"slog_alloc":     {{api.ValueTypeI32}, {api.ValueTypeI32}},
+		"slog_transform": {{api.ValueTypeI32, api.ValueTypeI32}, {api.ValueTypeI64}},
+	} {
+		def, ok := functions[name]
+		if !ok {
+			return fmt.Errorf("missing exported function '%s'", name)
+		}
+		if string(def.ParamTypes()) != string(signature[0]) || string(def.ResultTypes()) != string(signature[1]) {
+			return fmt.Errorf("exported function '%s' has wrong signature: %v -> %v", name, def.ParamTypes(), def.ResultTypes())
+		}
+	}
+	return nil
+}
+
+func (tf *wasmTransform) Transform(record *base.LogRecord) base.FilterResult {
+	tf.singleInput[0] = record
+	tf.TransformBatch(tf.singleInput[:], tf.singleResult[:], nil)
+	tf.singleInput[0] = nil
+	return tf.singleResult[0]
+}
+
+func (tf *wasmTransform) TransformBatch(inputs []*base.LogRecord, results []base.FilterResult, selectRecord base.LogRecordSelector) {
+	if err := tf.call(inputs, results); err != nil {
+		tf.logError(err, len(inputs))
+		for i, record := range inputs {
+			results[i] = base.PASS
+			if selectRecord != nil {
+				selectRecord(i)
+			}
+			tf.countFailed(record.RawLength)
+		}
+	}
+}
+
+// logError logs the error of a failed batch, unless another error has been logged within errorLogInterval
+func (tf *wasmTransform) logError(err error, numRecords int) {
+	now := tf.now()
+	if now.Sub(tf.lastErrorLog) < errorLogInterval {
+		tf.numUnlogged++
+		return
+	}
+	if tf.numUnlogged > 0 {
+		tf.logger.Errorf("failed to transform %d records: %s (%d more failed batches since the last error)", numRecords, err.Error(), tf.numUnlogged)
+	} else {
+		tf.logger.Errorf("failed to transform %d records: %s", numRecords, err.Error())
+	}
+	tf.lastErrorLog = now
+	tf.numUnlogged = 0
+}
+
+// Close closes the module instance and releases the compiled module
+func (tf *wasmTransform) Close() {
+	_ = tf.module.Close(context.Background())
+	tf.plugin.release()
+}
+
+func (tf *wasmTransform) call(inputs []*base.LogRecord, results []base.FilterResult) error {
+	ctx := context.Background()
+	size := 8
+	for _, record := range inputs {
+		for _, loc := range tf.keyLocators {
+			size += 4 + len(loc.Get(record.Fields))
+		}
+	}
+	if uint32(size) > tf.bufferSize {
+		tf.stack[0] = uint64(size)
+		if err := tf.allocFunc.CallWithStack(ctx, tf.stack); err != nil {
+			return fmt.Errorf("slog_alloc: %w", err)
+		}
+		tf.bufferPtr = uint32(tf.stack[0])
+		tf.bufferSize = uint32(size)
+	}
+
+	// write directly into module memory
+	buf, ok := tf.module.Memory().Read(tf.bufferPtr, uint32(size))
+	if !ok {
+		return fmt.Errorf("slog_alloc returned buffer out of range: %d", tf.bufferPtr)
+	}
+	binary.LittleEndian.PutUint32(buf[0:], uint32(len(inputs)))
+	binary.LittleEndian.PutUint32(buf[4:], uint32(len(tf.keyLocators)))
+	pos := 8
+	for _, record := range inputs {
+		for _, loc := range tf.keyLocators {
+			value := loc.Get(record.Fields)
+			binary.LittleEndian.PutUint32(buf[pos:], uint32(len(value)))
+			pos += 4 + copy(buf[pos+4:], value)
+		}
+	}
+
+	tf.stack[0] = uint64(tf.bufferPtr)
+	tf.stack[1] = uint64(size)
+	if err := tf.transformFunc.CallWithStack(ctx, tf.stack); err != nil {
+		return fmt.Errorf("slog_transform: %w", err)
+	}
+	outPtr, outLen := uint32(tf.stack[0]>>32), uint32(tf.stack[0])
+	out, ok := tf.module.Memory().Read(outPtr, outLen)
+	if !ok {
+		return fmt.Errorf("slog_transform returned results out of range: %d+%d", outPtr, outLen)
+	}
+	return tf.applyResults(out, inputs, results)
+}
+
+// applyResults updates records by results from the module, only if all of the results are valid. Field values are
+// copied out of module memory.
+func (tf *wasmTransform) applyResults(out []byte, inputs []*base.LogRecord, results []base.FilterResult) error {
+	defer func() { clear(tf.updates) }() // don't keep references to module memory
+	if err := tf.parseResults(out, len(inputs), results); err != nil {
+		return err
+	}
+	for _, update := range tf.updates {
+		tf.keyLocators[update.fieldIndex].Set(inputs[update.recordIndex].Fields, string(update.value))
+	}
+	return nil
+}
+
+// parseResults parses all results from the module into the results array and field updates, without changing records
+func (tf *wasmTransform) parseResults(out []byte, numInputs int, results []base.FilterResult) error {
+	tf.updates = tf.updates[:0]
+	pos := 0
+	for i := 0; i < numInputs; i++ {
+		if pos+2 > len(out) {
+			return fmt.Errorf("results truncated at record %d", i)
+		}
+		switch out[pos] {
+		case resultPass:
+			results[i] = base.PASS
+		case resultDrop:
+			results[i] = base.DROP
+		default:
+			return fmt.Errorf("invalid result at record %d: %d", i, out[pos])
+		}
+		numUpdates := int(out[pos+1])
+		pos += 2
+		for u := 0; u < numUpdates; u++ {
+			if pos+5 > len(out) {
+				return fmt.Errorf("results truncated at record %d", i)
+			}
+			index := int(out[pos])
+			length := int(binary.LittleEndian.Uint32(out[pos+1:]))
+			pos += 5
+			if index >= len(tf.keyLocators) {
+				return fmt.Errorf("invalid field index at record %d: %d", i, index)
+			}
+			if length > len(out)-pos {
+				return fmt.Errorf("results truncated at record %d", i)
+			}
+			tf.updates = append(tf.updates, wasmFieldUpdate{recordIndex: i, fieldIndex: index, value: out[pos : pos+length]})
+			pos += length
+		}
+	}
+	return nil
+}
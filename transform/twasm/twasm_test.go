@@ -0,0 +1,200 @@
+package twasm
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/relex/gotils/logger"
+	"github.com/relex/slog-agent/base"
+	"github.com/relex/slog-agent/base/bconfig"
+	"github.com/relex/slog-agent/base/btest"
+	"github.com/relex/slog-agent/defs"
+	"github.com/relex/slog-agent/transform/tnormalize"
+	"github.com/relex/slog-agent/util"
+	"github.com/stretchr/testify/assert"
+)
+
+// testdata/upper.wasm drops records with empty first field, and converts the first field to ASCII uppercase
+const testModulePath = "testdata/upper.wasm"
+
+func newTestTransform(t testing.TB, schema base.LogSchema) base.LogBatchTransform {
+	tf, _ := newTestTransformWithCounter(t, schema)
+	return tf
+}
+
+func newTestTransformWithCounter(t testing.TB, schema base.LogSchema) (base.LogBatchTransform, func(label string) (int64, int64)) {
+	c := &Config{}
+	assert.NoError(t, util.UnmarshalYamlString(`
+type: wasm
+path: `+testModulePath+`
+keys: [level, log]
+maxMemory: 1MB
+metricLabel: failed
+`, c))
+	assert.NoError(t, c.VerifyConfig(schema))
+	registry, lookup := btest.NewStubLogCustomCounterRegistry()
+	return c.NewTransform(schema, logger.Root(), registry).(base.LogBatchTransform), lookup
+}
+
+func TestWasmTransform(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"level", "log"})
+	tf := newTestTransform(t, schema)
+	defer tf.(base.LogTransformCloser).Close()
+
+	records := []*base.LogRecord{
+		schema.NewTestRecord1(base.LogFields{"info", "hello"}),
+		schema.NewTestRecord1(base.LogFields{"", "dropped"}),
+		schema.NewTestRecord1(base.LogFields{"Warn-1", "world"}),
+	}
+	results := make([]base.FilterResult, len(records))
+	tf.TransformBatch(records, results, nil)
+	assert.Equal(t, []base.FilterResult{base.PASS, base.DROP, base.PASS}, results)
+	assert.Equal(t, base.LogFields{"INFO", "hello"}, records[0].Fields)
+	assert.Equal(t, base.LogFields{"WARN-1", "world"}, records[2].Fields)
+
+	// larger than the previous buffer
+	record := schema.NewTestRecord1(base.LogFields{"debug", string(make([]byte, 100000))})
+	assert.Equal(t, base.PASS, tf.Transform(record))
+	assert.Equal(t, "DEBUG", record.Fields[0])
+	assert.Equal(t, base.DROP, tf.Transform(schema.NewTestRecord1(base.LogFields{"", ""})))
+}
+
+func TestWasmTransformInvalidResults(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"level", "log"})
+	tf := newTestTransform(t, schema)
+	defer tf.(base.LogTransformCloser).Close()
+
+	records := []*base.LogRecord{
+		schema.NewTestRecord1(base.LogFields{"info", "hello"}),
+		schema.NewTestRecord1(base.LogFields{"warn", "world"}),
+	}
+	results := []base.FilterResult{base.PASS, base.PASS}
+	// DROP and update the first record, then an invalid result for the second one
+	out := []byte{resultDrop, 1, 0, 4, 0, 0, 0, 'I', 'N', 'F', 'O', 9, 0}
+	assert.EqualError(t, tf.(*wasmTransform).applyResults(out, records, results), "invalid result at record 1: 9")
+	assert.Equal(t, base.LogFields{"info", "hello"}, records[0].Fields)
+
+	// the same results for one record only
+	assert.NoError(t, tf.(*wasmTransform).applyResults(out, records[:1], results[:1]))
+	assert.Equal(t, base.LogFields{"INFO", "hello"}, records[0].Fields)
+	assert.Equal(t, base.DROP, results[0])
+}
+
+func TestWasmTransformFailure(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"level", "log"})
+	tf, lookup := newTestTransformWithCounter(t, schema)
+	defer tf.(base.LogTransformCloser).Close()
+	wtf := tf.(*wasmTransform)
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	wtf.now = func() time.Time { return now }
+
+	records := []*base.LogRecord{
+		schema.NewTestRecord1(base.LogFields{"info", "hello"}),
+		schema.NewTestRecord1(base.LogFields{"", "world"}),
+	}
+	records[0].RawLength = 10
+	records[1].RawLength = 20
+	results := make([]base.FilterResult, len(records))
+	fail := func() {
+		// make the reused input buffer point out of module memory
+		wtf.bufferPtr = 0xFFFFFF00
+		wtf.bufferSize = 0xFFFF
+		tf.TransformBatch(records, results, nil)
+		assert.Equal(t, []base.FilterResult{base.PASS, base.PASS}, results)
+	}
+
+	fail()
+	assert.Equal(t, base.LogFields{"info", "hello"}, records[0].Fields)
+	count, length := lookup("failed")
+	assert.Equal(t, int64(2), count)
+	assert.Equal(t, int64(30), length)
+	assert.Equal(t, now, wtf.lastErrorLog)
+	assert.Equal(t, 0, wtf.numUnlogged)
+
+	now = now.Add(time.Second)
+	fail()
+	fail()
+	count, _ = lookup("failed")
+	assert.Equal(t, int64(6), count)
+	assert.Equal(t, now.Add(-time.Second), wtf.lastErrorLog)
+	assert.Equal(t, 2, wtf.numUnlogged)
+
+	now = now.Add(errorLogInterval)
+	fail()
+	assert.Equal(t, now, wtf.lastErrorLog)
+	assert.Equal(t, 0, wtf.numUnlogged)
+}
+
+func TestWasmTransformClose(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"level", "log"})
+	tf1 := newTestTransform(t, schema)
+	tf2 := newTestTransform(t, schema)
+	assert.Len(t, pluginMap, 1)
+	assert.Same(t, tf1.(*wasmTransform).plugin, tf2.(*wasmTransform).plugin)
+
+	tf1.(base.LogTransformCloser).Close()
+	assert.Len(t, pluginMap, 1)
+	tf2.(base.LogTransformCloser).Close()
+	assert.Len(t, pluginMap, 0)
+
+	// compiled again after being released
+	tf3 := newTestTransform(t, schema)
+	assert.Equal(t, base.PASS, tf3.Transform(schema.NewTestRecord1(base.LogFields{"info", ""})))
+	tf3.(base.LogTransformCloser).Close()
+	assert.Len(t, pluginMap, 0)
+}
+
+func TestWasmTransformVerify(t *testing.T) {
+	schema := base.MustNewLogSchema([]string{"level", "log"})
+	verify := func(yaml string) error {
+		c := &Config{}
+		assert.NoError(t, util.UnmarshalYamlString(yaml, c))
+		return c.VerifyConfig(schema)
+	}
+	assert.EqualError(t, verify("type: wasm\npath: "+testModulePath), ".keys is empty")
+	assert.EqualError(t, verify("type: wasm\npath: "+testModulePath+"\nkeys: [foo]"),
+		".keys[0] 'foo' is invalid: field 'foo' is not defined in schema")
+	assert.EqualError(t, verify("type: wasm\npath: "+testModulePath+"\nkeys: [log]"), ".metricLabel is unspecified")
+
+	emptyPath := filepath.Join(t.TempDir(), "empty.wasm")
+	assert.NoError(t, os.WriteFile(emptyPath, []byte("\x00asm\x01\x00\x00\x00"), 0o644))
+	assert.EqualError(t, verify("type: wasm\npath: "+emptyPath+"\nkeys: [log]\nmetricLabel: failed"),
+		".path '"+emptyPath+"': missing exported memory 'memory'")
+}
+
+func BenchmarkWasmTransform(b *testing.B) {
+	schema := base.MustNewLogSchema([]string{"level", "log"})
+	tf := newTestTransform(b, schema)
+	defer tf.(base.LogTransformCloser).Close()
+	records := newBenchmarkRecords(schema)
+	results := make([]base.FilterResult, len(records))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tf.TransformBatch(records, results, nil)
+	}
+}
+
+// BenchmarkNativeTransform does the same as BenchmarkWasmTransform but with the native uppercase transform
+func BenchmarkNativeTransform(b *testing.B) {
+	schema := base.MustNewLogSchema([]string{"level", "log"})
+	c := &tnormalize.Config{Header: bconfig.Header{Type: "uppercase"}, Keys: []string{"level"}}
+	tf := c.NewTransform(schema, logger.Root(), nil)
+	records := newBenchmarkRecords(schema)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, record := range records {
+			tf.Transform(record)
+		}
+	}
+}
+
+// newBenchmarkRecords creates a full buffer of records as from inputs
+func newBenchmarkRecords(schema base.LogSchema) []*base.LogRecord {
+	records := make([]*base.LogRecord, defs.IntermediateBufferMaxNumLogs)
+	for i := range records {
+		records[i] = schema.NewTestRecord1(base.LogFields{"info", "GET /index.html HTTP/1.1 200 0.005 Mozilla/5.0"})
+	}
+	return records
+}
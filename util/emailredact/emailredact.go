@@ -1,11 +1,14 @@
-package tredactemail
+// Package emailredact provides scanning and redaction of email addresses in strings, shared by 'redactEmail' transform
+// and rewriter
+package emailredact
 
 import (
 	"strings"
-
-	"github.com/relex/slog-agent/util"
 )
 
+// Replacement is the text to replace each email address
+const Replacement = "REDACTED"
+
 var (
 	validAddressChars = make([]bool, 256)
 	validWordChars    = make([]bool, 256)
@@ -29,16 +32,8 @@ func init() {
 	validAddressChars['_'] = true
 }
 
-func redactEmail(src string) string {
-	first := redactEmailFindFirst(src)
-	if first == -1 {
-		return src
-	}
-	dest, _ := redactEmail1(src, first)
-	return dest
-}
-
-func redactEmailFindFirst(src string) int {
+// FindFirst returns the position of the first '@' which could be part of an email address, or -1 if not found
+func FindFirst(src string) int {
 	sEnd := len(src) - 1
 	sAt := strings.IndexByte(src, '@')
 	// ignore src[0] and src[len-1] because no valid email possible
@@ -56,11 +51,13 @@ func redactEmailFindFirst(src string) int {
 	return -1
 }
 
-func redactEmail1(src string, start int) (string, int) {
+// AppendRedacted appends src to dst with email addresses replaced, starting from the position found by FindFirst
+//
+// Returns the extended dst and the number of redacted addresses
+func AppendRedacted(dst []byte, src string, first int) ([]byte, int) {
 	numRedacted := 0
-	dst := make([]byte, 0, len(src))
 	sEnd := len(src) - 1
-	sAt := start // sAt should point to '@'
+	sAt := first // sAt should point to '@'
 	sCopied := 0
 	// ignore src[0] and src[len-1] because no valid email possible
 	for sAt < sEnd {
@@ -69,7 +66,7 @@ func redactEmail1(src string, start int) (string, int) {
 			if emailStart != -1 && emailEnd != -1 {
 				// copy contents before email and the email
 				dst = append(dst, src[sCopied:emailStart]...)
-				dst = append(dst, "REDACTED"...)
+				dst = append(dst, Replacement...)
 				sCopied = emailEnd
 				sAt = emailEnd
 				numRedacted++
@@ -89,7 +86,7 @@ func redactEmail1(src string, start int) (string, int) {
 		sAt += nextAt
 	}
 	dst = append(dst, src[sCopied:]...)
-	return util.StringFromBytes(dst), numRedacted
+	return dst, numRedacted
 }
 
 func redactFindEmailBoundary(src string, atIndex int, limitStart int) (int, int) {
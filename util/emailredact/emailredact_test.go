@@ -1,4 +1,4 @@
-package tredactemail
+package emailredact
 
 import (
 	"testing"
@@ -6,6 +6,15 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+func redactEmail(src string) string {
+	first := FindFirst(src)
+	if first == -1 {
+		return src
+	}
+	dst, _ := AppendRedacted(nil, src, first)
+	return string(dst)
+}
+
 func TestRedactEmail(t *testing.T) {
 	t.Run("common", func(tt *testing.T) {
 		assert.Equal(t, "trx_key: user_123, user: REDACTED", redactEmail("trx_key: user_123, user: foo.bar@domain.fi"))
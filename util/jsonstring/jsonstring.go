@@ -0,0 +1,123 @@
+// Package jsonstring provides escaping of JSON string contents into fixed buffers, with the same results as
+// encoding/json (including HTML-safe escaping of '<', '>' and '&') but without allocations
+//
+// Invalid UTF-8 bytes are replaced by U+FFFD one by one.
+package jsonstring
+
+import (
+	"unicode/utf8"
+)
+
+// asciiEscapes maps ASCII bytes to the character after '\' in escape sequences, 'u' for "\u00XX" or 0 if safe
+var asciiEscapes [utf8.RuneSelf]byte
+
+const hex = "0123456789abcdef"
+
+func init() {
+	for b := 0; b < 0x20; b++ {
+		asciiEscapes[b] = 'u'
+	}
+	asciiEscapes['\b'] = 'b'
+	asciiEscapes['\f'] = 'f'
+	asciiEscapes['\n'] = 'n'
+	asciiEscapes['\r'] = 'r'
+	asciiEscapes['\t'] = 't'
+	asciiEscapes['"'] = '"'
+	asciiEscapes['\\'] = '\\'
+	asciiEscapes['<'] = 'u'
+	asciiEscapes['>'] = 'u'
+	asciiEscapes['&'] = 'u'
+}
+
+// MaxEscapedLength returns the max length of escaped string without quotes from n bytes, without scanning
+func MaxEscapedLength(n int) int {
+	return 6 * n // "\u00XX" for control characters
+}
+
+// EscapedLength returns the length of escaped s without quotes
+func EscapedLength(s string) int {
+	length := len(s)
+	for i := 0; i < len(s); {
+		b := s[i]
+		if b < utf8.RuneSelf {
+			switch asciiEscapes[b] {
+			case 0:
+			case 'u':
+				length += 5
+			default:
+				length++
+			}
+			i++
+			continue
+		}
+		c, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case c == utf8.RuneError && size == 1:
+			length += 2 // 1 byte to U+FFFD in UTF-8
+		case c == '\u2028' || c == '\u2029':
+			length += 3 // 3 bytes to "\u202X"
+		}
+		i += size
+	}
+	return length
+}
+
+// WriteEscaped writes escaped s without quotes to the beginning of dst, which must be at least as long as the result
+// from EscapedLength
+//
+// Returns the end position in dst
+func WriteEscaped(dst []byte, s string) int {
+	di := 0
+	start := 0 // start of bytes not yet copied
+	for i := 0; i < len(s); {
+		b := s[i]
+		if b < utf8.RuneSelf {
+			esc := asciiEscapes[b]
+			if esc == 0 {
+				i++
+				continue
+			}
+			di += copy(dst[di:], s[start:i])
+			di = WriteEscapedASCII(dst, di, b)
+			i++
+			start = i
+			continue
+		}
+		c, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case c == utf8.RuneError && size == 1:
+			di += copy(dst[di:], s[start:i])
+			di += copy(dst[di:], "\uFFFD")
+			start = i + size
+		case c == '\u2028' || c == '\u2029':
+			di += copy(dst[di:], s[start:i])
+			di += copy(dst[di:], `\u202`)
+			dst[di] = hex[c&0xF]
+			di++
+			start = i + size
+		}
+		i += size
+	}
+	return di + copy(dst[di:], s[start:])
+}
+
+// WriteEscapedASCII writes the ASCII byte b at position di of dst, escaped if needed, and returns the new position
+func WriteEscapedASCII(dst []byte, di int, b byte) int {
+	switch esc := asciiEscapes[b]; esc {
+	case 0:
+		dst[di] = b
+		return di + 1
+	case 'u':
+		dst[di] = '\\'
+		dst[di+1] = 'u'
+		dst[di+2] = '0'
+		dst[di+3] = '0'
+		dst[di+4] = hex[b>>4]
+		dst[di+5] = hex[b&0xF]
+		return di + 6
+	default:
+		dst[di] = '\\'
+		dst[di+1] = esc
+		return di + 2
+	}
+}
@@ -0,0 +1,28 @@
+package jsonstring
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteEscaped(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"plain text",
+		"quote \" and backslash \\",
+		"controls \b\f\n\r\t\x00\x1f\x7f",
+		"html <a href=\"x\">&amp;</a>",
+		"unicode 世界 \u2028\u2029",
+		"invalid \xff\xfe end \xe4\xb8",
+	} {
+		expected, err := json.Marshal(s)
+		assert.NoError(t, err)
+		expected = expected[1 : len(expected)-1]
+
+		buf := make([]byte, 6*len(s))
+		assert.Equal(t, len(expected), EscapedLength(s), s)
+		assert.Equal(t, string(expected), string(buf[:WriteEscaped(buf, s)]), s)
+	}
+}
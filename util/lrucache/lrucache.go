@@ -0,0 +1,131 @@
+// Package lrucache provides a fixed-capacity LRU cache for per-worker state, e.g. by-key counters in transforms
+//
+// The cache is not concurrently usable. Entries are kept in a preallocated slice and linked by indexes, so that no
+// allocation happens after the cache is full except for the keys themselves.
+package lrucache
+
+// LRUCache is a fixed-capacity map which evicts the least recently used entry when full
+type LRUCache[K comparable, V any] struct {
+	indexMap map[K]int     // map of key to index in entries
+	entries  []entry[K, V] // all entries, linked in order of recent usage
+	head     int           // index of the most recently used entry, -1 if empty
+	tail     int           // index of the least recently used entry, -1 if empty
+	capacity int
+}
+
+type entry[K comparable, V any] struct {
+	key   K
+	value V
+	prev  int // index of the previous (more recently used) entry or -1
+	next  int // index of the next (less recently used) entry or -1
+}
+
+// NewLRUCache creates an empty LRUCache of the given capacity, which must be larger than zero
+func NewLRUCache[K comparable, V any](capacity int) *LRUCache[K, V] {
+	if capacity <= 0 {
+		panic("lrucache: capacity must be larger than zero")
+	}
+	return &LRUCache[K, V]{
+		indexMap: make(map[K]int, capacity),
+		entries:  make([]entry[K, V], 0, capacity),
+		head:     -1,
+		tail:     -1,
+		capacity: capacity,
+	}
+}
+
+// Get returns the pointer to the value of given key and marks it as the most recently used, or nil if not found
+//
+// The returned pointer is only valid until the next call to Add
+func (c *LRUCache[K, V]) Get(key K) *V {
+	index, found := c.indexMap[key]
+	if !found {
+		return nil
+	}
+	c.moveToFront(index)
+	return &c.entries[index].value
+}
+
+// Add inserts a new entry and returns the pointer to its value, evicting the least recently used one if full
+//
+// The key must not exist in the cache. Any transient key (e.g. field values of log records) must be copied first.
+//
+// onEvict is called with the evicted entry before it's overwritten, and may be nil.
+func (c *LRUCache[K, V]) Add(key K, value V, onEvict func(key K, value *V)) *V {
+	var index int
+	if len(c.entries) < c.capacity {
+		index = len(c.entries)
+		c.entries = append(c.entries, entry[K, V]{key: key, value: value, prev: -1, next: -1})
+	} else {
+		index = c.tail
+		old := &c.entries[index]
+		if onEvict != nil {
+			onEvict(old.key, &old.value)
+		}
+		delete(c.indexMap, old.key)
+		c.unlink(index)
+		old.key = key
+		old.value = value
+	}
+	c.indexMap[key] = index
+	c.pushFront(index)
+	return &c.entries[index].value
+}
+
+// Len returns the number of entries
+func (c *LRUCache[K, V]) Len() int {
+	return len(c.entries)
+}
+
+// Walk iterates through all entries from the most recently used to the least
+func (c *LRUCache[K, V]) Walk(action func(key K, value *V)) {
+	for i := c.head; i != -1; i = c.entries[i].next {
+		action(c.entries[i].key, &c.entries[i].value)
+	}
+}
+
+// Clear removes all entries
+func (c *LRUCache[K, V]) Clear() {
+	clear(c.indexMap)
+	clear(c.entries) // release references held by keys and values
+	c.entries = c.entries[:0]
+	c.head = -1
+	c.tail = -1
+}
+
+func (c *LRUCache[K, V]) moveToFront(index int) {
+	if index == c.head {
+		return
+	}
+	c.unlink(index)
+	c.pushFront(index)
+}
+
+func (c *LRUCache[K, V]) pushFront(index int) {
+	e := &c.entries[index]
+	e.prev = -1
+	e.next = c.head
+	if c.head != -1 {
+		c.entries[c.head].prev = index
+	}
+	c.head = index
+	if c.tail == -1 {
+		c.tail = index
+	}
+}
+
+func (c *LRUCache[K, V]) unlink(index int) {
+	e := &c.entries[index]
+	if e.prev != -1 {
+		c.entries[e.prev].next = e.next
+	} else {
+		c.head = e.next
+	}
+	if e.next != -1 {
+		c.entries[e.next].prev = e.prev
+	} else {
+		c.tail = e.prev
+	}
+	e.prev = -1
+	e.next = -1
+}
@@ -0,0 +1,49 @@
+package lrucache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUCache(t *testing.T) {
+	c := NewLRUCache[string, int](3)
+	evicted := make([]string, 0)
+	onEvict := func(key string, value *int) {
+		evicted = append(evicted, key)
+	}
+
+	*c.Add("a", 1, onEvict) += 10
+	c.Add("b", 2, onEvict)
+	c.Add("c", 3, onEvict)
+	assert.Equal(t, 3, c.Len())
+	assert.Equal(t, 11, *c.Get("a"))
+
+	c.Add("d", 4, onEvict) // evicts "b" as "a" has been used
+	assert.Equal(t, []string{"b"}, evicted)
+	assert.Nil(t, c.Get("b"))
+	assert.Equal(t, 3, *c.Get("c"))
+
+	c.Add("e", 5, onEvict) // evicts "a"
+	assert.Equal(t, []string{"b", "a"}, evicted)
+
+	keys := make([]string, 0)
+	c.Walk(func(key string, value *int) {
+		keys = append(keys, key)
+	})
+	assert.Equal(t, []string{"e", "c", "d"}, keys)
+
+	c.Clear()
+	assert.Equal(t, 0, c.Len())
+	assert.Nil(t, c.Get("e"))
+	c.Add("f", 6, nil)
+	assert.Equal(t, 6, *c.Get("f"))
+}
+
+func TestLRUCacheSingle(t *testing.T) {
+	c := NewLRUCache[int, string](1)
+	c.Add(1, "x", nil)
+	c.Add(2, "y", nil)
+	assert.Nil(t, c.Get(1))
+	assert.Equal(t, "y", *c.Get(2))
+}
@@ -1,4 +1,7 @@
-package textractspecial
+// Package stringextract provides fast extraction of labels at the start or the end of strings, by simple patterns of
+// boundaries and a wildcard or a table of valid chars, e.g. `\[*\] - ` for "[MyClass] - message" or ":[0-9a-f-]" for
+// "task.log:123e4567-e89b-12d3-a456-426614174000".
+package stringextract
 
 import (
 	"fmt"
@@ -7,11 +10,13 @@ import (
 	"github.com/relex/slog-agent/util/stringunescape"
 )
 
-type stringExtractorPosition int
+// Position defines where to extract labels
+type Position int
 
+// Positions to extract labels
 const (
-	extractFromStart stringExtractorPosition = 1
-	extractFromEnd   stringExtractorPosition = 2
+	FromStart Position = 1
+	FromEnd   Position = 2
 )
 
 var patternUnescaper = stringunescape.NewUnescaper('\\', map[byte]byte{
@@ -20,20 +25,20 @@ var patternUnescaper = stringunescape.NewUnescaper('\\', map[byte]byte{
 	'*': '*',
 })
 
-// stringExtractor is a fast alternative to string extraction by regular expression, using simple boundaries and table of valid bytes
-type stringExtractor struct {
-	position   stringExtractorPosition
+// Extractor is a fast alternative to string extraction by regular expression, using simple boundaries and table of valid bytes
+type Extractor struct {
+	position   Position
 	leftBound  string
 	rightBound string
 	maxRange   int
 	validChars []bool
 }
 
-var emptyExtractor = stringExtractor{}
+var emptyExtractor = Extractor{}
 
-// newStringExtractorSimple creates a StringExtractor or returns error if the given pattern is not supported
+// NewExtractor creates an Extractor or returns error if the given pattern is not supported
 // A pattern is like "Foo*Bar" or "component=[a-z0-9_],", where there has to be exactly one wildcard or brackets
-func newStringExtractorSimple(position stringExtractorPosition, pattern string, maxRange int) (stringExtractor, error) {
+func NewExtractor(position Position, pattern string, maxRange int) (Extractor, error) {
 	parts, err := splitPattern(pattern)
 	if err != nil {
 		return emptyExtractor, err
@@ -44,7 +49,7 @@ func newStringExtractorSimple(position stringExtractorPosition, pattern string,
 // newStringExtractor creates a StringExtractor or returns error if the given pattern parts are not supported
 // The only type of patterns supported is ["left boundary", "wildcard for the target", "right boundary"]
 // Both boundaries are optional and may be empty, while the target wildcard can be either "*" or "[a-z123...A-Z-]", but not both or combined.
-func newStringExtractor(position stringExtractorPosition, patternParts []string, maxRange int) (stringExtractor, error) {
+func newStringExtractor(position Position, patternParts []string, maxRange int) (Extractor, error) {
 	if len(patternParts) != 3 {
 		return emptyExtractor, fmt.Errorf("must have 3 parts: [left, wildcard, right], not %d", len(patternParts))
 	}
@@ -65,7 +70,7 @@ func newStringExtractor(position stringExtractorPosition, patternParts []string,
 			return emptyExtractor, fmt.Errorf("patternParts[1]: %w", err)
 		}
 	}
-	return stringExtractor{
+	return Extractor{
 		position:   position,
 		leftBound:  leftBoundary,
 		rightBound: rightBoundary,
@@ -76,17 +81,35 @@ func newStringExtractor(position stringExtractorPosition, patternParts []string,
 
 // Extract extracts label from the given text
 // Returns (label, text after cutting label and boundaries)
-func (ex *stringExtractor) Extract(text string) (string, string) {
+func (ex *Extractor) Extract(text string) (string, string) {
 	switch ex.position {
-	case extractFromStart:
+	case FromStart:
 		return extractLabelAtStart(text, ex.leftBound, ex.rightBound, ex.maxRange, ex.validChars)
-	case extractFromEnd:
+	case FromEnd:
 		return extractLabelAtEnd(text, ex.leftBound, ex.rightBound, ex.maxRange, ex.validChars)
 	default:
 		panic(ex.position)
 	}
 }
 
+// Locate finds label in the given text the same way as Extract
+// Returns (start, end) of the label, or (-1, -1) if not found. The label may be empty if found.
+func (ex *Extractor) Locate(text string) (int, int) {
+	var m labelMatch
+	switch ex.position {
+	case FromStart:
+		m = locateLabelAtStart(text, ex.leftBound, ex.rightBound, ex.maxRange, ex.validChars)
+	case FromEnd:
+		m = locateLabelAtEnd(text, ex.leftBound, ex.rightBound, ex.maxRange, ex.validChars)
+	default:
+		panic(ex.position)
+	}
+	if m.cut == -1 {
+		return -1, -1
+	}
+	return m.labelStart, m.labelEnd
+}
+
 // splitPattern splits pattern e.g. "\[*\] - " into "[", "*", "] - "
 func splitPattern(pattern string) ([]string, error) {
 	if i := patternUnescaper.FindFirstUnescaped(pattern, '*'); i != -1 {
@@ -159,21 +182,53 @@ func fillValidCharsByRangeExpression(table []bool, expression string) error {
 	return nil
 }
 
+// labelMatch is the result of label search: label is text[labelStart:labelEnd], and the remaining text is text[cut:]
+// when searched from start or text[:cut] from end. cut is -1 if not found.
+type labelMatch struct {
+	labelStart int
+	labelEnd   int
+	cut        int
+}
+
+var noLabelMatch = labelMatch{-1, -1, -1}
+
 // extractLabelAtStart extracts for example "Foo" from "[Foo]...text" with given boundaries, search range and valid chars
 // The leftBoundary is optional, while at least one of rightBoundary or validChars should be present
 // Returns (label, text with the label and its boundaries removed)
 // If matching fails, returns ("", text)
 func extractLabelAtStart(text string, leftBoundary string, rightBoundary string, maxRange int, validChars []bool) (string, string) {
+	m := locateLabelAtStart(text, leftBoundary, rightBoundary, maxRange, validChars)
+	if m.cut == -1 {
+		return "", text
+	}
+	return text[m.labelStart:m.labelEnd], text[m.cut:]
+}
+
+// extractLabelAtEnd extracts for example "Bar" from "text...[Bar]" with given boundaries, search range and valid chars
+// The rightBoundary is optional, while at least one of leftBoundary or validChars should be present
+// Returns (label, text with the label and its boundaries removed)
+// If matching fails, returns ("", text)
+func extractLabelAtEnd(text string, leftBoundary string, rightBoundary string, maxRange int, validChars []bool) (string, string) {
+	m := locateLabelAtEnd(text, leftBoundary, rightBoundary, maxRange, validChars)
+	if m.cut == -1 {
+		return "", text
+	}
+	return text[m.labelStart:m.labelEnd], text[:m.cut]
+}
+
+func locateLabelAtStart(text string, leftBoundary string, rightBoundary string, maxRange int, validChars []bool) labelMatch {
 	s := text
+	offset := 0
 	if len(leftBoundary) > 0 {
 		if !strings.HasPrefix(text, leftBoundary) {
-			return "", text
+			return noLabelMatch
 		}
 		s = s[len(leftBoundary):]
+		offset = len(leftBoundary)
 	}
 	// fail fast if table match on tag won't succeed
 	if len(s) > 0 && validChars != nil && !validChars[s[0]] {
-		return "", text
+		return noLabelMatch
 	}
 	if len(rightBoundary) > 0 {
 		var iend int
@@ -183,13 +238,14 @@ func extractLabelAtStart(text string, leftBoundary string, rightBoundary string,
 			iend = strings.Index(s, rightBoundary)
 		}
 		if iend == -1 {
-			return "", text
+			return noLabelMatch
 		}
 		tag := s[:iend]
 		if validChars != nil && matchValidCharsFromStart(tag, validChars) != len(tag) {
-			return "", text
+			return noLabelMatch
 		}
-		return trimControlCharsAndSpaces(tag), s[iend+len(rightBoundary):]
+		tagStart, tagEnd := trimControlCharsAndSpaces(tag)
+		return labelMatch{offset + tagStart, offset + tagEnd, offset + iend + len(rightBoundary)}
 	} else {
 		var maxS string
 		if len(s) > maxRange {
@@ -199,27 +255,24 @@ func extractLabelAtStart(text string, leftBoundary string, rightBoundary string,
 		}
 		tagEnd := matchValidCharsFromStart(maxS, validChars)
 		if tagEnd == 0 {
-			return "", text
+			return noLabelMatch
 		}
-		return trimControlCharsAndSpaces(s[:tagEnd]), s[tagEnd:]
+		trimmedStart, trimmedEnd := trimControlCharsAndSpaces(s[:tagEnd])
+		return labelMatch{offset + trimmedStart, offset + trimmedEnd, offset + tagEnd}
 	}
 }
 
-// extractLabelAtEnd extracts for example "Bar" from "text...[Bar]" with given boundaries, search range and valid chars
-// The rightBoundary is optional, while at least one of leftBoundary or validChars should be present
-// Returns (label, text with the label and its boundaries removed)
-// If matching fails, returns ("", text)
-func extractLabelAtEnd(text string, leftBoundary string, rightBoundary string, maxRange int, validChars []bool) (string, string) {
+func locateLabelAtEnd(text string, leftBoundary string, rightBoundary string, maxRange int, validChars []bool) labelMatch {
 	s := text
 	if len(rightBoundary) > 0 {
 		if !strings.HasSuffix(text, rightBoundary) {
-			return "", text
+			return noLabelMatch
 		}
 		s = s[:len(s)-len(rightBoundary)]
 	}
 	// fail fast if table match on tag won't succeed
 	if len(s) > 0 && validChars != nil && !validChars[s[len(s)-1]] {
-		return "", text
+		return noLabelMatch
 	}
 	if len(leftBoundary) > 0 {
 		var iend int
@@ -233,13 +286,15 @@ func extractLabelAtEnd(text string, leftBoundary string, rightBoundary string, m
 			iend = strings.LastIndex(s, leftBoundary)
 		}
 		if iend == -1 {
-			return "", text
+			return noLabelMatch
 		}
-		tag := s[iend+len(leftBoundary):]
+		tagBeg := iend + len(leftBoundary)
+		tag := s[tagBeg:]
 		if validChars != nil && matchValidCharsFromEnd(tag, validChars) != 0 {
-			return "", text
+			return noLabelMatch
 		}
-		return trimControlCharsAndSpaces(tag), s[:iend]
+		trimmedStart, trimmedEnd := trimControlCharsAndSpaces(tag)
+		return labelMatch{tagBeg + trimmedStart, tagBeg + trimmedEnd, iend}
 	} else {
 		var offset int
 		var maxS string
@@ -252,9 +307,10 @@ func extractLabelAtEnd(text string, leftBoundary string, rightBoundary string, m
 		}
 		tagBeg := offset + matchValidCharsFromEnd(maxS, validChars)
 		if tagBeg == len(s) {
-			return "", text
+			return noLabelMatch
 		}
-		return trimControlCharsAndSpaces(s[tagBeg:]), s[:tagBeg]
+		trimmedStart, trimmedEnd := trimControlCharsAndSpaces(s[tagBeg:])
+		return labelMatch{tagBeg + trimmedStart, tagBeg + trimmedEnd, tagBeg}
 	}
 }
 
@@ -280,7 +336,8 @@ func matchValidCharsFromEnd(s string, validChars []bool) int {
 	return 0
 }
 
-func trimControlCharsAndSpaces(s string) string {
+// trimControlCharsAndSpaces returns (start, end) of the given string without leading or trailing control chars and spaces
+func trimControlCharsAndSpaces(s string) (int, int) {
 	istart := 0
 	for istart < len(s) {
 		if s[istart] > ' ' {
@@ -295,5 +352,5 @@ func trimControlCharsAndSpaces(s string) string {
 		}
 		iend--
 	}
-	return s[istart : iend+1]
+	return istart, iend + 1
 }
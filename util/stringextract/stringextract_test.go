@@ -1,4 +1,4 @@
-package textractspecial
+package stringextract
 
 import (
 	"testing"
@@ -8,27 +8,27 @@ import (
 )
 
 func TestExtractor(t *testing.T) {
-	if ex, err := newStringExtractor(extractFromStart, []string{"[", "*", "]"}, 100); assert.NoError(t, err) {
+	if ex, err := newStringExtractor(FromStart, []string{"[", "*", "]"}, 100); assert.NoError(t, err) {
 		lbl, txt := ex.Extract("[Hello]Message")
 		assert.Equal(t, "Hello", lbl)
 		assert.Equal(t, "Message", txt)
 	}
-	if ex, err := newStringExtractor(extractFromStart, []string{"", "[ ]", ""}, 10); assert.NoError(t, err) {
+	if ex, err := newStringExtractor(FromStart, []string{"", "[ ]", ""}, 10); assert.NoError(t, err) {
 		lbl, txt := ex.Extract("     Message")
 		assert.Equal(t, "", lbl)
 		assert.Equal(t, "Message", txt)
 	}
-	if ex, err := newStringExtractor(extractFromStart, []string{"", "[ ]", ""}, 2); assert.NoError(t, err) {
+	if ex, err := newStringExtractor(FromStart, []string{"", "[ ]", ""}, 2); assert.NoError(t, err) {
 		lbl, txt := ex.Extract("     Message")
 		assert.Equal(t, "", lbl)
 		assert.Equal(t, "   Message", txt)
 	}
-	if ex, err := newStringExtractor(extractFromEnd, []string{"", "[^ ]", ""}, 100); assert.NoError(t, err) {
+	if ex, err := newStringExtractor(FromEnd, []string{"", "[^ ]", ""}, 100); assert.NoError(t, err) {
 		lbl, txt := ex.Extract("Lorem ipsum dolor sit amet, consectetur adipiscing elit")
 		assert.Equal(t, "elit", lbl)
 		assert.Equal(t, "Lorem ipsum dolor sit amet, consectetur adipiscing ", txt)
 	}
-	if ex, err := newStringExtractor(extractFromEnd, []string{".", "[0-9]", ""}, 100); assert.NoError(t, err) {
+	if ex, err := newStringExtractor(FromEnd, []string{".", "[0-9]", ""}, 100); assert.NoError(t, err) {
 		{
 			num, fn := ex.Extract("error.log.123")
 			assert.Equal(t, "123", num)
@@ -40,17 +40,17 @@ func TestExtractor(t *testing.T) {
 			assert.Equal(t, "error.log.bz2", fn)
 		}
 	}
-	if ex, err := newStringExtractor(extractFromEnd, []string{"", "[ ]", ""}, 10); assert.NoError(t, err) {
+	if ex, err := newStringExtractor(FromEnd, []string{"", "[ ]", ""}, 10); assert.NoError(t, err) {
 		lbl, txt := ex.Extract("Message     ")
 		assert.Equal(t, "", lbl)
 		assert.Equal(t, "Message", txt)
 	}
-	if ex, err := newStringExtractor(extractFromEnd, []string{"", "[ ]", ""}, 2); assert.NoError(t, err) {
+	if ex, err := newStringExtractor(FromEnd, []string{"", "[ ]", ""}, 2); assert.NoError(t, err) {
 		lbl, txt := ex.Extract("Message     ")
 		assert.Equal(t, "", lbl)
 		assert.Equal(t, "Message   ", txt)
 	}
-	if ex, err := newStringExtractorSimple(extractFromStart, `([0-9a-z\]])`, 100); assert.NoError(t, err) {
+	if ex, err := NewExtractor(FromStart, `([0-9a-z\]])`, 100); assert.NoError(t, err) {
 		{
 			lbl, txt := ex.Extract("(x12]3)Foo")
 			assert.Equal(t, "x12]3", lbl)
@@ -211,3 +211,22 @@ func TestExtractLabelAtEndWithoutRightBoundary(t *testing.T) {
 		assert.Equal(t, "Filename", log)
 	}
 }
+
+func TestExtractorLocate(t *testing.T) {
+	if ex, err := NewExtractor(FromStart, `\[*\] - `, 50); assert.NoError(t, err) {
+		start, end := ex.Locate("[ Foo ] - Hello")
+		assert.Equal(t, "Foo", "[ Foo ] - Hello"[start:end])
+		start, end = ex.Locate("Foo - Hello")
+		assert.Equal(t, -1, start)
+		assert.Equal(t, -1, end)
+	}
+	if ex, err := NewExtractor(FromEnd, `account=[0-9]`, 50); assert.NoError(t, err) {
+		start, end := ex.Locate("Transfer to account=1234567890")
+		assert.Equal(t, 20, start)
+		assert.Equal(t, 30, end)
+	}
+	if ex, err := NewExtractor(FromEnd, `[0-9]`, 50); assert.NoError(t, err) {
+		start, end := ex.Locate("Call 5551234")
+		assert.Equal(t, "5551234", "Call 5551234"[start:end])
+	}
+}
@@ -1,4 +1,4 @@
-package textractspecial
+package stringextract
 
 import (
 	"regexp"
@@ -61,6 +61,26 @@ func BytesFromString(str MutableString) []byte {
 	return unsafe.Slice(unsafe.StringData(str), len(str))
 }
 
+// StringWithin returns whether the contents of sub lie entirely within the memory of str, e.g. sub is a substring of it
+func StringWithin(sub string, str string) bool {
+	if len(sub) == 0 || len(str) == 0 {
+		return false
+	}
+	subStart := uintptr(unsafe.Pointer(unsafe.StringData(sub)))
+	strStart := uintptr(unsafe.Pointer(unsafe.StringData(str)))
+	return subStart >= strStart && subStart+uintptr(len(sub)) <= strStart+uintptr(len(str))
+}
+
+// StringsOverlap returns whether the contents of two strings share any byte in memory
+func StringsOverlap(a string, b string) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return false
+	}
+	aStart := uintptr(unsafe.Pointer(unsafe.StringData(a)))
+	bStart := uintptr(unsafe.Pointer(unsafe.StringData(b)))
+	return aStart < bStart+uintptr(len(b)) && bStart < aStart+uintptr(len(a))
+}
+
 func OverwriteNTruncate(main []byte, start int, tail string) []byte {
 	if len(main)-start < len(tail) {
 		logger.Errorf("BUG: attempting to overwrite '%s' at %d of: %s", tail, start, string(main))
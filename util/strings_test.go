@@ -37,3 +37,14 @@ func TestStringOverwrite(t *testing.T) {
 	assert.Equal(t, "hell", string(OverwriteNTruncate([]byte("helloABC"), 4, "")))
 	assert.Equal(t, "hel^-", string(OverwriteNTruncate([]byte("hell."), 3, "^-^")))
 }
+
+func TestStringsOverlap(t *testing.T) {
+	str := StringFromBytes([]byte("hello world"))
+	assert.True(t, StringWithin(str[2:5], str))
+	assert.True(t, StringWithin(str, str))
+	assert.False(t, StringWithin(str, str[1:]))
+	assert.False(t, StringWithin("hello", str))
+	assert.True(t, StringsOverlap(str[:5], str[4:]))
+	assert.False(t, StringsOverlap(str[:5], str[5:]))
+	assert.False(t, StringsOverlap(str[:0], str))
+}
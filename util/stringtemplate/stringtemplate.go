@@ -124,6 +124,26 @@ func (tmpl Expander) RunWithBuffer(fields RecordType, buffer []byte) (string, []
 	return util.DeepCopyStringFromBytes(buf), buf[:0]
 }
 
+// Length returns the length of expanded result with given fields, without expanding it
+func (tmpl Expander) Length(fields RecordType) int {
+	length := 0
+	for _, provide := range tmpl.partProviders {
+		length += len(provide(fields))
+	}
+	return length
+}
+
+// RunToBuffer expands the template with given fields to the beginning of buffer, which must be at least as long as the
+// result from Length
+// Returns the end position in buffer
+func (tmpl Expander) RunToBuffer(fields RecordType, buffer []byte) int {
+	end := 0
+	for _, provide := range tmpl.partProviders {
+		end += copy(buffer[end:], provide(fields))
+	}
+	return end
+}
+
 func newPartResolverForString(s string) PartProvider {
 	return func(source RecordType) string {
 		return s
@@ -27,6 +27,10 @@ func TestStringTemplate(t *testing.T) {
 	if tmpl, err := NewExpander("mytag-$appname:${msgid}-route0", resolveVariable); assert.NoError(t, err) {
 		result := tmpl.Run([]string{"163", "TestParser", "10"})
 		assert.Equal(t, "mytag-TestParser:10-route0", result)
+
+		buf := make([]byte, 100)
+		assert.Equal(t, len(result), tmpl.Length([]string{"163", "TestParser", "10"}))
+		assert.Equal(t, result, string(buf[:tmpl.RunToBuffer([]string{"163", "TestParser", "10"}, buf)]))
 	}
 	if tmpl, err := NewExpander("mytag-${appname[1:-6]}-", resolveVariable); assert.NoError(t, err) {
 		result := tmpl.Run([]string{"4", "TestParser", ""})
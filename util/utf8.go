@@ -2,6 +2,7 @@ package util
 
 import (
 	"strings"
+	"unicode/utf8"
 )
 
 func CleanUTF8(s []byte) []byte {
@@ -27,3 +28,101 @@ func findLastEndOfASCII(s []byte) int {
 	}
 	return 0
 }
+
+// ControlCharSet is a set of C0 control characters (0x00 - 0x1F), each as a bit of the same order
+type ControlCharSet uint32
+
+// NewControlCharSet creates a ControlCharSet from the given characters, or returns false if any of them isn't C0
+func NewControlCharSet(chars string) (ControlCharSet, bool) {
+	set := ControlCharSet(0)
+	for i := 0; i < len(chars); i++ {
+		if chars[i] >= 0x20 {
+			return 0, false
+		}
+		set |= 1 << chars[i]
+	}
+	return set, true
+}
+
+// Contains checks whether the given byte is in the set
+func (set ControlCharSet) Contains(b byte) bool {
+	return b < 0x20 && set&(1<<b) != 0
+}
+
+// FindUnsafeUTF8 returns the position of the first invalid UTF-8 sequence or C0 control character not in keep, or -1
+func FindUnsafeUTF8(s string, keep ControlCharSet) int {
+	for i := 0; i < len(s); {
+		b := s[i]
+		if b < utf8.RuneSelf {
+			if b < 0x20 && !keep.Contains(b) {
+				return i
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			return i
+		}
+		i += size
+	}
+	return -1
+}
+
+// SanitizeUTF8 appends s to dst with each run of invalid UTF-8 bytes and each C0 control character not in keep replaced
+// by replacement, starting from the position of first unsafe byte found by FindUnsafeUTF8
+func SanitizeUTF8(dst []byte, s string, first int, replacement string, keep ControlCharSet) []byte {
+	dst = append(dst, s[:first]...)
+	invalid := false // whether the previous byte is invalid, to replace a run of them once
+	for i := first; i < len(s); {
+		b := s[i]
+		if b < utf8.RuneSelf {
+			invalid = false
+			if b < 0x20 && !keep.Contains(b) {
+				dst = append(dst, replacement...)
+			} else {
+				dst = append(dst, b)
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			if !invalid {
+				dst = append(dst, replacement...)
+			}
+			invalid = true
+			i++
+			continue
+		}
+		invalid = false
+		dst = append(dst, s[i:i+size]...)
+		i += size
+	}
+	return dst
+}
+
+// TruncateUTF8 returns the longest prefix of s no longer than maxLength bytes, without cutting a UTF-8 sequence
+func TruncateUTF8(s string, maxLength int) string {
+	if len(s) <= maxLength {
+		return s
+	}
+	end := maxLength
+	// step back to the start of the last sequence cut in the middle, if any
+	for i := 0; i < utf8.UTFMax-1 && end > 0 && !utf8.RuneStart(s[end]); i++ {
+		end--
+	}
+	if !utf8.RuneStart(s[end]) { // invalid continuation bytes
+		end = maxLength
+	}
+	return s[:end]
+}
+
+// FindTruncationEnd returns the end position to truncate s to maxLength bytes by TruncateUTF8, for a suffix of
+// suffixLength to be appended after, or -1 if s is not longer than maxLength plus suffixLength to need truncation
+func FindTruncationEnd(s string, maxLength int, suffixLength int) int {
+	if len(s) <= maxLength+suffixLength {
+		return -1
+	}
+	return len(TruncateUTF8(s, maxLength))
+}
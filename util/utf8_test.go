@@ -18,3 +18,37 @@ func TestCleanUTF8(t *testing.T) {
 	assert.Equal(t, "Hello, 世", string(CleanUTF8([]byte("Hello, 世界"[:12]))))
 	assert.Equal(t, "世界 Hi", string(CleanUTF8([]byte("世界 Hi"))))
 }
+
+func TestSanitizeUTF8(t *testing.T) {
+	keep, ok := NewControlCharSet("\t")
+	assert.True(t, ok)
+	_, ok = NewControlCharSet("\t ")
+	assert.False(t, ok)
+
+	sanitize := func(s string) string {
+		first := FindUnsafeUTF8(s, keep)
+		if first == -1 {
+			return s
+		}
+		return string(SanitizeUTF8(nil, s, first, "?", keep))
+	}
+	assert.Equal(t, "", sanitize(""))
+	assert.Equal(t, "Test\tбрэд-ЛГТМ", sanitize("Test\tбрэд-ЛГТМ"))
+	assert.Equal(t, "a?b", sanitize("a\x00b"))
+	assert.Equal(t, "a??b", sanitize("a\r\nb"))
+	assert.Equal(t, "世?", sanitize("世界"[:5]))
+	assert.Equal(t, "?世", sanitize("界"[1:]+"世"))
+	assert.Equal(t, "a?b?", sanitize("a\xff\xfeb\xc0"))
+}
+
+func TestTruncateUTF8(t *testing.T) {
+	assert.Equal(t, "世界", TruncateUTF8("世界", 6))
+	assert.Equal(t, "世", TruncateUTF8("世界", 5))
+	assert.Equal(t, "世", TruncateUTF8("世界", 3))
+	assert.Equal(t, "", TruncateUTF8("世界", 2))
+	assert.Equal(t, "ab", TruncateUTF8("abc", 2))
+	assert.Equal(t, "a\x80\x80\x80\x80", TruncateUTF8("a\x80\x80\x80\x80\x80", 5))
+
+	assert.Equal(t, -1, FindTruncationEnd("Hello123", 5, 3))
+	assert.Equal(t, 4, FindTruncationEnd("1234世界World", 5, 3))
+}
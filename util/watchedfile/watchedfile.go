@@ -0,0 +1,130 @@
+// Package watchedfile provides values loaded from files and reloaded in background when the files are changed or
+// replaced, e.g. lookup tables or databases used by transforms.
+//
+// Values are shared globally by ID and reference-counted. Each Watch must be paired with a Release of the returned
+// value when it's no longer used, e.g. when the pipeline is stopped. The last Release unloads the value and stops
+// watching its file.
+package watchedfile
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/relex/gotils/logger"
+	"github.com/relex/slog-agent/defs"
+)
+
+// LoadFunc defines a function to load and parse the file at given path
+type LoadFunc[T any] func(path string) (T, error)
+
+// Value holds the latest value loaded from a file
+//
+// The value is swapped atomically on reloading and may be read concurrently without locking.
+type Value[T any] struct {
+	current  atomic.Pointer[T]
+	id       string
+	refCount int           // protected by registryMutex
+	stop     chan struct{} // closed to stop the watcher
+}
+
+// Get returns the latest loaded value, which must not be modified
+func (v *Value[T]) Get() *T {
+	return v.current.Load()
+}
+
+// Release releases one reference from Watch. The value is unloaded when there is no more reference.
+func (v *Value[T]) Release() {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	v.refCount--
+	switch {
+	case v.refCount > 0:
+		return
+	case v.refCount < 0:
+		logger.Panicf("watched file '%s' is released more times than watched", v.id)
+	}
+	delete(registry, v.id)
+	close(v.stop)
+}
+
+var (
+	registry      = make(map[string]interface{})
+	registryMutex = &sync.Mutex{}
+)
+
+// Watch returns the shared Value of given ID, or loads the file at path and starts watching it for changes
+//
+// The ID must identify the loaded contents, e.g. path + parsing options. Values of the same ID must have the same type.
+func Watch[T any](id string, path string, load LoadFunc[T]) (*Value[T], error) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	if existing, found := registry[id]; found {
+		value, ok := existing.(*Value[T])
+		if !ok {
+			return nil, fmt.Errorf("watched file '%s' has been registered as a different type: %T", id, existing)
+		}
+		value.refCount++
+		return value, nil
+	}
+
+	stat, serr := os.Stat(path)
+	if serr != nil {
+		return nil, serr
+	}
+	initial, lerr := load(path)
+	if lerr != nil {
+		return nil, lerr
+	}
+	value := &Value[T]{
+		current:  atomic.Pointer[T]{},
+		id:       id,
+		refCount: 1,
+		stop:     make(chan struct{}),
+	}
+	value.current.Store(&initial)
+	registry[id] = value
+
+	wlogger := logger.WithFields(logger.Fields{
+		defs.LabelComponent: "WatchedFile",
+		defs.LabelName:      id,
+	})
+	go watch(wlogger, path, stat, value, load)
+	return value, nil
+}
+
+func watch[T any](wlogger logger.Logger, path string, lastStat os.FileInfo, value *Value[T], load LoadFunc[T]) {
+	ticker := time.NewTicker(defs.WatchedFileCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-value.stop:
+			wlogger.Info("stopped watching")
+			return
+		case <-ticker.C:
+		}
+
+		stat, serr := os.Stat(path)
+		if serr != nil {
+			wlogger.Warnf("failed to check file: %s", serr.Error())
+			continue
+		}
+		if os.SameFile(stat, lastStat) && stat.ModTime().Equal(lastStat.ModTime()) && stat.Size() == lastStat.Size() {
+			continue
+		}
+		lastStat = stat
+
+		newValue, lerr := load(path)
+		if lerr != nil {
+			wlogger.Errorf("failed to reload file, continue with the previous version: %s", lerr.Error())
+			continue
+		}
+		value.current.Store(&newValue)
+		wlogger.Infof("reloaded file modified at %s", stat.ModTime())
+	}
+}
@@ -0,0 +1,61 @@
+package watchedfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/relex/slog-agent/defs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatch(t *testing.T) {
+	defs.WatchedFileCheckInterval = 20 * time.Millisecond
+	path := filepath.Join(t.TempDir(), "value.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("foo"), 0o644))
+
+	load := func(path string) (string, error) {
+		data, err := os.ReadFile(path)
+		return string(data), err
+	}
+
+	value, err := Watch("test:"+path, path, load)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "foo", *value.Get())
+
+	sameValue, err := Watch("test:"+path, path, load)
+	assert.NoError(t, err)
+	assert.Same(t, value, sameValue)
+
+	// replace by renaming a new file
+	newPath := path + ".new"
+	assert.NoError(t, os.WriteFile(newPath, []byte("bar!"), 0o644))
+	assert.NoError(t, os.Rename(newPath, path))
+	assert.Eventually(t, func() bool { return *value.Get() == "bar!" }, 5*time.Second, 10*time.Millisecond)
+
+	_, err = Watch("test:"+path, path, func(path string) (int, error) { return 0, nil })
+	assert.ErrorContains(t, err, "has been registered as a different type")
+
+	_, err = Watch("test:missing", path+".missing", load)
+	assert.Error(t, err)
+
+	// unloaded and no longer watched after the last release
+	value.Release()
+	assert.Contains(t, registry, "test:"+path)
+	sameValue.Release()
+	assert.NotContains(t, registry, "test:"+path)
+	assert.Panics(t, value.Release)
+
+	assert.NoError(t, os.WriteFile(path, []byte("foo"), 0o644))
+	time.Sleep(5 * defs.WatchedFileCheckInterval)
+	assert.Equal(t, "bar!", *value.Get())
+
+	newValue, err := Watch("test:"+path, path, load)
+	assert.NoError(t, err)
+	assert.NotSame(t, value, newValue)
+	assert.Equal(t, "foo", *newValue.Get())
+	newValue.Release()
+}